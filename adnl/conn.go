@@ -0,0 +1,305 @@
+package adnl
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/mercuryoio/ton-validator/tl"
+)
+
+var (
+	idTCPPing         = tl.ID("tcp.ping random_id:long = tcp.Pong")
+	idTCPPong         = tl.ID("tcp.pong random_id:long = tcp.Pong")
+	idTCPAuthenticate = tl.ID("tcp.authentificate nonce:bytes = tcp.Message")
+	idTCPAuthNonce    = tl.ID("tcp.authentificationNonce nonce:bytes = tcp.Message")
+	idTCPAuthComplete = tl.ID("tcp.authentificationComplete key:PublicKey signature:bytes = tcp.Message")
+	idMessageQuery    = tl.ID("adnl.message.query query_id:int256 query:bytes = adnl.Message")
+	idMessageAnswer   = tl.ID("adnl.message.answer query_id:int256 answer:bytes = adnl.Message")
+)
+
+//maxPacketSize guard against garbage lengths from a broken stream
+const maxPacketSize = 16 << 20
+
+//ErrClosed connection is closed or broken, dial again
+var ErrClosed = errors.New("adnl: connection closed")
+
+//ErrTimeout query got no answer in time
+var ErrTimeout = errors.New("adnl: query timed out")
+
+//Conn ADNL over TCP connection, as used by lite-servers and the validator engine control port
+type Conn struct {
+	conn    net.Conn
+	timeout time.Duration
+	enc     cipher.Stream
+	dec     cipher.Stream
+
+	writeMu sync.Mutex
+	mu      sync.Mutex
+	pending map[[32]byte]chan []byte
+	err     error
+	done    chan struct{}
+}
+
+//Dial connect to addr, which must own serverKey, and authenticate with clientKey when it is not nil
+func Dial(addr string, serverKey ed25519.PublicKey, clientKey ed25519.PrivateKey, timeout time.Duration) (*Conn, error) {
+	nc, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return nil, err
+	}
+	c := &Conn{
+		conn:    nc,
+		timeout: timeout,
+		pending: make(map[[32]byte]chan []byte),
+		done:    make(chan struct{}),
+	}
+	nc.SetDeadline(time.Now().Add(timeout))
+	if err = c.handshake(serverKey); err != nil {
+		nc.Close()
+		return nil, fmt.Errorf("adnl handshake with %s failed: %v", addr, err)
+	}
+	if clientKey != nil {
+		if err = c.authenticate(clientKey); err != nil {
+			nc.Close()
+			return nil, fmt.Errorf("adnl authentication with %s failed: %v", addr, err)
+		}
+	}
+	nc.SetDeadline(time.Time{})
+	go c.readLoop()
+	return c, nil
+}
+
+//handshake send session keys encrypted for the server and wait for its empty packet
+func (c *Conn) handshake(serverKey ed25519.PublicKey) error {
+	params := make([]byte, 160)
+	if _, err := rand.Read(params); err != nil {
+		return err
+	}
+	var err error
+	// client reads with key 0..32 / iv 64..80 and writes with key 32..64 / iv 80..96
+	if c.dec, err = newCTR(params[0:32], params[64:80]); err != nil {
+		return err
+	}
+	if c.enc, err = newCTR(params[32:64], params[80:96]); err != nil {
+		return err
+	}
+
+	_, ephemeral, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+	secret, err := SharedSecret(ephemeral, serverKey)
+	if err != nil {
+		return err
+	}
+	digest := sha256.Sum256(params)
+	key := append(append([]byte{}, secret[0:16]...), digest[16:32]...)
+	iv := append(append([]byte{}, digest[0:4]...), secret[20:32]...)
+	stream, err := newCTR(key, iv)
+	if err != nil {
+		return err
+	}
+	encrypted := make([]byte, len(params))
+	stream.XORKeyStream(encrypted, params)
+
+	packet := make([]byte, 0, 256)
+	packet = append(packet, KeyID(serverKey)...)
+	packet = append(packet, ephemeral.Public().(ed25519.PublicKey)...)
+	packet = append(packet, digest[:]...)
+	packet = append(packet, encrypted...)
+	if _, err = c.conn.Write(packet); err != nil {
+		return err
+	}
+
+	payload, err := c.readPacket()
+	if err != nil {
+		return err
+	}
+	if len(payload) != 0 {
+		return fmt.Errorf("unexpected %d byte reply", len(payload))
+	}
+	return nil
+}
+
+//authenticate prove ownership of clientKey by signing both nonces
+func (c *Conn) authenticate(clientKey ed25519.PrivateKey) error {
+	nonce := make([]byte, 32)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	if err := c.writePacket(tl.AppendBytes(tl.AppendUint32(nil, idTCPAuthenticate), nonce)); err != nil {
+		return err
+	}
+	payload, err := c.readPacket()
+	if err != nil {
+		return err
+	}
+	r := tl.NewReader(payload)
+	if id := r.Uint32(); id != idTCPAuthNonce {
+		return fmt.Errorf("unexpected answer %08x", id)
+	}
+	serverNonce := r.Bytes()
+	if err = r.Err(); err != nil {
+		return err
+	}
+	signature := ed25519.Sign(clientKey, append(nonce, serverNonce...))
+	msg := tl.AppendUint32(nil, idTCPAuthComplete)
+	msg = append(msg, SerializePublicKey(clientKey.Public().(ed25519.PublicKey))...)
+	msg = tl.AppendBytes(msg, signature)
+	return c.writePacket(msg)
+}
+
+func newCTR(key, iv []byte) (cipher.Stream, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewCTR(block, iv), nil
+}
+
+//writePacket frame, checksum and encrypt payload
+func (c *Conn) writePacket(payload []byte) error {
+	nonce := make([]byte, 32)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	packet := make([]byte, 4, 4+32+len(payload)+32)
+	binary.LittleEndian.PutUint32(packet, uint32(32+len(payload)+32))
+	packet = append(packet, nonce...)
+	packet = append(packet, payload...)
+	h := sha256.New()
+	h.Write(nonce)
+	h.Write(payload)
+	packet = h.Sum(packet)
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.enc.XORKeyStream(packet, packet)
+	_, err := c.conn.Write(packet)
+	return err
+}
+
+//readPacket read, decrypt and verify one packet, only the reader goroutine calls it after Dial
+func (c *Conn) readPacket() ([]byte, error) {
+	head := make([]byte, 4)
+	if _, err := io.ReadFull(c.conn, head); err != nil {
+		return nil, err
+	}
+	c.dec.XORKeyStream(head, head)
+	size := binary.LittleEndian.Uint32(head)
+	if size < 64 || size > maxPacketSize {
+		return nil, fmt.Errorf("bad packet size %d", size)
+	}
+	packet := make([]byte, size)
+	if _, err := io.ReadFull(c.conn, packet); err != nil {
+		return nil, err
+	}
+	c.dec.XORKeyStream(packet, packet)
+	sum := sha256.Sum256(packet[:size-32])
+	if string(sum[:]) != string(packet[size-32:]) {
+		return nil, errors.New("packet checksum mismatch")
+	}
+	return packet[32 : size-32], nil
+}
+
+func (c *Conn) readLoop() {
+	var err error
+	for {
+		var payload []byte
+		payload, err = c.readPacket()
+		if err != nil {
+			break
+		}
+		r := tl.NewReader(payload)
+		switch r.Uint32() {
+		case idMessageAnswer:
+			var queryID [32]byte
+			copy(queryID[:], r.Int256())
+			answer := r.Bytes()
+			if r.Err() != nil {
+				continue
+			}
+			c.mu.Lock()
+			ch, ok := c.pending[queryID]
+			delete(c.pending, queryID)
+			c.mu.Unlock()
+			if ok {
+				ch <- answer
+			}
+		case idTCPPong:
+		}
+	}
+	c.mu.Lock()
+	c.err = err
+	c.mu.Unlock()
+	c.conn.Close()
+	close(c.done)
+}
+
+//Query send adnl.message.query and wait for the matching answer
+func (c *Conn) Query(query []byte) ([]byte, error) {
+	var queryID [32]byte
+	if _, err := rand.Read(queryID[:]); err != nil {
+		return nil, err
+	}
+	ch := make(chan []byte, 1)
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return nil, ErrClosed
+	}
+	c.pending[queryID] = ch
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, queryID)
+		c.mu.Unlock()
+	}()
+
+	msg := tl.AppendUint32(nil, idMessageQuery)
+	msg = tl.AppendInt256(msg, queryID[:])
+	msg = tl.AppendBytes(msg, query)
+	if err := c.writePacket(msg); err != nil {
+		c.Close()
+		return nil, err
+	}
+
+	timer := time.NewTimer(c.timeout)
+	defer timer.Stop()
+	select {
+	case answer := <-ch:
+		return answer, nil
+	case <-c.done:
+		return nil, ErrClosed
+	case <-timer.C:
+		return nil, fmt.Errorf("%w after %s", ErrTimeout, c.timeout)
+	}
+}
+
+//Ping check the connection is alive
+func (c *Conn) Ping() error {
+	var id [8]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return err
+	}
+	return c.writePacket(append(tl.AppendUint32(nil, idTCPPing), id[:]...))
+}
+
+//Closed channel closed once the connection is gone
+func (c *Conn) Closed() <-chan struct{} {
+	return c.done
+}
+
+//Close close the connection, pending queries fail with ErrClosed
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
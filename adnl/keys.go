@@ -0,0 +1,121 @@
+package adnl
+
+import (
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+
+	"github.com/mercuryoio/ton-validator/tl"
+)
+
+//TL constructors of ed25519 keys as stored in server.pub and client key files
+var (
+	idPubEd25519 = tl.ID("pub.ed25519 key:int256 = PublicKey")
+	idPkEd25519  = tl.ID("pk.ed25519 key:int256 = PrivateKey")
+)
+
+//SerializePublicKey TL form of the key: pub.ed25519 constructor followed by the key
+func SerializePublicKey(pub ed25519.PublicKey) []byte {
+	return tl.AppendInt256(tl.AppendUint32(nil, idPubEd25519), pub)
+}
+
+//ParsePublicKey read TL serialized or raw ed25519 public key
+func ParsePublicKey(data []byte) (ed25519.PublicKey, error) {
+	switch len(data) {
+	case ed25519.PublicKeySize:
+		return ed25519.PublicKey(data), nil
+	case ed25519.PublicKeySize + 4:
+		r := tl.NewReader(data)
+		if id := r.Uint32(); id != idPubEd25519 {
+			return nil, fmt.Errorf("unsupported public key type %08x", id)
+		}
+		return ed25519.PublicKey(r.Int256()), nil
+	}
+	return nil, fmt.Errorf("bad public key length %d", len(data))
+}
+
+//ParsePrivateKey read TL serialized or raw ed25519 private key seed
+func ParsePrivateKey(data []byte) (ed25519.PrivateKey, error) {
+	switch len(data) {
+	case ed25519.SeedSize:
+		return ed25519.NewKeyFromSeed(data), nil
+	case ed25519.SeedSize + 4:
+		r := tl.NewReader(data)
+		if id := r.Uint32(); id != idPkEd25519 {
+			return nil, fmt.Errorf("unsupported private key type %08x", id)
+		}
+		return ed25519.NewKeyFromSeed(r.Int256()), nil
+	}
+	return nil, fmt.Errorf("bad private key length %d", len(data))
+}
+
+//ReadPublicKey load public key file such as server.pub
+func ReadPublicKey(path string) (ed25519.PublicKey, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePublicKey(data)
+}
+
+//ReadPrivateKey load private key file such as the console client key
+func ReadPrivateKey(path string) (ed25519.PrivateKey, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePrivateKey(data)
+}
+
+//KeyID short id of the key, sha256 of its TL form
+func KeyID(pub ed25519.PublicKey) []byte {
+	h := sha256.Sum256(SerializePublicKey(pub))
+	return h[:]
+}
+
+//p25519 field prime 2^255 - 19
+var p25519, _ = new(big.Int).SetString("7fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffed", 16)
+
+//montgomeryU convert ed25519 public key to X25519 one, u = (1 + y) / (1 - y)
+func montgomeryU(pub ed25519.PublicKey) []byte {
+	le := make([]byte, 32)
+	copy(le, pub)
+	le[31] &= 0x7f
+	y := new(big.Int).SetBytes(reverse(le))
+
+	num := new(big.Int).Add(big.NewInt(1), y)
+	den := new(big.Int).Sub(big.NewInt(1), y)
+	den.Mod(den, p25519)
+	den.ModInverse(den, p25519)
+	u := num.Mul(num, den)
+	u.Mod(u, p25519)
+
+	out := make([]byte, 32)
+	u.FillBytes(out)
+	return reverse(out)
+}
+
+func reverse(b []byte) []byte {
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+	return b
+}
+
+//SharedSecret ECDH over ed25519 keys the same way TON does it
+func SharedSecret(priv ed25519.PrivateKey, pub ed25519.PublicKey) ([]byte, error) {
+	h := sha512.Sum512(priv.Seed())
+	local, err := ecdh.X25519().NewPrivateKey(h[:32])
+	if err != nil {
+		return nil, err
+	}
+	remote, err := ecdh.X25519().NewPublicKey(montgomeryU(pub))
+	if err != nil {
+		return nil, err
+	}
+	return local.ECDH(remote)
+}
@@ -0,0 +1,184 @@
+package cell
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+)
+
+//BOC magics: generic and the two legacy indexed formats
+const (
+	bocMagic           = 0xb5ee9c72
+	bocMagicIndexed    = 0x68ff65f3
+	bocMagicIndexedCRC = 0xacc3a728
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+type bocReader struct {
+	data []byte
+	err  error
+}
+
+func (r *bocReader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || len(r.data) < n {
+		r.err = errors.New("cell: truncated bag of cells")
+		return nil
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+func (r *bocReader) uint(n int) int {
+	b := r.next(n)
+	v := 0
+	for _, x := range b {
+		v = v<<8 | int(x)
+	}
+	return v
+}
+
+//FromBOC deserialize bag of cells and return its root cells
+func FromBOC(data []byte) ([]*Cell, error) {
+	r := &bocReader{data: data}
+	magic := uint32(r.uint(4))
+	if r.err != nil {
+		return nil, r.err
+	}
+	var hasIdx, hasCRC bool
+	var size int
+	flags := r.uint(1)
+	switch magic {
+	case bocMagic:
+		hasIdx = flags&0x80 != 0
+		hasCRC = flags&0x40 != 0
+		size = flags & 7
+	case bocMagicIndexed:
+		hasIdx, size = true, flags
+	case bocMagicIndexedCRC:
+		hasIdx, hasCRC, size = true, true, flags
+	default:
+		return nil, fmt.Errorf("cell: unknown BOC magic %08x", magic)
+	}
+	if size == 0 || size > 4 {
+		return nil, fmt.Errorf("cell: bad BOC reference size %d", size)
+	}
+	if hasCRC {
+		if len(data) < 4 {
+			return nil, errors.New("cell: truncated bag of cells")
+		}
+		body, sum := data[:len(data)-4], binary.LittleEndian.Uint32(data[len(data)-4:])
+		if crc32.Checksum(body, crcTable) != sum {
+			return nil, errors.New("cell: BOC checksum mismatch")
+		}
+		r.data = r.data[:len(r.data)-4]
+	}
+	offBytes := r.uint(1)
+	cellsNum := r.uint(size)
+	rootsNum := r.uint(size)
+	r.uint(size) // absent
+	totalSize := r.uint(offBytes)
+	if r.err != nil {
+		return nil, r.err
+	}
+	// every cell takes at least its two descriptor bytes
+	if cellsNum > len(r.data)/2 || rootsNum > cellsNum {
+		return nil, fmt.Errorf("cell: bad BOC header: %d cells, %d roots in %d bytes", cellsNum, rootsNum, len(r.data))
+	}
+	roots := make([]int, rootsNum)
+	if magic == bocMagic {
+		for i := range roots {
+			roots[i] = r.uint(size)
+		}
+	}
+	if hasIdx {
+		r.next(cellsNum * offBytes)
+	}
+	cellData := r.next(totalSize)
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	type rawCell struct {
+		cell *Cell
+		refs []int
+	}
+	raw := make([]rawCell, cellsNum)
+	cr := &bocReader{data: cellData}
+	for i := 0; i < cellsNum; i++ {
+		d1 := cr.uint(1)
+		d2 := cr.uint(1)
+		refsNum := d1 & 7
+		if refsNum > maxRefs {
+			return nil, fmt.Errorf("cell: cell %d has %d references", i, refsNum)
+		}
+		if d1&16 != 0 {
+			// stored hashes and depths, one pair per significant level
+			levels := 1
+			for mask := d1 >> 5; mask != 0; mask >>= 1 {
+				levels += mask & 1
+			}
+			cr.next(levels * (32 + 2))
+		}
+		data := append([]byte(nil), cr.next((d2+1)/2)...)
+		bits := (d2 / 2) * 8
+		if d2%2 == 1 && len(data) > 0 {
+			// completion tag: the last byte ends with 1 followed by zeros
+			last := data[len(data)-1]
+			if last == 0 {
+				return nil, fmt.Errorf("cell: cell %d has bad completion tag", i)
+			}
+			n := 7
+			for last&1 == 0 {
+				last >>= 1
+				n--
+			}
+			bits += n
+			data[len(data)-1] &^= 1 << uint(7-n)
+		}
+		c := &Cell{data: data, bits: bits, exotic: d1&8 != 0}
+		refs := make([]int, refsNum)
+		for j := range refs {
+			refs[j] = cr.uint(size)
+			if refs[j] <= i || refs[j] >= cellsNum {
+				return nil, fmt.Errorf("cell: cell %d has bad reference %d", i, refs[j])
+			}
+		}
+		raw[i] = rawCell{cell: c, refs: refs}
+	}
+	if cr.err != nil {
+		return nil, cr.err
+	}
+	// references always point forward, so link from the end
+	for i := cellsNum - 1; i >= 0; i-- {
+		for _, ref := range raw[i].refs {
+			raw[i].cell.refs = append(raw[i].cell.refs, raw[ref].cell)
+		}
+	}
+
+	result := make([]*Cell, rootsNum)
+	for i, idx := range roots {
+		if idx >= cellsNum {
+			return nil, fmt.Errorf("cell: bad root index %d", idx)
+		}
+		result[i] = raw[idx].cell
+	}
+	return result, nil
+}
+
+//FromBOCSingle deserialize bag of cells with exactly one root
+func FromBOCSingle(data []byte) (*Cell, error) {
+	roots, err := FromBOC(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(roots) != 1 {
+		return nil, fmt.Errorf("cell: expected one root, got %d", len(roots))
+	}
+	return roots[0], nil
+}
@@ -0,0 +1,403 @@
+package cell
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"math/big"
+	"math/bits"
+)
+
+//Builder cell under construction, the first error sticks and is returned by EndCell
+type Builder struct {
+	data []byte
+	bits int
+	refs []*Cell
+	err  error
+}
+
+//BeginCell new empty builder
+func BeginCell() *Builder {
+	return &Builder{}
+}
+
+func (b *Builder) fail(format string, args ...interface{}) *Builder {
+	if b.err == nil {
+		b.err = fmt.Errorf("cell: "+format, args...)
+	}
+	return b
+}
+
+//BitsLeft free data bits
+func (b *Builder) BitsLeft() int {
+	return maxBits - b.bits
+}
+
+//RefsLeft free references
+func (b *Builder) RefsLeft() int {
+	return maxRefs - len(b.refs)
+}
+
+func (b *Builder) storeBit(v uint) {
+	if b.bits%8 == 0 {
+		b.data = append(b.data, 0)
+	}
+	if v != 0 {
+		b.data[b.bits/8] |= 1 << (7 - uint(b.bits%8))
+	}
+	b.bits++
+}
+
+//StoreBit store one bit
+func (b *Builder) StoreBit(v bool) *Builder {
+	if v {
+		return b.StoreUint(1, 1)
+	}
+	return b.StoreUint(0, 1)
+}
+
+//StoreUint store unsigned integer in n bits, n <= 64
+func (b *Builder) StoreUint(v uint64, n int) *Builder {
+	if b.err != nil {
+		return b
+	}
+	if n < 0 || n > 64 || (n < 64 && v>>uint(n) != 0) {
+		return b.fail("value %d does not fit in %d bits", v, n)
+	}
+	if b.BitsLeft() < n {
+		return b.fail("cell overflow storing %d bits", n)
+	}
+	for i := n - 1; i >= 0; i-- {
+		b.storeBit(uint(v>>uint(i)) & 1)
+	}
+	return b
+}
+
+//StoreInt store signed integer in n bits, n <= 64
+func (b *Builder) StoreInt(v int64, n int) *Builder {
+	if n < 64 && n > 0 && (v < -(int64(1)<<uint(n-1)) || v >= int64(1)<<uint(n-1)) {
+		return b.fail("value %d does not fit in %d signed bits", v, n)
+	}
+	if n < 64 {
+		return b.StoreUint(uint64(v)&(uint64(1)<<uint(n)-1), n)
+	}
+	return b.StoreUint(uint64(v), n)
+}
+
+//StoreBigUint store unsigned integer of any width
+func (b *Builder) StoreBigUint(v *big.Int, n int) *Builder {
+	if b.err != nil {
+		return b
+	}
+	if v.Sign() < 0 || v.BitLen() > n {
+		return b.fail("value %s does not fit in %d bits", v, n)
+	}
+	if b.BitsLeft() < n {
+		return b.fail("cell overflow storing %d bits", n)
+	}
+	for i := n - 1; i >= 0; i-- {
+		b.storeBit(v.Bit(i))
+	}
+	return b
+}
+
+//StoreBits store the first n bits of data
+func (b *Builder) StoreBits(data []byte, n int) *Builder {
+	if b.err != nil {
+		return b
+	}
+	if n > len(data)*8 {
+		return b.fail("only %d bits of data, %d requested", len(data)*8, n)
+	}
+	if b.BitsLeft() < n {
+		return b.fail("cell overflow storing %d bits", n)
+	}
+	for i := 0; i < n; i++ {
+		b.storeBit(uint(data[i/8]>>(7-uint(i%8))) & 1)
+	}
+	return b
+}
+
+//StoreBytes store whole bytes
+func (b *Builder) StoreBytes(data []byte) *Builder {
+	return b.StoreBits(data, len(data)*8)
+}
+
+//StoreGrams store nanograms as Grams (VarUInteger 16)
+func (b *Builder) StoreGrams(v int64) *Builder {
+	if v < 0 {
+		return b.fail("negative grams %d", v)
+	}
+	n := 0
+	for x := v; x > 0; x >>= 8 {
+		n++
+	}
+	return b.StoreUint(uint64(n), 4).StoreUint(uint64(v), n*8)
+}
+
+//StoreRef store reference to c
+func (b *Builder) StoreRef(c *Cell) *Builder {
+	if b.err != nil {
+		return b
+	}
+	if c == nil {
+		return b.fail("nil reference")
+	}
+	if b.RefsLeft() == 0 {
+		return b.fail("cell overflow storing reference")
+	}
+	b.refs = append(b.refs, c)
+	return b
+}
+
+//StoreSlice append the unread part of s
+func (b *Builder) StoreSlice(s *Slice) *Builder {
+	if s.Err() != nil {
+		return b.fail("%v", s.Err())
+	}
+	for s.BitsLeft() > 0 {
+		n := s.BitsLeft()
+		if n > 64 {
+			n = 64
+		}
+		b.StoreUint(s.LoadUint(n), n)
+	}
+	for s.RefsLeft() > 0 {
+		b.StoreRef(s.LoadRef())
+	}
+	return b
+}
+
+//EndCell finish the cell
+func (b *Builder) EndCell() (*Cell, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	return &Cell{
+		data: append([]byte(nil), b.data...),
+		bits: b.bits,
+		refs: append([]*Cell(nil), b.refs...),
+	}, nil
+}
+
+//descriptors d1 and d2 of the cell with the level mask
+func (c *Cell) descriptors(mask byte) (byte, byte) {
+	d1 := byte(len(c.refs)) | mask<<5
+	if c.exotic {
+		d1 |= 8
+	}
+	return d1, byte(c.bits/8 + (c.bits+7)/8)
+}
+
+//paddedData data with completion tag when bits do not fill the last byte
+func (c *Cell) paddedData() []byte {
+	n := (c.bits + 7) / 8
+	data := make([]byte, n)
+	copy(data, c.data)
+	if c.bits%8 != 0 {
+		data[n-1] |= 1 << (7 - uint(c.bits%8))
+	}
+	return data
+}
+
+//levelMask levels of the Merkle proofs the pruned branches below the cell were cut from
+func (c *Cell) levelMask() byte {
+	c.compute()
+	return c.mask
+}
+
+//compute level mask, hashes and depths of the cell once, the cell does not change after it is built
+func (c *Cell) compute() {
+	c.once.Do(func() {
+		c.mask = c.computeMask()
+		c.hashes, c.depths = c.levels()
+	})
+}
+
+func (c *Cell) computeMask() byte {
+	switch c.Type() {
+	case TypePrunedBranch:
+		if c.bits < 16 {
+			return 0
+		}
+		return c.data[1] & 7
+	case TypeLibrary:
+		return 0
+	}
+	var mask byte
+	for _, ref := range c.refs {
+		mask |= ref.levelMask()
+	}
+	if t := c.Type(); t == TypeMerkleProof || t == TypeMerkleUpdate {
+		mask >>= 1
+	}
+	return mask
+}
+
+//levels hashes and depths of the cell for its significant levels, lowest first.
+//A pruned branch only has its highest one, the lower ones are stored in its data.
+func (c *Cell) levels() ([][]byte, []int) {
+	mask := c.mask
+	typ := c.Type()
+	skip := 0
+	if typ == TypePrunedBranch {
+		skip = bits.OnesCount8(mask)
+	}
+	// references of Merkle cells are one level deeper
+	shift := 0
+	if typ == TypeMerkleProof || typ == TypeMerkleUpdate {
+		shift = 1
+	}
+	var hashes [][]byte
+	var depths []int
+	for level, i := 0, 0; level <= bits.Len8(mask); level++ {
+		if level > 0 && mask&(1<<uint(level-1)) == 0 {
+			continue
+		}
+		i++
+		if i <= skip {
+			continue
+		}
+		h := sha256.New()
+		d1, d2 := c.descriptors(mask & (1<<uint(level) - 1))
+		h.Write([]byte{d1, d2})
+		if len(hashes) == 0 {
+			h.Write(c.paddedData())
+		} else {
+			h.Write(hashes[len(hashes)-1])
+		}
+		depth := 0
+		for _, ref := range c.refs {
+			d := ref.depthAt(level + shift)
+			var buf [2]byte
+			binary.BigEndian.PutUint16(buf[:], uint16(d))
+			h.Write(buf[:])
+			if d+1 > depth {
+				depth = d + 1
+			}
+		}
+		for _, ref := range c.refs {
+			h.Write(ref.hashAt(level + shift))
+		}
+		hashes = append(hashes, h.Sum(nil))
+		depths = append(depths, depth)
+	}
+	return hashes, depths
+}
+
+//storedLevel index of the hash of level among the ones a pruned branch stores, -1 for the computed one
+func (c *Cell) storedLevel(level int) int {
+	if c.Type() != TypePrunedBranch {
+		return -1
+	}
+	mask := c.levelMask()
+	i := bits.OnesCount8(mask & (1<<uint(level) - 1))
+	n := bits.OnesCount8(mask)
+	if i >= n || c.bits != 16+n*(256+16) {
+		return -1
+	}
+	return i
+}
+
+//hashAt hash of the cell seen from level
+func (c *Cell) hashAt(level int) []byte {
+	if i := c.storedLevel(level); i >= 0 {
+		return c.data[2+32*i : 2+32*(i+1)]
+	}
+	c.compute()
+	return c.hashes[levelIndex(c.mask, level, len(c.hashes))]
+}
+
+//depthAt depth of the cell seen from level
+func (c *Cell) depthAt(level int) int {
+	if i := c.storedLevel(level); i >= 0 {
+		n := bits.OnesCount8(c.levelMask())
+		return int(binary.BigEndian.Uint16(c.data[2+32*n+2*i:]))
+	}
+	c.compute()
+	return c.depths[levelIndex(c.mask, level, len(c.depths))]
+}
+
+//levelIndex index of level among the n computed levels of a cell with mask
+func levelIndex(mask byte, level, n int) int {
+	i := bits.OnesCount8(mask & (1<<uint(level) - 1))
+	if i >= n {
+		i = n - 1
+	}
+	return i
+}
+
+//Depth max depth of the references tree
+func (c *Cell) Depth() int {
+	return c.depthAt(maxLevel)
+}
+
+//Hash representation hash of the cell
+func (c *Cell) Hash() []byte {
+	return c.hashAt(maxLevel)
+}
+
+//ToBOC serialize the cell tree as a bag of cells with crc32c
+func (c *Cell) ToBOC() []byte {
+	// parents go before children, equal subtrees are stored once
+	var order []*Cell
+	index := map[string]int{}
+	visited := map[string]bool{}
+	var visit func(c *Cell)
+	visit = func(c *Cell) {
+		key := string(c.Hash())
+		if visited[key] {
+			return
+		}
+		visited[key] = true
+		for _, ref := range c.refs {
+			visit(ref)
+		}
+		order = append(order, c)
+	}
+	visit(c)
+	for i, j := 0, len(order)-1; i < j; i, j = i+1, j-1 {
+		order[i], order[j] = order[j], order[i]
+	}
+	for i, cell := range order {
+		index[string(cell.Hash())] = i
+	}
+
+	size := 1
+	for len(order) >= 1<<(uint(size)*8) {
+		size++
+	}
+	var cells []byte
+	for _, cell := range order {
+		d1, d2 := cell.descriptors(cell.levelMask())
+		cells = append(cells, d1, d2)
+		cells = append(cells, cell.paddedData()...)
+		for _, ref := range cell.refs {
+			cells = appendUint(cells, index[string(ref.Hash())], size)
+		}
+	}
+	offBytes := 1
+	for len(cells) >= 1<<(uint(offBytes)*8) {
+		offBytes++
+	}
+
+	boc := []byte{0xb5, 0xee, 0x9c, 0x72}
+	boc = append(boc, 0x40|byte(size), byte(offBytes))
+	boc = appendUint(boc, len(order), size)
+	boc = appendUint(boc, 1, size) // roots
+	boc = appendUint(boc, 0, size) // absent
+	boc = appendUint(boc, len(cells), offBytes)
+	boc = appendUint(boc, 0, size) // root index
+	boc = append(boc, cells...)
+	var sum [4]byte
+	binary.LittleEndian.PutUint32(sum[:], crc32.Checksum(boc, crcTable))
+	return append(boc, sum[:]...)
+}
+
+func appendUint(buf []byte, v, n int) []byte {
+	for i := n - 1; i >= 0; i-- {
+		buf = append(buf, byte(v>>(uint(i)*8)))
+	}
+	return buf
+}
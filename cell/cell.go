@@ -0,0 +1,249 @@
+package cell
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+)
+
+//Exotic cell types
+const (
+	TypeOrdinary      = -1
+	TypePrunedBranch  = 1
+	TypeLibrary       = 2
+	TypeMerkleProof   = 3
+	TypeMerkleUpdate  = 4
+	maxBits           = 1023
+	maxRefs           = 4
+	maxLevel          = 3
+	merkleProofHeader = 8 + 256 + 16
+)
+
+//ErrPruned branch of a Merkle proof that the server did not include
+var ErrPruned = errors.New("cell: pruned branch")
+
+//Cell TVM cell: up to 1023 bits and 4 references
+type Cell struct {
+	data   []byte
+	bits   int
+	refs   []*Cell
+	exotic bool
+
+	//level mask, hashes and depths per significant level, computed on first use
+	once   sync.Once
+	mask   byte
+	hashes [][]byte
+	depths []int
+}
+
+//Bits number of data bits
+func (c *Cell) Bits() int {
+	return c.bits
+}
+
+//RefsNum number of references
+func (c *Cell) RefsNum() int {
+	return len(c.refs)
+}
+
+//Ref i-th reference or nil
+func (c *Cell) Ref(i int) *Cell {
+	if i < 0 || i >= len(c.refs) {
+		return nil
+	}
+	return c.refs[i]
+}
+
+//Type exotic type from the first data byte, TypeOrdinary for ordinary cells
+func (c *Cell) Type() int {
+	if !c.exotic || c.bits < 8 {
+		return TypeOrdinary
+	}
+	return int(c.data[0])
+}
+
+//Data data bytes, the last one may be partially used
+func (c *Cell) Data() []byte {
+	return c.data
+}
+
+//Virtualize step into the cell proven by a Merkle proof, other cells are returned as is
+func (c *Cell) Virtualize() *Cell {
+	if c.Type() == TypeMerkleProof && len(c.refs) == 1 {
+		return c.refs[0]
+	}
+	return c
+}
+
+//CheckProof check that the Merkle proof is for the cell with hash and return the proven cell
+func CheckProof(proof *Cell, hash []byte) (*Cell, error) {
+	if proof.Type() != TypeMerkleProof || proof.bits != merkleProofHeader || len(proof.refs) != 1 {
+		return nil, errors.New("cell: not a Merkle proof")
+	}
+	root := proof.refs[0]
+	if stored := proof.data[1:33]; !bytes.Equal(stored, root.hashAt(0)) {
+		return nil, errors.New("cell: Merkle proof hash mismatch")
+	}
+	if !bytes.Equal(proof.data[1:33], hash) {
+		return nil, fmt.Errorf("cell: Merkle proof is for %x, expected %x", proof.data[1:33], hash)
+	}
+	return root, nil
+}
+
+//BeginParse slice over the whole cell
+func (c *Cell) BeginParse() *Slice {
+	s := &Slice{cell: c}
+	if c.Type() == TypePrunedBranch {
+		s.err = ErrPruned
+	}
+	return s
+}
+
+//Slice reader over a cell, the first error sticks and zero values are returned after it
+type Slice struct {
+	cell *Cell
+	pos  int
+	ref  int
+	err  error
+}
+
+//Err first read error
+func (s *Slice) Err() error {
+	return s.err
+}
+
+//BitsLeft unread data bits
+func (s *Slice) BitsLeft() int {
+	return s.cell.bits - s.pos
+}
+
+//RefsLeft unread references
+func (s *Slice) RefsLeft() int {
+	return len(s.cell.refs) - s.ref
+}
+
+func (s *Slice) fail(format string, args ...interface{}) {
+	if s.err == nil {
+		s.err = fmt.Errorf("cell: "+format, args...)
+	}
+}
+
+func (s *Slice) bit(i int) uint {
+	return uint(s.cell.data[i/8]>>(7-uint(i%8))) & 1
+}
+
+//LoadBit read one bit
+func (s *Slice) LoadBit() bool {
+	return s.LoadUint(1) == 1
+}
+
+//LoadUint read unsigned integer of up to 64 bits
+func (s *Slice) LoadUint(n int) uint64 {
+	if s.err != nil {
+		return 0
+	}
+	if n < 0 || n > 64 || s.BitsLeft() < n {
+		s.fail("can't read %d bits, %d left", n, s.BitsLeft())
+		return 0
+	}
+	var v uint64
+	for i := 0; i < n; i++ {
+		v = v<<1 | uint64(s.bit(s.pos+i))
+	}
+	s.pos += n
+	return v
+}
+
+//LoadInt read signed integer of up to 64 bits
+func (s *Slice) LoadInt(n int) int64 {
+	v := s.LoadUint(n)
+	if n > 0 && n < 64 && v&(1<<uint(n-1)) != 0 {
+		return int64(v) - int64(1)<<uint(n)
+	}
+	return int64(v)
+}
+
+//LoadBigUint read unsigned integer of any width
+func (s *Slice) LoadBigUint(n int) *big.Int {
+	v := new(big.Int)
+	for n > 0 && s.err == nil {
+		chunk := n
+		if chunk > 64 {
+			chunk = 64
+		}
+		v.Lsh(v, uint(chunk))
+		v.Or(v, new(big.Int).SetUint64(s.LoadUint(chunk)))
+		n -= chunk
+	}
+	return v
+}
+
+//LoadBigInt read signed integer of any width
+func (s *Slice) LoadBigInt(n int) *big.Int {
+	v := s.LoadBigUint(n)
+	if n > 0 && v.Bit(n-1) == 1 {
+		v.Sub(v, new(big.Int).Lsh(big.NewInt(1), uint(n)))
+	}
+	return v
+}
+
+//LoadBits read n bits, packed from the most significant bit of the first byte
+func (s *Slice) LoadBits(n int) []byte {
+	if s.err != nil {
+		return nil
+	}
+	if n < 0 || s.BitsLeft() < n {
+		s.fail("can't read %d bits, %d left", n, s.BitsLeft())
+		return nil
+	}
+	out := make([]byte, (n+7)/8)
+	for i := 0; i < n; i++ {
+		out[i/8] |= byte(s.bit(s.pos+i) << (7 - uint(i%8)))
+	}
+	s.pos += n
+	return out
+}
+
+//LoadRef read next reference
+func (s *Slice) LoadRef() *Cell {
+	if s.err != nil {
+		return nil
+	}
+	if s.RefsLeft() == 0 {
+		s.fail("no references left")
+		return nil
+	}
+	c := s.cell.refs[s.ref]
+	s.ref++
+	return c
+}
+
+//LoadVarUint read VarUInteger n: byte length in log2(n) bits followed by the value
+func (s *Slice) LoadVarUint(n int) *big.Int {
+	lenBits := 0
+	for 1<<uint(lenBits) < n {
+		lenBits++
+	}
+	l := int(s.LoadUint(lenBits))
+	return s.LoadBigUint(l * 8)
+}
+
+//LoadGrams read Grams (VarUInteger 16) as nanograms
+func (s *Slice) LoadGrams() int64 {
+	v := s.LoadVarUint(16)
+	if !v.IsInt64() {
+		s.fail("grams value %s overflows int64", v)
+		return 0
+	}
+	return v.Int64()
+}
+
+//LoadMaybeRef read Maybe ^X, nil when absent
+func (s *Slice) LoadMaybeRef() *Cell {
+	if !s.LoadBit() {
+		return nil
+	}
+	return s.LoadRef()
+}
@@ -0,0 +1,155 @@
+package cell
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+//walletV3Code code of wallet v3 r2 and its well-known hash
+const (
+	walletV3Code     = "B5EE9C724101010100710000DEFF0020DD2082014C97BA218201339CBAB19F71B0ED44D0D31FD31F31D70BFFE304E0A4F2608308D71820D31FD31FD31FF82313BBF263ED44D0D31FD31FD3FFD15132BAF2A15144BAF2A204F901541055F910F2A3F8009320D74A96D307D402FB00E8D101A4C8CB1FCB1FCBFFC9ED5410BD6DAD"
+	walletV3CodeHash = "84dafa449f98a6987789ba232358072bc0f76dc4524002a5d0918b9a75d2d599"
+)
+
+func mustCell(t *testing.T, b *Builder) *Cell {
+	t.Helper()
+	c, err := b.EndCell()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestHash(t *testing.T) {
+	tests := []struct {
+		name string
+		cell *Cell
+		hash string
+	}{
+		{"empty", mustCell(t, BeginCell()), "96a296d224f285c67bee93c30f8a309157f0daa35dc5b87e410b78630a09cfc7"},
+		{"recover_stake", mustCell(t, BeginCell().StoreUint(0x47657424, 32).StoreUint(1600000001, 64)), "abd50a76537f753d38d71a09889285db94de79dc9558fba0f411d3692a61e5ec"},
+	}
+	for _, tt := range tests {
+		if got := hex.EncodeToString(tt.cell.Hash()); got != tt.hash {
+			t.Errorf("%s: Hash() = %s, want %s", tt.name, got, tt.hash)
+		}
+	}
+}
+
+func TestBOC(t *testing.T) {
+	boc, err := hex.DecodeString(walletV3Code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, err := FromBOCSingle(boc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := hex.EncodeToString(code.Hash()); got != walletV3CodeHash {
+		t.Errorf("wallet code hash %s, want %s", got, walletV3CodeHash)
+	}
+	if got := strings.ToUpper(hex.EncodeToString(code.ToBOC())); got != walletV3Code {
+		t.Errorf("ToBOC() = %s, want %s", got, walletV3Code)
+	}
+
+	// odd bit lengths, a shared subtree and several levels
+	leaf := mustCell(t, BeginCell().StoreUint(5, 3))
+	mid := mustCell(t, BeginCell().StoreUint(0x1ff, 9).StoreRef(leaf))
+	root := mustCell(t, BeginCell().StoreBit(true).StoreRef(mid).StoreRef(leaf).StoreRef(mid))
+	got, err := FromBOCSingle(root.ToBOC())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got.Hash(), root.Hash()) || got.Depth() != 2 {
+		t.Errorf("round trip gives %x of depth %d, want %x of depth 2", got.Hash(), got.Depth(), root.Hash())
+	}
+	if got.Ref(0) != got.Ref(2) {
+		t.Errorf("shared subtree stored twice")
+	}
+	if s := got.Ref(0).BeginParse(); s.LoadUint(9) != 0x1ff || s.BitsLeft() != 0 {
+		t.Errorf("bits of the middle cell lost")
+	}
+}
+
+//exoticCell exotic cell of type with data after the type byte
+func exoticCell(typ byte, data []byte, refs ...*Cell) *Cell {
+	return &Cell{data: append([]byte{typ}, data...), bits: 8 * (1 + len(data)), refs: refs, exotic: true}
+}
+
+//prune pruned branch of c at level 1
+func prune(c *Cell) *Cell {
+	data := append([]byte{1}, c.Hash()...)
+	data = binary.BigEndian.AppendUint16(data, uint16(c.Depth()))
+	return exoticCell(TypePrunedBranch, data)
+}
+
+//proof Merkle proof of c
+func proof(c *Cell) *Cell {
+	data := append([]byte(nil), c.hashAt(0)...)
+	data = binary.BigEndian.AppendUint16(data, uint16(c.depthAt(0)))
+	return exoticCell(TypeMerkleProof, data, c)
+}
+
+func TestCheckProof(t *testing.T) {
+	leaf := mustCell(t, BeginCell().StoreUint(0xdead, 16))
+	kept := mustCell(t, BeginCell().StoreUint(1, 8).StoreRef(leaf))
+	cut := mustCell(t, BeginCell().StoreUint(2, 8).StoreRef(leaf))
+	root := mustCell(t, BeginCell().StoreUint(3, 8).StoreRef(kept).StoreRef(cut))
+
+	partial := mustCell(t, BeginCell().StoreUint(3, 8).StoreRef(kept).StoreRef(prune(cut)))
+	if partial.levelMask() != 1 {
+		t.Fatalf("level mask %d, want 1", partial.levelMask())
+	}
+	if !bytes.Equal(partial.hashAt(0), root.Hash()) || partial.depthAt(0) != root.Depth() {
+		t.Fatalf("pruned tree does not keep the hash and depth of the full one")
+	}
+	if bytes.Equal(partial.Hash(), root.Hash()) {
+		t.Fatalf("pruned tree has the representation hash of the full one")
+	}
+
+	// through BOC to check the level mask survives serialization
+	p, err := FromBOCSingle(proof(partial).ToBOC())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.levelMask() != 0 {
+		t.Fatalf("proof level mask %d, want 0", p.levelMask())
+	}
+	got, err := CheckProof(p, root.Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got.Ref(0).Hash(), kept.Hash()) {
+		t.Errorf("proven root lost its kept branch")
+	}
+	if err = got.Ref(1).BeginParse().Err(); err != ErrPruned {
+		t.Errorf("cut branch: got %v, want %v", err, ErrPruned)
+	}
+
+	if _, err = CheckProof(p, kept.Hash()); err == nil {
+		t.Errorf("proof accepted for another hash")
+	}
+	forged := proof(partial)
+	forged.refs = []*Cell{mustCell(t, BeginCell().StoreUint(4, 8).StoreRef(kept).StoreRef(prune(cut)))}
+	if _, err = CheckProof(forged, root.Hash()); err == nil {
+		t.Errorf("proof accepted with a changed root")
+	}
+	if _, err = CheckProof(root, root.Hash()); err == nil {
+		t.Errorf("ordinary cell accepted as proof")
+	}
+}
+
+func TestFromBOCBadHeader(t *testing.T) {
+	// one byte references and offsets, 2^24-1 cells claimed in a few bytes
+	boc := []byte{0xb5, 0xee, 0x9c, 0x72, 0x03, 0x01, 0xff, 0xff, 0xff, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}
+	if _, err := FromBOC(boc); err == nil {
+		t.Errorf("huge cell count accepted")
+	}
+	boc = []byte{0xb5, 0xee, 0x9c, 0x72, 0x01, 0x01, 0x01, 0x05, 0x00, 0x02, 0x00, 0x00, 0x00, 0x00}
+	if _, err := FromBOC(boc); err == nil {
+		t.Errorf("more roots than cells accepted")
+	}
+}
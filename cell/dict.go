@@ -0,0 +1,118 @@
+package cell
+
+import (
+	"math/big"
+)
+
+//DictEntry key and value of a hashmap leaf
+type DictEntry struct {
+	Key   *big.Int
+	Value *Slice
+}
+
+//loadLabel read HmLabel of a node with m key bits left, returns label bits as an integer and its length
+func loadLabel(s *Slice, m int) (*big.Int, int) {
+	lenBits := 0
+	for 1<<uint(lenBits) <= m {
+		lenBits++
+	}
+	if !s.LoadBit() {
+		// hml_short$0 len:(Unary ~n) s:(n * Bit)
+		n := 0
+		for s.LoadBit() {
+			n++
+		}
+		return s.LoadBigUint(n), n
+	}
+	if !s.LoadBit() {
+		// hml_long$10 n:(#<= m) s:(n * Bit)
+		n := int(s.LoadUint(lenBits))
+		return s.LoadBigUint(n), n
+	}
+	// hml_same$11 v:Bit n:(#<= m)
+	v := s.LoadBit()
+	n := int(s.LoadUint(lenBits))
+	label := new(big.Int)
+	if v {
+		label.Sub(new(big.Int).Lsh(big.NewInt(1), uint(n)), big.NewInt(1))
+	}
+	return label, n
+}
+
+//DictGet look up key in Hashmap n X rooted at root, the value slice is nil when the key is absent
+func DictGet(root *Cell, n int, key *big.Int) (*Slice, error) {
+	c := root
+	m := n
+	for c != nil {
+		s := c.BeginParse()
+		label, l := loadLabel(s, m)
+		if s.Err() != nil {
+			return nil, s.Err()
+		}
+		if l > m {
+			s.fail("hashmap label of %d bits with %d key bits left", l, m)
+			return nil, s.Err()
+		}
+		prefix := new(big.Int).Rsh(key, uint(m-l))
+		prefix.And(prefix, new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), uint(l)), big.NewInt(1)))
+		if prefix.Cmp(label) != 0 {
+			return nil, nil
+		}
+		m -= l
+		if m == 0 {
+			return s, nil
+		}
+		// fork: next key bit chooses the branch
+		branch := int(key.Bit(m - 1))
+		if s.RefsLeft() < 2 {
+			s.fail("hashmap fork without two references")
+			return nil, s.Err()
+		}
+		c = s.cell.refs[branch]
+		m--
+	}
+	return nil, nil
+}
+
+//DictEntries all entries of Hashmap n X rooted at root, in key order
+func DictEntries(root *Cell, n int) ([]DictEntry, error) {
+	if root == nil {
+		return nil, nil
+	}
+	return LoadDictEntries(root.BeginParse(), n)
+}
+
+//LoadDictEntries all entries of Hashmap n X stored inline starting at s
+func LoadDictEntries(s *Slice, n int) ([]DictEntry, error) {
+	var entries []DictEntry
+	var walk func(s *Slice, m int, prefix *big.Int) error
+	walk = func(s *Slice, m int, prefix *big.Int) error {
+		label, l := loadLabel(s, m)
+		if s.Err() != nil {
+			return s.Err()
+		}
+		if l > m {
+			s.fail("hashmap label of %d bits with %d key bits left", l, m)
+			return s.Err()
+		}
+		key := new(big.Int).Lsh(prefix, uint(l))
+		key.Or(key, label)
+		m -= l
+		if m == 0 {
+			entries = append(entries, DictEntry{Key: key, Value: s})
+			return nil
+		}
+		left, right := s.LoadRef(), s.LoadRef()
+		if s.Err() != nil {
+			return s.Err()
+		}
+		if err := walk(left.BeginParse(), m-1, new(big.Int).Lsh(key, 1)); err != nil {
+			return err
+		}
+		return walk(right.BeginParse(), m-1, new(big.Int).SetBit(new(big.Int).Lsh(key, 1), 0, 1))
+	}
+	if err := walk(s, n, new(big.Int)); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
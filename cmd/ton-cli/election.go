@@ -0,0 +1,180 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/mercuryoio/ton-validator/adnl"
+	"github.com/mercuryoio/ton-validator/database"
+	"github.com/mercuryoio/ton-validator/planner"
+	"github.com/mercuryoio/ton-validator/staking"
+	"github.com/mercuryoio/ton-validator/utils"
+	"github.com/mercuryoio/ton-validator/wrappers/liteclient"
+)
+
+//loadPlanner planner over the active election, nil when there is no active election
+func loadPlanner(lc *liteclient.Config) (*planner.Planner, int64, error) {
+	elector, err := lc.GetCurrentElectorAddress()
+	if err != nil {
+		return nil, 0, err
+	}
+	electionID, err := lc.GetActiveElectionID(elector)
+	if err != nil || electionID == 0 {
+		return nil, 0, err
+	}
+	stakeConfig, err := lc.GetStakeConfig()
+	if err != nil {
+		return nil, 0, err
+	}
+	limits, err := lc.GetValidatorsConfig()
+	if err != nil {
+		return nil, 0, err
+	}
+	pl, err := planner.Load(lc, elector, limits, stakeConfig)
+	if err != nil {
+		return nil, 0, err
+	}
+	return pl, electionID, nil
+}
+
+//showActiveElection print the open election and the stakes it takes to get in
+func showActiveElection(lc *liteclient.Config) error {
+	pl, electionID, err := loadPlanner(lc)
+	if err != nil {
+		return err
+	}
+	if pl == nil {
+		fmt.Println("No active election")
+		return nil
+	}
+	periods, err := lc.GetElectionConfig()
+	if err != nil {
+		return err
+	}
+
+	var total int64
+	for _, p := range pl.Participants {
+		total += p.Stake
+	}
+	fmt.Println("Active election ID:", electionID)
+	fmt.Println("Starts at:", formatTime(electionID-periods.ElectionsStartBefore), "\tCloses at:", formatTime(electionID-periods.ElectionsEndBefore))
+	fmt.Println("Participants:", len(pl.Participants), "\tTotal stake:", utils.FormatGrams(total))
+	printSimulation(pl.Simulate(), pl.StakeConfig)
+	return nil
+}
+
+//printSimulation elected validators of the simulated election and the stakes that count in it
+func printSimulation(election planner.Election, stakeConfig liteclient.StakeConfig) {
+	if election.Validators == 0 {
+		fmt.Println("Election would fail with the current participants")
+		return
+	}
+	maxStake := election.Cap(stakeConfig.MaxStakeFactor)
+	if maxStake > stakeConfig.MaxStake {
+		maxStake = stakeConfig.MaxStake
+	}
+	fmt.Println("Elected now:", election.Validators, "\tEffective total stake:", utils.FormatGrams(election.TotalStake))
+	fmt.Println("Min effective stake:", utils.FormatGrams(election.MinStake), "\tMax effective stake:", utils.FormatGrams(maxStake))
+}
+
+//listElections print known elections with our participation and what the elector says about it
+func listElections(s *database.Store, lc *liteclient.Config) error {
+	elections, err := s.GetElections()
+	if err != nil {
+		return err
+	}
+	elector, err := lc.GetCurrentElectorAddress()
+	if err != nil {
+		return err
+	}
+	activeID, err := lc.GetActiveElectionID(elector)
+	if err != nil {
+		return err
+	}
+	pastElections, err := lc.GetPastElections(elector)
+	if err != nil {
+		return err
+	}
+	past := make(map[int64]liteclient.PastElection, len(pastElections))
+	for _, e := range pastElections {
+		past[e.ElectionID] = e
+	}
+
+	for _, election := range elections {
+		fmt.Println("Election ID:", election.ElectionID, "\tStart:", formatTime(election.StartAt), "\tClose:", formatTime(election.CloseAt))
+		participates, err := s.GetElectionParticipates(election.ElectionID)
+		if err != nil {
+			return err
+		}
+		for _, p := range participates {
+			outcome := verifiedOutcome(p)
+			if outcome == "" {
+				if outcome, err = electionOutcome(s, p, activeID, past); err != nil {
+					return err
+				}
+			}
+			fmt.Println("\tNode:", p.NodeID, "\tStake:", p.StakeAmount, "\tMax factor:", p.MaxFactor, "\tOutcome:", outcome)
+		}
+	}
+	return nil
+}
+
+//verifiedOutcome outcome the bot recorded for the participate record, empty if it has not checked it yet
+func verifiedOutcome(p database.Participate) string {
+	switch p.Outcome {
+	case staking.OutcomeElected:
+		return fmt.Sprintf("elected, effective stake %s, weight %d", utils.FormatGrams(p.EffectiveStake), p.Weight)
+	case staking.OutcomeNotElected:
+		return "not elected, stake returned after the election"
+	case staking.OutcomeReturned:
+		return "returned, the elector did not take the stake"
+	case staking.OutcomeUnknown:
+		return "unknown, checked after the stake was unfrozen"
+	}
+	return ""
+}
+
+//electionOutcome what the elector says about the stake of the participate record
+func electionOutcome(s *database.Store, p database.Participate, activeID int64, past map[int64]liteclient.PastElection) (string, error) {
+	if p.ElectionID == activeID {
+		return "election in progress", nil
+	}
+	election, ok := past[p.ElectionID]
+	if !ok {
+		return "no longer held by the elector", nil
+	}
+	pubKey, err := s.GetKey("pubkey", p.NodeID, p.ElectionID)
+	if err == sql.ErrNoRows {
+		return "unknown, no public key stored", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	raw, err := base64.StdEncoding.DecodeString(pubKey.Key)
+	if err != nil {
+		return "", fmt.Errorf("bad public key %s: %v", pubKey.Key, err)
+	}
+	pub, err := adnl.ParsePublicKey(raw)
+	if err != nil {
+		return "", err
+	}
+	frozen, ok := election.Frozen[hex.EncodeToString(pub)]
+	if !ok {
+		return "not elected", nil
+	}
+	outcome := fmt.Sprintf("elected, stake %s, weight %d, unfreezes at %s", utils.FormatGrams(frozen.Stake), frozen.Weight, formatTime(election.UnfreezeAt))
+	if frozen.Banned {
+		outcome += ", banned"
+	}
+	return outcome, nil
+}
+
+func formatTime(unix int64) string {
+	if unix == 0 {
+		return "-"
+	}
+	return time.Unix(unix, 0).UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mercuryoio/ton-validator/database"
+	"github.com/mercuryoio/ton-validator/staking"
+	"github.com/mercuryoio/ton-validator/wrappers/liteclient"
+	"github.com/mercuryoio/ton-validator/wrappers/validator"
+)
+
+//keyMachine staking machine that only needs the node and the election periods
+func keyMachine(s *database.Store, lc *liteclient.Config, vc *validator.Config, nodeID int) (*staking.Machine, database.Node, error) {
+	node, err := s.GetNode(nodeID)
+	if err != nil {
+		return nil, node, fmt.Errorf("node %d: %v", nodeID, err)
+	}
+	periods, err := lc.GetElectionConfig()
+	if err != nil {
+		return nil, node, err
+	}
+	return &staking.Machine{Store: s, Validator: vc, Periods: periods}, node, nil
+}
+
+//listKeys print the keys created on the node by election and whether they are still there
+func listKeys(s *database.Store, lc *liteclient.Config, vc *validator.Config, nodeID int) error {
+	m, _, err := keyMachine(s, lc, vc, nodeID)
+	if err != nil {
+		return err
+	}
+	sets, err := m.NodeKeys(nodeID)
+	if err != nil {
+		return err
+	}
+	now := time.Now().Unix()
+	for _, set := range sets {
+		state := "active"
+		if set.Deleted() {
+			state = "deleted"
+		} else if set.ExpireAt <= now {
+			state = "expired"
+		}
+		fmt.Println("Election ID:", set.ElectionID, "\tExpires:", formatTime(set.ExpireAt), "\tState:", state)
+		for _, k := range set.Keys {
+			fmt.Println("\t", k.Type, k.Key)
+		}
+	}
+	return nil
+}
+
+//pruneKeys remove expired keys from the node
+func pruneKeys(s *database.Store, lc *liteclient.Config, vc *validator.Config, nodeID int, dryRun bool) error {
+	m, node, err := keyMachine(s, lc, vc, nodeID)
+	if err != nil {
+		return err
+	}
+	pruned, err := m.PruneKeys(node, time.Now().Unix(), dryRun)
+	for _, set := range pruned {
+		if dryRun {
+			fmt.Println("Would remove keys of election", set.ElectionID, "expired at", formatTime(set.ExpireAt))
+		} else {
+			fmt.Println("Removed keys of election", set.ElectionID, "expired at", formatTime(set.ExpireAt))
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if len(pruned) == 0 {
+		fmt.Println("No expired keys on node", node.HostPort)
+	}
+	return nil
+}
+
+//verifyNode check the node's keys, with recreate replacing the lost keys of the open election
+func verifyNode(s *database.Store, lc *liteclient.Config, vc *validator.Config, nodeID int, recreate bool) error {
+	m, node, err := keyMachine(s, lc, vc, nodeID)
+	if err != nil {
+		return err
+	}
+	checks, err := m.VerifyKeys(node, time.Now().Unix())
+	if err != nil {
+		return err
+	}
+	if len(checks) == 0 {
+		fmt.Println("No keys in use on node", node.HostPort)
+		return nil
+	}
+	lost := make(map[int64]bool)
+	for _, c := range checks {
+		line := fmt.Sprintf("Election ID: %d\t%s %s\t%s", c.ElectionID, c.Type, c.Key, c.State)
+		switch c.State {
+		case staking.KeyMissing:
+			line += " (" + c.Detail + ")"
+		case staking.KeyMismatch:
+			line += ", node has public key " + c.Detail
+		}
+		fmt.Println(line)
+		if c.State != staking.KeyOK {
+			lost[c.ElectionID] = true
+		}
+	}
+	if len(lost) == 0 {
+		fmt.Println("All keys are on the node")
+		return nil
+	}
+
+	elector, err := lc.GetCurrentElectorAddress()
+	if err != nil {
+		return err
+	}
+	activeID, err := lc.GetActiveElectionID(elector)
+	if err != nil {
+		return err
+	}
+	for electionID := range lost {
+		if electionID != activeID {
+			fmt.Println("Election", electionID, "is closed, restore the node keyring from a backup to keep validating with its keys")
+			continue
+		}
+		if !recreate {
+			fmt.Println("Election", electionID, "is still open, run again with --recreate to replace its keys with new ones")
+			continue
+		}
+		if err = m.RecreateKeys(node, electionID, activeID); err != nil {
+			return err
+		}
+		fmt.Println("Replaced keys of election", electionID, ", the bot signs the election request with the new ones")
+	}
+	return nil
+}
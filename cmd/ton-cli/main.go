@@ -6,42 +6,145 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"time"
 
 	"github.com/mercuryoio/ton-validator/database"
+	"github.com/mercuryoio/ton-validator/keystore"
+	"github.com/mercuryoio/ton-validator/message"
+	"github.com/mercuryoio/ton-validator/staking"
+	"github.com/mercuryoio/ton-validator/wrappers/liteclient"
+	"github.com/mercuryoio/ton-validator/wrappers/validator"
 	"github.com/peterbourgon/ff/ffcli"
 )
 
 func main() {
 	var (
-		rootFlagSet   = flag.NewFlagSet("ton-cli", flag.ExitOnError)
-		nodeFlagSet   = flag.NewFlagSet("ton-cli node", flag.ExitOnError)
-		nodeEnabled   = nodeFlagSet.Int("enabled", 2, "\t\"Filter nodes: 0 - disabled, 1 - enabled, 2 - all\"")
-		walletID      = nodeFlagSet.Int("wallet", 1, "\t\"Filter by wallet ID\"")
-		walletFlagSet = flag.NewFlagSet("ton-cli wallet", flag.ExitOnError)
-		walletEnabled = walletFlagSet.Int("enabled", 2, "\t\"Filter wallets: 0 - disabled, 1 - enabled, 2 - all\"")
-		stakeFlagSet  = flag.NewFlagSet("ton-cli stake", flag.ExitOnError)
+		rootFlagSet      = flag.NewFlagSet("ton-cli", flag.ExitOnError)
+		nodeFlagSet      = flag.NewFlagSet("ton-cli node", flag.ExitOnError)
+		nodeEnabled      = nodeFlagSet.Int("enabled", 2, "\t\"Filter nodes: 0 - disabled, 1 - enabled, 2 - all\"")
+		walletID         = nodeFlagSet.Int("wallet", 1, "\t\"Filter by wallet ID\"")
+		walletFlagSet    = flag.NewFlagSet("ton-cli wallet", flag.ExitOnError)
+		walletEnabled    = walletFlagSet.Int("enabled", 2, "\t\"Filter wallets: 0 - disabled, 1 - enabled, 2 - all\"")
+		walletAddFlagSet = flag.NewFlagSet("ton-cli wallet add", flag.ExitOnError)
+		walletVersion    = walletAddFlagSet.Int("version", 1, "\t\"Wallet contract version: 1, 2 or 3\"")
+		walletSubwallet  = walletAddFlagSet.Int64("subwallet", 0, "\t\"Subwallet ID of v3 wallet, 0 - default\"")
+		walletImport     = walletAddFlagSet.String("import", "", "\t\"Wallet files base, the key of <base>.pk is imported into the keystore\"")
+		historyFlagSet   = flag.NewFlagSet("ton-cli wallet history", flag.ExitOnError)
+		historyLimit     = historyFlagSet.Int("limit", 50, "\t\"Number of latest balance changes to show\"")
+		stakeFlagSet     = flag.NewFlagSet("ton-cli stake", flag.ExitOnError)
+		stakeWallet      = stakeFlagSet.Int("wallet", 0, "\t\"Filter by wallet ID, 0 - all\"")
+		submitFlagSet    = flag.NewFlagSet("ton-cli stake submit", flag.ExitOnError)
+		submitWallet     = submitFlagSet.Int("wallet", 0, "\t\"Wallet ID to stake from\"")
+		submitNode       = submitFlagSet.Int("node", 0, "\t\"Node ID to stake for\"")
+		submitAmount     = submitFlagSet.Int64("amount", 0, "\t\"Stake amount in grams\"")
+		submitMaxFactor  = submitFlagSet.String("max-factor", "2.7", "\t\"Max factor\"")
+		planFlagSet      = flag.NewFlagSet("ton-cli stake plan", flag.ExitOnError)
+		planAmount       = planFlagSet.Int64("amount", 0, "\t\"Stake amount in grams to simulate\"")
+		planMaxFactor    = planFlagSet.String("max-factor", "2.7", "\t\"Max factor\"")
+		recoverFlagSet   = flag.NewFlagSet("ton-cli stake recover", flag.ExitOnError)
+		recoverWallet    = recoverFlagSet.Int("wallet", 0, "\t\"Wallet ID to recover stake to\"")
+		recoverDryRun    = recoverFlagSet.Bool("dry-run", false, "\t\"Show credit and the message without sending it\"")
+		recoverTimeout   = recoverFlagSet.Duration("timeout", 3*time.Minute, "\t\"How long to wait for the elector's answer\"")
+		electionFlagSet  = flag.NewFlagSet("ton-cli election", flag.ExitOnError)
+		messageFlagSet   = flag.NewFlagSet("ton-cli message list", flag.ExitOnError)
+		messageWallet    = messageFlagSet.Int("wallet", 0, "\t\"Filter by wallet ID, 0 - all\"")
+		messageLimit     = messageFlagSet.Int("limit", 50, "\t\"Number of latest messages to show\"")
+		earningsFlagSet  = flag.NewFlagSet("ton-cli report earnings", flag.ExitOnError)
+		earningsFrom     = earningsFlagSet.String("from", "", "\t\"First day of elections to report, YYYY-MM-DD\"")
+		earningsTo       = earningsFlagSet.String("to", "", "\t\"Last day of elections to report, YYYY-MM-DD\"")
+		earningsFormat   = earningsFlagSet.String("format", "csv", "\t\"Output format: csv or json\"")
+		statusFlagSet    = flag.NewFlagSet("ton-cli node status", flag.ExitOnError)
+		verifyFlagSet    = flag.NewFlagSet("ton-cli node verify", flag.ExitOnError)
+		verifyRecreate   = verifyFlagSet.Bool("recreate", false, "\t\"Replace missing keys of the open election with new ones\"")
+		keyListFlagSet   = flag.NewFlagSet("ton-cli key list", flag.ExitOnError)
+		keyListNode      = keyListFlagSet.Int("node", 0, "\t\"Node ID\"")
+		keyPruneFlagSet  = flag.NewFlagSet("ton-cli key prune", flag.ExitOnError)
+		keyPruneNode     = keyPruneFlagSet.Int("node", 0, "\t\"Node ID\"")
+		keyPruneDryRun   = keyPruneFlagSet.Bool("dry-run", false, "\t\"Only show the keys that would be removed\"")
+		policySetFlagSet = flag.NewFlagSet("ton-cli policy set", flag.ExitOnError)
+		policySet        = database.StakePolicy{}
+		policyGetFlagSet = flag.NewFlagSet("ton-cli policy get", flag.ExitOnError)
+		policyGetWallet  = policyGetFlagSet.Int("wallet", 0, "\t\"Wallet ID\"")
+		policyGetNode    = policyGetFlagSet.Int("node", 0, "\t\"Node ID\"")
+		tonlibConfig     = rootFlagSet.String("tonlib-config", "tonlib.config.json", "tonlib config")
+		liteClientConfig = rootFlagSet.String("lite-client-config", "ton-lite-client-test1.config.json", "path to lite-client config")
+		liteTimeout      = rootFlagSet.Duration("lite-client-timeout", 10*time.Second, "lite server connection timeout")
+		validatorTimeout = rootFlagSet.Duration("validator-timeout", 10*time.Second, "validator engine control connection timeout")
+		verbose          = rootFlagSet.Bool("verbose", false, "tool verbosity")
+		signerSpec       = rootFlagSet.String("signer", "", "sign wallet messages with a remote signer at this http(s) URL, empty to use wallet keys")
+		signerToken      = rootFlagSet.String("signer-token", "", "bearer token of the remote signer")
+		passphraseFile   = rootFlagSet.String("keystore-passphrase-file", "", "file with the wallet keystore passphrase, "+keystore.PassphraseEnv+" or a prompt is used without it")
 	)
 
+	thresholds := validator.DefaultThresholds
+	statusFlagSet.Int64Var(&thresholds.SyncLag, "sync-lag", thresholds.SyncLag, "\t\"Seconds the masterchain may be behind before the node is degraded\"")
+	statusFlagSet.Int64Var(&thresholds.DownSyncLag, "down-sync-lag", thresholds.DownSyncLag, "\t\"Seconds the masterchain may be behind before the node is down\"")
+	statusFlagSet.Int64Var(&thresholds.ShardClientLag, "shard-client-lag", thresholds.ShardClientLag, "\t\"Masterchain blocks the shard client may be behind\"")
+	statusFlagSet.Int64Var(&thresholds.StateSerializerLag, "state-serializer-lag", thresholds.StateSerializerLag, "\t\"Masterchain blocks the state serializer may be behind\"")
+	statusFlagSet.Int64Var(&thresholds.TimeDrift, "time-drift", thresholds.TimeDrift, "\t\"Seconds the node's clock may be off\"")
+	statusFlagSet.IntVar(&thresholds.MinPeers, "min-peers", thresholds.MinPeers, "\t\"Fewest overlay peers of a healthy node\"")
+
+	policySetFlagSet.IntVar(&policySet.WalletID, "wallet", 0, "\t\"Set policy of the wallet\"")
+	policySetFlagSet.IntVar(&policySet.NodeID, "node", 0, "\t\"Set policy of the node, it overrides the wallet policy\"")
+	policySetFlagSet.StringVar(&policySet.Mode, "mode", staking.PolicyFixed, "\t\"Stake amount mode: fixed, percent or all-but-reserve\"")
+	policySetFlagSet.Int64Var(&policySet.Amount, "amount", 0, "\t\"Stake amount in grams for fixed mode\"")
+	policySetFlagSet.Float64Var(&policySet.Percent, "percent", 0, "\t\"Percentage of wallet balance for percent mode\"")
+	policySetFlagSet.StringVar(&policySet.MaxFactor, "max-factor", "2.7", "\t\"Max factor\"")
+	policySetFlagSet.Int64Var(&policySet.MinReserve, "min-reserve", 0, "\t\"Grams always kept on the wallet\"")
+	policySetFlagSet.BoolVar(&policySet.SkipNext, "skip-next", false, "\t\"Skip the next election\"")
+
 	s, err := database.NewClient("./ton.db")
 	if err != nil {
 		fmt.Println("Failed to connect to db:", err)
 		os.Exit(1)
 	}
+	liteConfig := liteclient.Config{
+		LiteclientConfig: liteClientConfig,
+		Timeout:          liteTimeout,
+		Verbose:          verbose,
+	}
+	lc := liteclient.NewClient(&liteConfig)
+	defer lc.Close()
+
+	validatorConfig := validator.Config{
+		Timeout: validatorTimeout,
+		Verbose: verbose,
+	}
+	vc := validator.NewClient(&validatorConfig)
+	defer vc.Close()
 
 	addWallet := &ffcli.Command{
 		Name:       "add",
-		ShortUsage: "add <wallet_address> <wallet_file_path>",
-		ShortHelp:  "Add wallet.",
+		ShortUsage: "add --import <wallet_file_base> [-version <n>] [-subwallet <id>] <wallet_address>",
+		ShortHelp:  "Add wallet with its key imported into the keystore.",
+		FlagSet:    walletAddFlagSet,
 		Exec: func(_ context.Context, args []string) error {
-			if n := len(args); n != 2 {
-				return fmt.Errorf("Add wallet requires exactly 2 arguments, but you provided %d", n)
+			if *walletImport == "" {
+				return fmt.Errorf("Add wallet requires --import, key file paths are not stored anymore")
+			}
+			if n := len(args); n != 1 {
+				return fmt.Errorf("Add wallet requires exactly 1 argument, but you provided %d", n)
 			}
-			walletID, err := s.AddWallet(args[1], args[0])
+			if *walletVersion < message.WalletV1 || *walletVersion > message.WalletV3 {
+				return fmt.Errorf("Unsupported wallet version: %d", *walletVersion)
+			}
+			return addWallet(s, *passphraseFile, *walletImport, args[0], *walletVersion, *walletSubwallet)
+		},
+	}
+
+	importWalletCmd := &ffcli.Command{
+		Name:       "import",
+		ShortUsage: "import <id>",
+		ShortHelp:  "Move the key of a wallet added with a key file path into the keystore.",
+		Exec: func(_ context.Context, args []string) error {
+			if n := len(args); n != 1 {
+				return fmt.Errorf("Import wallet requires exactly 1 argument, but you provided %d", n)
+			}
+			id, err := strconv.Atoi(args[0])
 			if err != nil {
-				return err
+				return fmt.Errorf("Bad wallet ID %s: %v", args[0], err)
 			}
-			fmt.Fprintf(os.Stdout, "Added wallet: %s %s with ID: %d\n", args[0], args[1], walletID)
-			return nil
+			return importWallet(s, *passphraseFile, id)
 		},
 	}
 
@@ -76,17 +179,38 @@ func main() {
 				return err
 			}
 			for _, wallet := range wallets {
-				fmt.Println("ID:", wallet.ID, "\tAddress:", wallet.Addr, "\tWallet File:", wallet.FilePath, "\tEnabled:", wallet.Enabled)
+				key := "keystore"
+				if wallet.FilePath != "" {
+					key = wallet.FilePath
+				}
+				fmt.Println("ID:", wallet.ID, "\tAddress:", wallet.Addr, "\tKey:", key, "\tVersion:", wallet.Version, "\tEnabled:", wallet.Enabled)
 			}
 			return nil
 		},
 	}
 
+	walletHistory := &ffcli.Command{
+		Name:       "history",
+		ShortUsage: "history [--limit <n>] <id>",
+		ShortHelp:  "Show balance changes of the wallet.",
+		FlagSet:    historyFlagSet,
+		Exec: func(_ context.Context, args []string) error {
+			if n := len(args); n != 1 {
+				return fmt.Errorf("Wallet history requires exactly 1 argument, but you provided %d", n)
+			}
+			id, err := strconv.Atoi(args[0])
+			if err != nil {
+				return fmt.Errorf("Bad wallet ID %s: %v", args[0], err)
+			}
+			return showBalanceHistory(s, id, *historyLimit)
+		},
+	}
+
 	wallet := &ffcli.Command{
 		Name:        "wallet",
 		ShortUsage:  "wallet [<arg> ...]",
 		ShortHelp:   "Wallet management.",
-		Subcommands: []*ffcli.Command{addWallet, listWallets, delWallet},
+		Subcommands: []*ffcli.Command{addWallet, importWalletCmd, listWallets, delWallet, walletHistory},
 		Exec: func(context.Context, []string) error {
 			return flag.ErrHelp
 		},
@@ -147,11 +271,38 @@ func main() {
 		},
 	}
 
+	nodeStatusCmd := &ffcli.Command{
+		Name:       "status",
+		ShortUsage: "status [--sync-lag <s>] [--down-sync-lag <s>] [--shard-client-lag <n>] [--state-serializer-lag <n>] [--time-drift <s>] [--min-peers <n>]",
+		ShortHelp:  "Show health of all enabled nodes.",
+		FlagSet:    statusFlagSet,
+		Exec: func(_ context.Context, args []string) error {
+			return nodeStatus(s, vc, thresholds)
+		},
+	}
+
+	verifyNodeCmd := &ffcli.Command{
+		Name:       "verify",
+		ShortUsage: "verify [--recreate] <id>",
+		ShortHelp:  "Check that the node has the keys stored for it.",
+		FlagSet:    verifyFlagSet,
+		Exec: func(_ context.Context, args []string) error {
+			if n := len(args); n != 1 {
+				return fmt.Errorf("Verify node requires exactly 1 argument, but you provided %d", n)
+			}
+			id, err := strconv.Atoi(args[0])
+			if err != nil {
+				return fmt.Errorf("Bad node ID %s: %v", args[0], err)
+			}
+			return verifyNode(s, lc, vc, id, *verifyRecreate)
+		},
+	}
+
 	node := &ffcli.Command{
 		Name:        "node",
 		ShortUsage:  "node [<arg> ...]",
 		ShortHelp:   "Node management",
-		Subcommands: []*ffcli.Command{addNode, listNodes, delNode},
+		Subcommands: []*ffcli.Command{addNode, listNodes, delNode, nodeStatusCmd, verifyNodeCmd},
 		Exec: func(context.Context, []string) error {
 			return flag.ErrHelp
 		},
@@ -159,19 +310,76 @@ func main() {
 
 	listStakes := &ffcli.Command{
 		Name:       "list",
-		ShortUsage: "list",
+		ShortUsage: "list [-wallet <id>]",
 		ShortHelp:  "List stakes.",
 		FlagSet:    stakeFlagSet,
 		Exec: func(_ context.Context, args []string) error {
+			stakes, err := s.GetStakes(*stakeWallet)
+			if err != nil {
+				return err
+			}
+			for _, stake := range stakes {
+				fmt.Println("ID:", stake.ID, "\tWallet:", stake.WalletID, "\tNode:", stake.NodeID, "\tElection:", stake.ElectionID, "\tAmount:", stake.StakeAmount, "\tMax factor:", stake.MaxFactor, "\tState:", stake.State)
+				if stake.Error != "" {
+					fmt.Println("\tLast error:", stake.Error)
+				}
+			}
 			return nil
 		},
 	}
 
+	submitStakeCmd := &ffcli.Command{
+		Name:       "submit",
+		ShortUsage: "submit --wallet <id> --node <id> --amount <grams> [--max-factor <f>]",
+		ShortHelp:  "Stake for one node in the active election.",
+		FlagSet:    submitFlagSet,
+		Exec: func(_ context.Context, args []string) error {
+			if *submitWallet == 0 || *submitNode == 0 || *submitAmount <= 0 {
+				return fmt.Errorf("Submit stake requires --wallet, --node and --amount")
+			}
+			m, err := newMachine(s, lc, vc, *tonlibConfig, *passphraseFile, *signerSpec, *signerToken, *verbose)
+			if err != nil {
+				return err
+			}
+			return submitStake(s, lc, m, *submitWallet, *submitNode, *submitAmount, *submitMaxFactor)
+		},
+	}
+
+	planStakeCmd := &ffcli.Command{
+		Name:       "plan",
+		ShortUsage: "plan [--amount <grams>] [--max-factor <f>]",
+		ShortHelp:  "Simulate the active election with a stake.",
+		FlagSet:    planFlagSet,
+		Exec: func(_ context.Context, args []string) error {
+			if *planAmount < 0 {
+				return fmt.Errorf("Stake amount can't be negative")
+			}
+			return showStakePlan(lc, *planAmount, *planMaxFactor)
+		},
+	}
+
+	recoverStakeCmd := &ffcli.Command{
+		Name:       "recover",
+		ShortUsage: "recover --wallet <id> [--dry-run] [--timeout <duration>]",
+		ShortHelp:  "Recover stake and bonuses from the elector.",
+		FlagSet:    recoverFlagSet,
+		Exec: func(_ context.Context, args []string) error {
+			if *recoverWallet == 0 {
+				return fmt.Errorf("Recover stake requires --wallet")
+			}
+			m, err := newMachine(s, lc, vc, *tonlibConfig, *passphraseFile, *signerSpec, *signerToken, *verbose)
+			if err != nil {
+				return err
+			}
+			return recoverStake(s, m, *recoverWallet, *recoverDryRun, *recoverTimeout)
+		},
+	}
+
 	stake := &ffcli.Command{
 		Name:        "stake",
 		ShortUsage:  "stake [<arg> ...]",
 		ShortHelp:   "Participate in election with stake.",
-		Subcommands: []*ffcli.Command{listStakes},
+		Subcommands: []*ffcli.Command{listStakes, submitStakeCmd, planStakeCmd, recoverStakeCmd},
 		Exec: func(context.Context, []string) error {
 			return flag.ErrHelp
 		},
@@ -181,21 +389,19 @@ func main() {
 		Name:       "active",
 		ShortUsage: "active",
 		ShortHelp:  "Get active election id.",
-		FlagSet:    stakeFlagSet,
+		FlagSet:    electionFlagSet,
 		Exec: func(_ context.Context, args []string) error {
-			fmt.Println("To Be Done")
-			return nil
+			return showActiveElection(lc)
 		},
 	}
 
-	listElections := &ffcli.Command{
+	listElectionsCmd := &ffcli.Command{
 		Name:       "list",
 		ShortUsage: "list",
 		ShortHelp:  "List elections.",
-		FlagSet:    stakeFlagSet,
+		FlagSet:    electionFlagSet,
 		Exec: func(_ context.Context, args []string) error {
-
-			return nil
+			return listElections(s, lc)
 		},
 	}
 
@@ -203,7 +409,113 @@ func main() {
 		Name:        "election",
 		ShortUsage:  "election [<arg> ...]",
 		ShortHelp:   "Show elections information.",
-		Subcommands: []*ffcli.Command{listElections, activeElection},
+		Subcommands: []*ffcli.Command{listElectionsCmd, activeElection},
+		Exec: func(context.Context, []string) error {
+			return flag.ErrHelp
+		},
+	}
+
+	setPolicyCmd := &ffcli.Command{
+		Name:       "set",
+		ShortUsage: "set (--wallet <id> | --node <id>) --mode <mode> [--amount <grams>] [--percent <p>] [--max-factor <f>] [--min-reserve <grams>] [--skip-next]",
+		ShortHelp:  "Set stake policy of a wallet or a node.",
+		FlagSet:    policySetFlagSet,
+		Exec: func(_ context.Context, args []string) error {
+			return setPolicy(s, policySet)
+		},
+	}
+
+	getPolicyCmd := &ffcli.Command{
+		Name:       "get",
+		ShortUsage: "get (--wallet <id> | --node <id>)",
+		ShortHelp:  "Show stake policy of a wallet or the one that applies to a node.",
+		FlagSet:    policyGetFlagSet,
+		Exec: func(_ context.Context, args []string) error {
+			return getPolicy(s, *policyGetWallet, *policyGetNode)
+		},
+	}
+
+	policy := &ffcli.Command{
+		Name:        "policy",
+		ShortUsage:  "policy [<arg> ...]",
+		ShortHelp:   "Stake policies of wallets and nodes.",
+		Subcommands: []*ffcli.Command{setPolicyCmd, getPolicyCmd},
+		Exec: func(context.Context, []string) error {
+			return flag.ErrHelp
+		},
+	}
+
+	listKeysCmd := &ffcli.Command{
+		Name:       "list",
+		ShortUsage: "list --node <id>",
+		ShortHelp:  "List validator keys created on the node.",
+		FlagSet:    keyListFlagSet,
+		Exec: func(_ context.Context, args []string) error {
+			if *keyListNode == 0 {
+				return fmt.Errorf("List keys requires --node")
+			}
+			return listKeys(s, lc, vc, *keyListNode)
+		},
+	}
+
+	pruneKeysCmd := &ffcli.Command{
+		Name:       "prune",
+		ShortUsage: "prune --node <id> [--dry-run]",
+		ShortHelp:  "Remove expired validator keys from the node.",
+		FlagSet:    keyPruneFlagSet,
+		Exec: func(_ context.Context, args []string) error {
+			if *keyPruneNode == 0 {
+				return fmt.Errorf("Prune keys requires --node")
+			}
+			return pruneKeys(s, lc, vc, *keyPruneNode, *keyPruneDryRun)
+		},
+	}
+
+	keys := &ffcli.Command{
+		Name:        "key",
+		ShortUsage:  "key [<arg> ...]",
+		ShortHelp:   "Validator keys of nodes.",
+		Subcommands: []*ffcli.Command{listKeysCmd, pruneKeysCmd},
+		Exec: func(context.Context, []string) error {
+			return flag.ErrHelp
+		},
+	}
+
+	listMessagesCmd := &ffcli.Command{
+		Name:       "list",
+		ShortUsage: "list [--wallet <id>] [--limit <n>]",
+		ShortHelp:  "List messages sent from wallets.",
+		FlagSet:    messageFlagSet,
+		Exec: func(_ context.Context, args []string) error {
+			return listMessages(s, *messageWallet, *messageLimit)
+		},
+	}
+
+	messages := &ffcli.Command{
+		Name:        "message",
+		ShortUsage:  "message [<arg> ...]",
+		ShortHelp:   "Journal of messages sent from wallets.",
+		Subcommands: []*ffcli.Command{listMessagesCmd},
+		Exec: func(context.Context, []string) error {
+			return flag.ErrHelp
+		},
+	}
+
+	earningsCmd := &ffcli.Command{
+		Name:       "earnings",
+		ShortUsage: "earnings [--from <YYYY-MM-DD>] [--to <YYYY-MM-DD>] [--format csv|json]",
+		ShortHelp:  "Stakes, returns, bonuses and fees per election with APY.",
+		FlagSet:    earningsFlagSet,
+		Exec: func(_ context.Context, args []string) error {
+			return reportEarnings(s, lc, *earningsFrom, *earningsTo, *earningsFormat)
+		},
+	}
+
+	report := &ffcli.Command{
+		Name:        "report",
+		ShortUsage:  "report [<arg> ...]",
+		ShortHelp:   "Accounting reports.",
+		Subcommands: []*ffcli.Command{earningsCmd},
 		Exec: func(context.Context, []string) error {
 			return flag.ErrHelp
 		},
@@ -212,7 +524,7 @@ func main() {
 	root := &ffcli.Command{
 		ShortUsage:  "ton-cli [flags] <subcommand>",
 		FlagSet:     rootFlagSet,
-		Subcommands: []*ffcli.Command{wallet, node, stake, election},
+		Subcommands: []*ffcli.Command{wallet, node, stake, election, policy, keys, messages, report},
 		Exec: func(context.Context, []string) error {
 			return flag.ErrHelp
 		},
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/mercuryoio/ton-validator/database"
+	"github.com/mercuryoio/ton-validator/utils"
+)
+
+//listMessages print the latest messages sent from wallets, newest first
+func listMessages(s *database.Store, walletID, limit int) error {
+	if limit <= 0 {
+		return fmt.Errorf("Limit must be positive")
+	}
+	messages, err := s.GetMessages(walletID, limit)
+	if err != nil {
+		return err
+	}
+	for _, m := range messages {
+		fmt.Println("ID:", m.ID, "\tWallet:", m.WalletID, "\tSeqno:", m.Seqno, "\tPurpose:", m.Purpose, "\tAmount:", utils.FormatGrams(m.Amount), "\tTo:", m.Destination, "\tState:", m.State, "\tAttempts:", m.Attempts)
+		fmt.Println("\tHash:", m.Hash, "\tCreated:", m.CreatedAt, "\tSent:", orDash(m.SentAt), "\tConfirmed:", orDash(m.ConfirmedAt))
+		if m.TxHash != "" {
+			fmt.Println("\tTransaction:", m.TxLt, m.TxHash, "\tFee:", utils.FormatGrams(m.Fee))
+		}
+		if m.Error != "" {
+			fmt.Println("\tLast error:", m.Error)
+		}
+	}
+	return nil
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
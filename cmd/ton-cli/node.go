@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mercuryoio/ton-validator/database"
+	"github.com/mercuryoio/ton-validator/staking"
+	"github.com/mercuryoio/ton-validator/wrappers/validator"
+)
+
+//nodeStatus check the health of enabled nodes of all wallets at once
+func nodeStatus(s *database.Store, vc *validator.Config, t validator.Thresholds) error {
+	wallets, err := s.GetWallets(2)
+	if err != nil {
+		return err
+	}
+	var nodes []database.Node
+	for _, wallet := range wallets {
+		walletNodes, err := s.GetNodes(wallet.ID, 1)
+		if err != nil {
+			return err
+		}
+		nodes = append(nodes, walletNodes...)
+	}
+	if len(nodes) == 0 {
+		fmt.Println("No enabled nodes")
+		return nil
+	}
+	reports := make([]validator.Health, len(nodes))
+	pool := staking.NewPool(8)
+	for i, node := range nodes {
+		i, node := i, node
+		pool.Go(func() {
+			reports[i] = vc.NodeHealth(node, t)
+		})
+	}
+	pool.Wait()
+
+	for i, h := range reports {
+		fmt.Println("ID:", nodes[i].ID, "\tAddress:", h.Node, "\tHealth:", h.Verdict)
+		if h.Verdict != validator.HealthDown {
+			fmt.Println("\tMasterchain seqno:", h.Stats.MasterchainSeqno, "\tSync lag:", h.SyncLag, "s", "\tShard client lag:", h.ShardClientLag, "\tState serializer lag:", h.StateSerializerLag, "\tTime drift:", h.TimeDrift, "s")
+			fmt.Println("\tActive validator keys:", orUnknown(h.ActiveKeys), "\tPeers:", orUnknown(h.Peers))
+		}
+		if len(h.Problems) > 0 {
+			fmt.Println("\tProblems:", strings.Join(h.Problems, ", "))
+		}
+	}
+	return nil
+}
+
+//orUnknown count or ? when the node didn't report it
+func orUnknown(n int) string {
+	if n < 0 {
+		return "?"
+	}
+	return fmt.Sprint(n)
+}
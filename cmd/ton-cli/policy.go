@@ -0,0 +1,81 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/mercuryoio/ton-validator/database"
+	"github.com/mercuryoio/ton-validator/staking"
+)
+
+//setPolicy save the stake policy of a wallet or a node
+func setPolicy(s *database.Store, p database.StakePolicy) error {
+	if err := staking.CheckPolicy(p); err != nil {
+		return err
+	}
+	if p.WalletID != 0 {
+		if _, err := s.GetWallet(p.WalletID); err != nil {
+			return fmt.Errorf("wallet %d: %v", p.WalletID, err)
+		}
+	} else if _, err := s.GetNode(p.NodeID); err != nil {
+		return fmt.Errorf("node %d: %v", p.NodeID, err)
+	}
+	// an election that is already being skipped stays skipped
+	old, err := s.GetStakePolicy(p.WalletID, p.NodeID)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	p.SkippedElection = old.SkippedElection
+	if err = s.SetStakePolicy(p); err != nil {
+		return err
+	}
+	printPolicy("Saved policy:", p)
+	return nil
+}
+
+//getPolicy print the stake policy of a wallet, or the one that applies to a node
+func getPolicy(s *database.Store, walletID, nodeID int) error {
+	if (walletID == 0) == (nodeID == 0) {
+		return fmt.Errorf("Get policy requires either --wallet or --node")
+	}
+	if nodeID != 0 {
+		p, err := s.GetStakePolicy(0, nodeID)
+		if err == nil {
+			printPolicy("Node policy:", p)
+			return nil
+		}
+		if err != sql.ErrNoRows {
+			return err
+		}
+		node, err := s.GetNode(nodeID)
+		if err != nil {
+			return fmt.Errorf("node %d: %v", nodeID, err)
+		}
+		fmt.Println("Node", nodeID, "has no policy of its own, the policy of wallet", node.WalletID, "applies")
+		walletID = node.WalletID
+	}
+	p, err := s.GetStakePolicy(walletID, 0)
+	if err == sql.ErrNoRows {
+		fmt.Println("Wallet", walletID, "has no policy, the bot stakes -stake-amount with -max-factor")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	printPolicy("Wallet policy:", p)
+	return nil
+}
+
+func printPolicy(title string, p database.StakePolicy) {
+	amount := "-"
+	switch p.Mode {
+	case staking.PolicyFixed:
+		amount = fmt.Sprintf("%d", p.Amount)
+	case staking.PolicyPercent:
+		amount = fmt.Sprintf("%g%%", p.Percent)
+	}
+	fmt.Println(title, "\tWallet:", p.WalletID, "\tNode:", p.NodeID, "\tMode:", p.Mode, "\tAmount:", amount, "\tMax factor:", p.MaxFactor, "\tMin reserve:", p.MinReserve, "\tSkip next:", p.SkipNext)
+	if p.SkippedElection != 0 {
+		fmt.Println("\tLast skipped election:", p.SkippedElection)
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/mercuryoio/ton-validator/database"
+	"github.com/mercuryoio/ton-validator/staking"
+	"github.com/mercuryoio/ton-validator/utils"
+	"github.com/mercuryoio/ton-validator/wrappers/liteclient"
+)
+
+const secondsPerYear = 365 * 24 * 3600
+
+//earningsRow ledger entry of one wallet in one election as reported
+type earningsRow struct {
+	ElectionID    int64  `json:"election_id"`
+	Date          string `json:"date"`
+	WalletID      int    `json:"wallet_id"`
+	StakeSent     string `json:"stake_sent"`
+	StakeReturned string `json:"stake_returned"`
+	Bonus         string `json:"bonus"`
+	Fees          string `json:"fees"`
+	Net           string `json:"net"`
+	//APY and CumulativeAPY percents, empty while the stake is not returned
+	APY           string `json:"apy"`
+	CumulativeAPY string `json:"cumulative_apy"`
+}
+
+//reportEarnings print the earnings ledger of elections held between from and to (YYYY-MM-DD) as csv or json
+func reportEarnings(s *database.Store, lc *liteclient.Config, from, to, format string) error {
+	if format != "csv" && format != "json" {
+		return fmt.Errorf("Unknown format %q, use csv or json", format)
+	}
+	fromTime, toTime := int64(0), time.Now().Unix()
+	if from != "" {
+		t, err := time.Parse("2006-01-02", from)
+		if err != nil {
+			return fmt.Errorf("Bad --from date: %v", err)
+		}
+		fromTime = t.Unix()
+	}
+	if to != "" {
+		t, err := time.Parse("2006-01-02", to)
+		if err != nil {
+			return fmt.Errorf("Bad --to date: %v", err)
+		}
+		// the whole last day
+		toTime = t.Unix() + 24*3600 - 1
+	}
+	periods, err := lc.GetElectionConfig()
+	if err != nil {
+		return err
+	}
+	// a stake is locked from the close of the election until it is unfrozen
+	locked := periods.ElectionsEndBefore + periods.ValidatorsElectedFor + periods.StakeHeldFor
+
+	earnings, err := s.GetEarnings(fromTime, toTime)
+	if err != nil {
+		return err
+	}
+	rows := make([]earningsRow, 0, len(earnings))
+	var totalStake, totalNet int64
+	for _, e := range earnings {
+		row := earningsRow{
+			ElectionID:    e.ElectionID,
+			Date:          time.Unix(e.ElectionID, 0).UTC().Format("2006-01-02"),
+			WalletID:      e.WalletID,
+			StakeSent:     utils.FormatGrams(e.StakeSent),
+			StakeReturned: utils.FormatGrams(e.StakeReturned),
+			Bonus:         utils.FormatGrams(e.Bonus),
+			Fees:          utils.FormatGrams(e.Fees),
+			Net:           utils.FormatGrams(e.Net()),
+		}
+		if e.ElectionID == staking.WalletLedgerID {
+			row.Date = ""
+		}
+		if e.StakeReturned > 0 && e.StakeSent > 0 {
+			row.APY = formatAPY(e.Net(), e.StakeSent, locked)
+			totalStake += e.StakeSent
+			totalNet += e.Net()
+			row.CumulativeAPY = formatAPY(totalNet, totalStake, locked)
+		}
+		rows = append(rows, row)
+	}
+
+	if format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(rows)
+	}
+	w := csv.NewWriter(os.Stdout)
+	w.Write([]string{"election_id", "date", "wallet_id", "stake_sent", "stake_returned", "bonus", "fees", "net", "apy", "cumulative_apy"})
+	for _, r := range rows {
+		w.Write([]string{strconv.FormatInt(r.ElectionID, 10), r.Date, strconv.Itoa(r.WalletID), r.StakeSent, r.StakeReturned, r.Bonus, r.Fees, r.Net, r.APY, r.CumulativeAPY})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+//formatAPY yearly yield in percent of net earned on stake locked for seconds, compounded every lock period
+func formatAPY(net, stake, seconds int64) string {
+	if stake <= 0 || seconds <= 0 {
+		return ""
+	}
+	r := float64(net) / float64(stake)
+	apy := math.Pow(1+r, float64(secondsPerYear)/float64(seconds)) - 1
+	return strconv.FormatFloat(apy*100, 'f', 2, 64)
+}
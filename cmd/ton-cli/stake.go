@@ -0,0 +1,205 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mercuryoio/ton-validator/database"
+	"github.com/mercuryoio/ton-validator/keystore"
+	"github.com/mercuryoio/ton-validator/message"
+	"github.com/mercuryoio/ton-validator/signer"
+	"github.com/mercuryoio/ton-validator/staking"
+	"github.com/mercuryoio/ton-validator/utils"
+	"github.com/mercuryoio/ton-validator/wrappers/liteclient"
+	"github.com/mercuryoio/ton-validator/wrappers/validator"
+	tonlib "github.com/mercuryoio/tonlib-go/v2"
+)
+
+//tonlibClient tonlib client for the config file
+func tonlibClient(configPath string, verbose bool) (*tonlib.Client, error) {
+	options, err := tonlib.ParseConfigFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse tonlib config: %v", err)
+	}
+	req := tonlib.TonInitRequest{
+		Type:    "init",
+		Options: *options,
+	}
+	cln, err := tonlib.NewClient(&req, tonlib.Config{}, 60, verbose, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to init tonlib client: %v", err)
+	}
+	return cln, nil
+}
+
+//newMachine staking machine for one-shot commands, reading network parameters from lite servers
+func newMachine(s *database.Store, lc *liteclient.Config, vc *validator.Config, tonlibConfig, passphraseFile, signerSpec, signerToken string, verbose bool) (*staking.Machine, error) {
+	elector, err := lc.GetCurrentElectorAddress()
+	if err != nil {
+		return nil, err
+	}
+	periods, err := lc.GetElectionConfig()
+	if err != nil {
+		return nil, err
+	}
+	stakeConfig, err := lc.GetStakeConfig()
+	if err != nil {
+		return nil, err
+	}
+	limits, err := lc.GetValidatorsConfig()
+	if err != nil {
+		return nil, err
+	}
+	cln, err := tonlibClient(tonlibConfig, verbose)
+	if err != nil {
+		return nil, err
+	}
+	walletSigner, err := signer.New(signerSpec, signerToken)
+	if err != nil {
+		return nil, err
+	}
+	var keys *keystore.Keystore
+	if empty, err := keystore.New(s).Empty(); err != nil {
+		return nil, err
+	} else if !empty && walletSigner == nil {
+		if keys, err = openKeystore(s, passphraseFile); err != nil {
+			return nil, err
+		}
+	}
+	return &staking.Machine{
+		Store:       s,
+		Ton:         cln,
+		Validator:   vc,
+		ElectorAddr: elector,
+		Periods:     periods,
+		StakeConfig: stakeConfig,
+		Lite:        lc,
+		Limits:      limits,
+		Keys:        keys,
+		Signer:      walletSigner,
+	}, nil
+}
+
+//submitStake stake amount grams from the wallet for the node in the active election
+func submitStake(s *database.Store, lc *liteclient.Config, m *staking.Machine, walletID, nodeID int, amount int64, maxFactor string) error {
+	wallet, err := s.GetWallet(walletID)
+	if err != nil {
+		return fmt.Errorf("wallet %d: %v", walletID, err)
+	}
+	node, err := s.GetNode(nodeID)
+	if err != nil {
+		return fmt.Errorf("node %d: %v", nodeID, err)
+	}
+	factor, err := message.ParseMaxFactor(maxFactor)
+	if err != nil {
+		return err
+	}
+	stakeConfig := m.StakeConfig
+	if amount*1e9 < stakeConfig.MinStake {
+		return fmt.Errorf("stake %d is below the network minimum of %s", amount, utils.FormatGrams(stakeConfig.MinStake))
+	}
+	if int64(factor) > stakeConfig.MaxStakeFactor {
+		return fmt.Errorf("max factor %s is above the network maximum of %d", maxFactor, stakeConfig.MaxStakeFactor/65536)
+	}
+	state, err := m.Ton.GetAccountState(*tonlib.NewAccountAddress(wallet.Addr))
+	if err != nil {
+		return fmt.Errorf("getAccountState failed: %v", err)
+	}
+	// one gram on top pays for the wallet and elector fees
+	if int64(state.Balance) < (amount+1)*1e9 {
+		return fmt.Errorf("wallet balance %s is not enough for stake of %d", utils.FormatGrams(int64(state.Balance)), amount)
+	}
+	activeElectionID, err := lc.GetActiveElectionID(m.ElectorAddr)
+	if err != nil {
+		return err
+	}
+
+	stake, err := m.Submit(wallet, node, activeElectionID, amount, maxFactor)
+	if err != nil {
+		return err
+	}
+	fmt.Println("Stake", stake.ID, "of node", node.ID, "in election", stake.ElectionID, "is", stake.State)
+	return nil
+}
+
+//showStakePlan simulate the active election with a stake of amount grams, or find the smallest one that wins
+func showStakePlan(lc *liteclient.Config, amount int64, maxFactor string) error {
+	factor, err := message.ParseMaxFactor(maxFactor)
+	if err != nil {
+		return err
+	}
+	pl, electionID, err := loadPlanner(lc)
+	if err != nil {
+		return err
+	}
+	if pl == nil {
+		fmt.Println("No active election")
+		return nil
+	}
+	fmt.Println("Active election ID:", electionID, "\tParticipants:", len(pl.Participants))
+	printSimulation(pl.Simulate(), pl.StakeConfig)
+
+	minWinning := pl.MinWinningStake(nil, int64(factor))
+	if minWinning == 0 {
+		fmt.Println("No stake gets elected with max factor", maxFactor)
+	} else {
+		fmt.Println("Smallest winning stake with max factor", maxFactor+":", utils.FormatGrams(minWinning))
+	}
+	if amount == 0 {
+		return nil
+	}
+
+	sized, plan := pl.Size(nil, amount*1e9, int64(factor))
+	if !plan.Elected {
+		fmt.Println("Stake of", amount, "would not be elected")
+		return nil
+	}
+	fmt.Println("Stake of", amount, "would be elected with effective stake", utils.FormatGrams(plan.Effective))
+	printSimulation(plan.Election, pl.StakeConfig)
+	if sized < amount*1e9 {
+		fmt.Println("The elector would return", utils.FormatGrams(amount*1e9-sized), "- the bot stakes", utils.FormatGrams(sized))
+	}
+	return nil
+}
+
+//recoverStake ask the elector to return the wallet's credit and report its answer
+func recoverStake(s *database.Store, m *staking.Machine, walletID int, dryRun bool, timeout time.Duration) error {
+	wallet, err := s.GetWallet(walletID)
+	if err != nil {
+		return fmt.Errorf("wallet %d: %v", walletID, err)
+	}
+	credit, err := m.Credit(wallet)
+	if err != nil {
+		return err
+	}
+	fmt.Println("Elector holds", utils.FormatGrams(credit), "for wallet", wallet.Addr)
+	if credit == 0 {
+		fmt.Println("Nothing to recover")
+		return nil
+	}
+	if dryRun {
+		msg, queryID, err := m.RecoverMessage(wallet)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Would send recover_stake with query ID %d, unsigned message hash %X\n", queryID, msg.Hash())
+		return nil
+	}
+
+	fmt.Println("Sending recover_stake from wallet", wallet.Addr, "and waiting for the elector's answer")
+	recovery, err := m.RecoverAndWait(wallet, timeout)
+	if err != nil {
+		return err
+	}
+	switch recovery.Result {
+	case staking.RecoveryCredited:
+		fmt.Println("Recovered", utils.FormatGrams(recovery.Amount), "in transaction", recovery.TxHash)
+	case staking.RecoveryRejected:
+		fmt.Println("Elector had nothing to return, got back", utils.FormatGrams(recovery.Amount))
+	case staking.RecoveryBounced:
+		fmt.Println("Request bounced, got back", utils.FormatGrams(recovery.Amount))
+	default:
+		fmt.Println("No answer from the elector within", timeout, "- the bot closes the stakes once it answers")
+	}
+	return nil
+}
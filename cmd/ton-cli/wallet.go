@@ -0,0 +1,122 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"fmt"
+	"strings"
+
+	"github.com/mercuryoio/ton-validator/adnl"
+	"github.com/mercuryoio/ton-validator/database"
+	"github.com/mercuryoio/ton-validator/keystore"
+	"github.com/mercuryoio/ton-validator/message"
+	"github.com/mercuryoio/ton-validator/utils"
+)
+
+//showBalanceHistory print the latest balance changes of the wallet, newest first
+func showBalanceHistory(s *database.Store, walletID, limit int) error {
+	if limit <= 0 {
+		return fmt.Errorf("Limit must be positive")
+	}
+	wallet, err := s.GetWallet(walletID)
+	if err != nil {
+		return fmt.Errorf("wallet %d: %v", walletID, err)
+	}
+	history, err := s.GetBalanceHistory(walletID, limit)
+	if err != nil {
+		return err
+	}
+	fmt.Println("Wallet:", wallet.Addr)
+	for _, b := range history {
+		change := utils.FormatGrams(b.Change)
+		if b.Change > 0 {
+			change = "+" + change
+		}
+		fmt.Println(b.CreatedAt, "\tBalance:", utils.FormatGrams(b.Balance), "\tChange:", change, "\tLast transaction:", b.TxLt, b.TxHash)
+	}
+	return nil
+}
+
+//openKeystore keystore unlocked with the passphrase, the passphrase of a new keystore is asked twice
+func openKeystore(s *database.Store, passphraseFile string) (*keystore.Keystore, error) {
+	keys := keystore.New(s)
+	empty, err := keys.Empty()
+	if err != nil {
+		return nil, err
+	}
+	passphrase, err := keystore.ReadPassphrase(passphraseFile, empty)
+	if err != nil {
+		return nil, err
+	}
+	if err = keys.Unlock(passphrase); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+//readWalletFiles private key of <base>.pk checked against the address in <base>.addr
+func readWalletFiles(base, address string) (ed25519.PrivateKey, error) {
+	base = strings.TrimSuffix(base, ".pk")
+	addr, err := message.ParseAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	fileAddr, err := message.ReadAddressFile(base + ".addr")
+	if err != nil {
+		return nil, err
+	}
+	if !fileAddr.Equal(addr) {
+		return nil, fmt.Errorf("%s.addr holds address %s, not %s", base, fileAddr, address)
+	}
+	return adnl.ReadPrivateKey(base + ".pk")
+}
+
+//addWallet add the wallet with its key imported from <base>.pk into the keystore
+func addWallet(s *database.Store, passphraseFile, base, address string, version int, subwalletID int64) error {
+	key, err := readWalletFiles(base, address)
+	if err != nil {
+		return err
+	}
+	keys, err := openKeystore(s, passphraseFile)
+	if err != nil {
+		return err
+	}
+	walletID, err := s.AddWallet("", address, version, subwalletID)
+	if err != nil {
+		return err
+	}
+	if err = keys.Import(int(walletID), key); err != nil {
+		if derr := s.DelWallet(int(walletID)); derr != nil {
+			fmt.Println("Failed to remove wallet", walletID, derr)
+		}
+		return fmt.Errorf("failed to import key: %v", err)
+	}
+	fmt.Printf("Added wallet: %s with ID: %d, key imported from %s.pk\n", address, walletID, strings.TrimSuffix(base, ".pk"))
+	return nil
+}
+
+//importWallet move the key of a wallet added with a key file path into the keystore
+func importWallet(s *database.Store, passphraseFile string, walletID int) error {
+	wallet, err := s.GetWallet(walletID)
+	if err != nil {
+		return fmt.Errorf("wallet %d: %v", walletID, err)
+	}
+	if wallet.FilePath == "" {
+		return fmt.Errorf("Wallet %d has no key file, its key is in the keystore already", walletID)
+	}
+	key, err := readWalletFiles(wallet.FilePath, wallet.Addr)
+	if err != nil {
+		return err
+	}
+	keys, err := openKeystore(s, passphraseFile)
+	if err != nil {
+		return err
+	}
+	if err = keys.Import(walletID, key); err != nil {
+		return err
+	}
+	if err = s.ClearWalletFile(walletID); err != nil {
+		return err
+	}
+	fmt.Println("Imported key of wallet", walletID, "from", wallet.FilePath, ", the key files may be removed now")
+	return nil
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/mercuryoio/ton-validator/database"
+	"github.com/mercuryoio/ton-validator/keystore"
+	"github.com/mercuryoio/ton-validator/message"
+	"github.com/mercuryoio/ton-validator/signer"
+	"github.com/peterbourgon/ff"
+)
+
+var (
+	listenAddr     string
+	dbFile         string
+	passphraseFile string
+	allow          string
+	maxAmount      int64
+	token          string
+	certFile       string
+	keyFile        string
+)
+
+func getConfig() error {
+	fs := flag.NewFlagSet("ton-signer", flag.ExitOnError)
+	fs.StringVar(&listenAddr, "listen", "127.0.0.1:8731", "address to serve signing requests on")
+	fs.StringVar(&dbFile, "db-file", "./ton.db", "path to db file with the wallets and their keystore")
+	fs.StringVar(&passphraseFile, "keystore-passphrase-file", "", "file with the wallet keystore passphrase, "+keystore.PassphraseEnv+" or a prompt is used without it")
+	fs.StringVar(&allow, "allow", "", "comma separated destinations wallets may send to, e.g. the elector address")
+	fs.Int64Var(&maxAmount, "max-amount", 0, "most grams one message may send")
+	fs.StringVar(&token, "token", "", "bearer token clients must send")
+	fs.StringVar(&certFile, "tls-cert", "", "TLS certificate, plain HTTP without it")
+	fs.StringVar(&keyFile, "tls-key", "", "TLS key")
+	_ = fs.String("config", "", "config file (optional)")
+
+	return ff.Parse(fs, os.Args[1:],
+		ff.WithConfigFileFlag("config"),
+		ff.WithConfigFileParser(ff.JSONParser),
+		ff.WithEnvVarPrefix("TON_SIGNER"),
+	)
+}
+
+func main() {
+	if err := getConfig(); err != nil {
+		log.Fatalln(err)
+	}
+	policy, err := parsePolicy(allow, maxAmount)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	s, err := database.NewClient(dbFile)
+	if err != nil {
+		log.Fatalln("Failed to connect to db:", err)
+	}
+	signers, err := loadSigners(s)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	server := &signer.Server{Signers: signers, Policy: policy, Token: token}
+	log.Println("Signing for", len(signers), "wallets on", listenAddr)
+	if certFile != "" {
+		err = http.ListenAndServeTLS(listenAddr, certFile, keyFile, server)
+	} else {
+		err = http.ListenAndServe(listenAddr, server)
+	}
+	log.Fatalln(err)
+}
+
+//parsePolicy policy of the allowed destinations and the max amount in grams
+func parsePolicy(allow string, maxAmount int64) (signer.Policy, error) {
+	var policy signer.Policy
+	for _, a := range strings.Split(allow, ",") {
+		if a = strings.TrimSpace(a); a == "" {
+			continue
+		}
+		addr, err := message.ParseAddress(a)
+		if err != nil {
+			return policy, err
+		}
+		policy.Allow = append(policy.Allow, addr)
+	}
+	if len(policy.Allow) == 0 {
+		return policy, fmt.Errorf("no destinations allowed, pass -allow")
+	}
+	if maxAmount <= 0 {
+		return policy, fmt.Errorf("-max-amount must be positive")
+	}
+	policy.MaxAmount = maxAmount * 1e9
+	return policy, nil
+}
+
+//loadSigners signers of all wallets with keys in the keystore or in key files
+func loadSigners(s *database.Store) (map[string]message.Signer, error) {
+	wallets, err := s.GetWallets(2)
+	if err != nil {
+		return nil, err
+	}
+	keys := keystore.New(s)
+	if empty, err := keys.Empty(); err != nil {
+		return nil, err
+	} else if !empty {
+		passphrase, err := keystore.ReadPassphrase(passphraseFile, false)
+		if err != nil {
+			return nil, err
+		}
+		if err = keys.Unlock(passphrase); err != nil {
+			return nil, fmt.Errorf("failed to unlock keystore: %v", err)
+		}
+	}
+	signers := make(map[string]message.Signer)
+	for _, wallet := range wallets {
+		addr, err := message.ParseAddress(wallet.Addr)
+		if err != nil {
+			return nil, fmt.Errorf("wallet %d: %v", wallet.ID, err)
+		}
+		if _, ok := keys.Key(wallet.ID); ok {
+			signers[addr.String()] = signer.NewKeystore(keys, wallet.ID)
+			continue
+		}
+		if wallet.FilePath == "" {
+			log.Println("No key of wallet", wallet.ID, wallet.Addr)
+			continue
+		}
+		log.Printf("Warning: wallet %d signs with the unencrypted key file %s, import it with `ton-cli wallet import %d`", wallet.ID, wallet.FilePath, wallet.ID)
+		local, fileAddr, err := signer.LoadFile(wallet.FilePath)
+		if err != nil {
+			return nil, fmt.Errorf("wallet %d: %v", wallet.ID, err)
+		}
+		if !fileAddr.Equal(addr) {
+			return nil, fmt.Errorf("wallet %d: %s is for address %s", wallet.ID, wallet.FilePath, fileAddr)
+		}
+		signers[addr.String()] = local
+	}
+	return signers, nil
+}
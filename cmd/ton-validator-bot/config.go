@@ -3,51 +3,78 @@ package main
 import (
 	"flag"
 	"os"
+	"time"
 
+	"github.com/mercuryoio/ton-validator/keystore"
+	"github.com/mercuryoio/ton-validator/notify"
+	"github.com/mercuryoio/ton-validator/wrappers/validator"
 	"github.com/peterbourgon/ff"
 )
 
 var (
-	fiftBin                 string
-	fiftPath                string
-	liteClient              string
-	liteclientConfig        string
-	dbFile                  string
-	tonlibConfig            string
-	validatorConsole        string
-	validatorWalletAddr     string
-	maxFactor               string
-	validatorHost           string
-	stakeAmount             int
-	walletFif               string
-	recoverFif              string
-	validatorElectReqFif    string
-	validatorElectSignedFif string
-	validatorClientCert     string
-	validatorServerPub      string
-	validatorWalletFile     string
-	verbose                 bool
-	verboseTonlib           int
+	liteClientTimeout   time.Duration
+	liteclientConfig    string
+	dbFile              string
+	tonlibConfig        string
+	validatorTimeout    time.Duration
+	validatorWalletAddr string
+	maxFactor           string
+	validatorHost       string
+	stakeAmount         int
+	validatorClientCert string
+	validatorServerPub  string
+	verbose             bool
+	verboseTonlib       int
+	metricsAddr         string
+	notifyConfig        notify.Config
+	cmdTimeout          time.Duration
+	cmdRetries          int
+	cmdTools            string
+	workers             int
+	reconcileInterval   time.Duration
+	passphraseFile      string
+	signerSpec          string
+	signerToken         string
+	health              validator.Thresholds
 )
 
 // GetConfig Gets the conf in the config file
 func GetConfig() error {
 	fs := flag.NewFlagSet("ton-validator", flag.ExitOnError)
-	fs.StringVar(&fiftBin, "fift-bin", "fift", "path to fift binary")
-	fs.StringVar(&fiftPath, "fift-path", "crypto/fift/lib/", "path to fift lib")
-	fs.StringVar(&liteClient, "lite-client", "lite-client", "path to lite-client binary")
 	fs.StringVar(&liteclientConfig, "lite-client-config", "ton-lite-client-test1.config.json", "path to lite-client config")
+	fs.DurationVar(&liteClientTimeout, "lite-client-timeout", 10*time.Second, "lite server connection timeout")
 	fs.StringVar(&tonlibConfig, "tonlib-config", "tonlib.config.json", "tonlib config")
 	fs.StringVar(&dbFile, "db-file", "./ton.db", "path to db file")
-	fs.StringVar(&validatorConsole, "validator-console", "validator-engine-console", "path to validator-engine-console binary")
+	fs.DurationVar(&validatorTimeout, "validator-timeout", 10*time.Second, "validator engine control connection timeout")
 	fs.StringVar(&maxFactor, "max-factor", "2.7", "max factor")
 	fs.IntVar(&stakeAmount, "stake-amount", 20000, "stake amount")
-	fs.StringVar(&walletFif, "wallet-fif", "crypto/smartcont/wallet.fif", "path to wallet.fif file")
-	fs.StringVar(&recoverFif, "recover-fif", "crypto/smartcont/recover-stake.fif", "path to recover-stake.fif file")
-	fs.StringVar(&validatorElectReqFif, "validator-elect-req-fif", "crypto/smartcont/validator-elect-req.fif", "path to validator-elect-req.fif file")
-	fs.StringVar(&validatorElectSignedFif, "validator-elect-signed-fif", "crypto/smartcont/validator-elect-signed.fif", "path to validator-elect-signed.fif file")
 	fs.BoolVar(&verbose, "verbose", false, "tool verbosity")
 	fs.IntVar(&verboseTonlib, "verbose-tonlib", 0, "tonlib versbosity")
+	fs.StringVar(&metricsAddr, "metrics-addr", "", "address to serve prometheus /metrics on, empty to disable")
+	fs.StringVar(&notifyConfig.WebhookURL, "notify-webhook", "", "URL to post alerts to as JSON")
+	fs.StringVar(&notifyConfig.SMTPAddr, "notify-smtp-addr", "", "SMTP server host:port to mail alerts through")
+	fs.StringVar(&notifyConfig.SMTPUser, "notify-smtp-user", "", "SMTP user")
+	fs.StringVar(&notifyConfig.SMTPPassword, "notify-smtp-password", "", "SMTP password")
+	fs.StringVar(&notifyConfig.SMTPFrom, "notify-smtp-from", "", "alert mail sender")
+	fs.StringVar(&notifyConfig.SMTPTo, "notify-smtp-to", "", "comma separated alert mail recipients")
+	fs.StringVar(&notifyConfig.TelegramToken, "notify-telegram-token", "", "Telegram bot token to send alerts with")
+	fs.StringVar(&notifyConfig.TelegramChatID, "notify-telegram-chat", "", "Telegram chat ID to send alerts to")
+	fs.DurationVar(&notifyConfig.RepeatAfter, "notify-repeat", time.Hour, "how long the same alert stays silenced")
+	fs.IntVar(&notifyConfig.MaxPerHour, "notify-max-per-hour", 30, "alerts delivered per hour, 0 - unlimited")
+	fs.DurationVar(&cmdTimeout, "cmd-timeout", time.Minute, "timeout of external commands")
+	fs.IntVar(&cmdRetries, "cmd-retries", 0, "retries of failed external commands")
+	fs.StringVar(&cmdTools, "cmd-tools", "", "per tool timeout and retries, e.g. lite-client=20s/2,fift=5s")
+	fs.IntVar(&workers, "workers", 4, "nodes processed concurrently")
+	fs.DurationVar(&reconcileInterval, "reconcile-interval", 5*time.Minute, "how often sent messages are matched with wallet transactions, 0 to disable")
+	fs.StringVar(&passphraseFile, "keystore-passphrase-file", "", "file with the wallet keystore passphrase, "+keystore.PassphraseEnv+" or a prompt is used without it")
+	fs.StringVar(&signerSpec, "signer", "", "sign wallet messages with a remote signer at this http(s) URL, empty to use wallet keys")
+	fs.StringVar(&signerToken, "signer-token", "", "bearer token of the remote signer")
+	fs.Int64Var(&health.SyncLag, "health-sync-lag", validator.DefaultThresholds.SyncLag, "seconds the masterchain may be behind before the node is degraded")
+	fs.Int64Var(&health.DownSyncLag, "health-down-sync-lag", validator.DefaultThresholds.DownSyncLag, "seconds the masterchain may be behind before the node is down")
+	fs.Int64Var(&health.ShardClientLag, "health-shard-client-lag", validator.DefaultThresholds.ShardClientLag, "masterchain blocks the shard client may be behind")
+	fs.Int64Var(&health.StateSerializerLag, "health-state-serializer-lag", validator.DefaultThresholds.StateSerializerLag, "masterchain blocks the state serializer may be behind")
+	fs.Int64Var(&health.TimeDrift, "health-time-drift", validator.DefaultThresholds.TimeDrift, "seconds the node's clock may be off")
+	fs.IntVar(&health.MinPeers, "health-min-peers", validator.DefaultThresholds.MinPeers, "fewest overlay peers of a healthy node")
 	_ = fs.String("config", "", "config file (optional)")
 
 	err := ff.Parse(fs, os.Args[1:],
@@ -1,15 +1,22 @@
 package main
 
 import (
+	"database/sql"
 	"fmt"
 	"github.com/mercuryoio/ton-validator/database"
+	"github.com/mercuryoio/ton-validator/keystore"
+	"github.com/mercuryoio/ton-validator/message"
+	"github.com/mercuryoio/ton-validator/metrics"
+	"github.com/mercuryoio/ton-validator/notify"
+	"github.com/mercuryoio/ton-validator/signer"
+	"github.com/mercuryoio/ton-validator/staking"
 	"github.com/mercuryoio/ton-validator/utils"
-	"github.com/mercuryoio/ton-validator/wrappers/fift"
 	"github.com/mercuryoio/ton-validator/wrappers/liteclient"
 	"github.com/mercuryoio/ton-validator/wrappers/validator"
 	tonlib "github.com/mercuryoio/tonlib-go/v2"
 	"log"
 	"os"
+	"strings"
 	"time"
 )
 
@@ -35,37 +42,59 @@ func GetTonlibClient() *tonlib.Client {
 
 func main() {
 	GetConfig()
+	if _, err := message.ParseMaxFactor(maxFactor); err != nil {
+		log.Println(err)
+		os.Exit(1)
+	}
+	tools, err := utils.ParseTools(cmdTools)
+	if err != nil {
+		log.Println(err)
+		os.Exit(1)
+	}
+	utils.DefaultRunner.Default = utils.Tool{Timeout: cmdTimeout, Retries: cmdRetries, RetryDelay: time.Second}
+	utils.DefaultRunner.Tools = tools
+	utils.DefaultRunner.Verbose = verbose
+	utils.DefaultRunner.Observe = metrics.ObserveCommand
+	if metricsAddr != "" {
+		metrics.Serve(metricsAddr)
+	}
 	cln := GetTonlibClient()
 	s, err := database.NewClient(dbFile)
 	if err != nil {
 		fmt.Println("Failed to connect to db:", err)
 		os.Exit(1)
 	}
-
-	fiftConfig := fift.Config{
-		FiftBin:                 &fiftBin,
-		FiftPath:                &fiftPath,
-		WalletFif:               &walletFif,
-		RecoverFif:              &recoverFif,
-		ValidatorElectReqFif:    &validatorElectReqFif,
-		ValidatorElectSignedFif: &validatorElectSignedFif,
-		ValidatorWalletFile:     &validatorWalletFile,
-		Verbose:                 &verbose,
+	walletSigner, err := signer.New(signerSpec, signerToken)
+	if err != nil {
+		log.Println(err)
+		os.Exit(1)
+	}
+	// with a signer the keys stay off this host
+	var keys *keystore.Keystore
+	if walletSigner == nil {
+		keys, err = unlockKeystore(s)
+		if err != nil {
+			log.Println("Failed to unlock keystore:", err)
+			os.Exit(1)
+		}
+	} else {
+		log.Println("Wallet messages are signed by", signerSpec)
 	}
-	f := fift.NewClient(&fiftConfig)
 
 	liteConfig := liteclient.Config{
-		LiteClient:       &liteClient,
 		LiteclientConfig: &liteclientConfig,
+		Timeout:          &liteClientTimeout,
 		Verbose:          &verbose,
 	}
 	lc := liteclient.NewClient(&liteConfig)
+	defer lc.Close()
 
 	validatorConfig := validator.Config{
-		ValidatorConsole: &validatorConsole,
-		Verbose:          &verbose,
+		Timeout: &validatorTimeout,
+		Verbose: &verbose,
 	}
 	vc := validator.NewClient(&validatorConfig)
+	defer vc.Close()
 	err = s.SyncWalletsBalance(cln)
 	if err != nil {
 		log.Println(err)
@@ -78,261 +107,301 @@ func main() {
 	}
 	fmt.Println("Current elector Address:", currentElectorAddress)
 
-	periods, _ := lc.GetElectionConfig()
+	periods, err := lc.GetElectionConfig()
+	if err != nil {
+		log.Println("Election config failed", err)
+		os.Exit(1)
+	}
 	fmt.Println("Network configuration:")
 	fmt.Printf("\tvalidators_elected_for: %d\telections_start_before: %d\telections_end_before: %d\tstake_held_for: %d\t\n", periods.ValidatorsElectedFor, periods.ElectionsStartBefore, periods.ElectionsEndBefore, periods.StakeHeldFor)
 
 	stakeConfig, err := lc.GetStakeConfig()
+	if err != nil {
+		log.Println("Stake config failed", err)
+		os.Exit(1)
+	}
 	fmt.Println("Network stake config:")
 	fmt.Printf("\tmin_stake: %s\tmax_stake: %s\tmin_total_stake: %s\tmax_stake_factor: %d (%d)\t\n", utils.FormatGrams(stakeConfig.MinStake), utils.FormatGrams(stakeConfig.MaxStake), utils.FormatGrams(stakeConfig.MinTotalStake), stakeConfig.MaxStakeFactor, (stakeConfig.MaxStakeFactor / 65536))
 
+	limits, err := lc.GetValidatorsConfig()
+	if err != nil {
+		log.Println("Validators config failed", err)
+		os.Exit(1)
+	}
+	fmt.Printf("\tmin_validators: %d\tmax_validators: %d\t\n", limits.MinValidators, limits.MaxValidators)
+
+	alerts := notify.NewDispatcher(notifyConfig)
+	machine := &staking.Machine{
+		Store:       s,
+		Ton:         cln,
+		Validator:   vc,
+		ElectorAddr: currentElectorAddress,
+		Periods:     periods,
+		StakeConfig: stakeConfig,
+		Lite:        lc,
+		Limits:      limits,
+		StakeAmount: stakeAmount,
+		MaxFactor:   maxFactor,
+		Notifier:    alerts,
+		Keys:        keys,
+		Signer:      walletSigner,
+	}
+
+	checkKeys(s, machine, alerts)
+
+	if reconcileInterval > 0 {
+		go reconcileMessages(s, machine, reconcileInterval)
+	}
+
 	ticker := time.NewTicker(60 * time.Second)
 	defer ticker.Stop()
 
 	for range ticker.C {
-		err = cln.UpdateTonConnection()
-		if err != nil {
-			log.Fatalln("UpdateTonConnection", err)
+		if err := runElection(s, cln, vc, machine, alerts, stakeConfig); err != nil {
+			alerts.Notify(notify.NewEvent(notify.EventBotError, err.Error(), "%v", err))
 		}
+	}
+}
 
-		activeElectionID, err := cln.GetActiveElectionID(currentElectorAddress)
-
-		if err != nil {
-			log.Fatalln("GetActiveElectionID failed", err)
-		}
+//unlockKeystore unlock the wallet keystore, nil while no wallet key is imported
+func unlockKeystore(s *database.Store) (*keystore.Keystore, error) {
+	keys := keystore.New(s)
+	empty, err := keys.Empty()
+	if err != nil {
+		return nil, err
+	}
+	if empty {
+		log.Println("Keystore is empty, wallets are signed with their key files")
+		return nil, nil
+	}
+	passphrase, err := keystore.ReadPassphrase(passphraseFile, false)
+	if err != nil {
+		return nil, err
+	}
+	if err = keys.Unlock(passphrase); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
 
-		if activeElectionID != 0 {
+//runElection one pass over all wallets and their nodes
+func runElection(s *database.Store, cln *tonlib.Client, vc *validator.Config, machine *staking.Machine, alerts *notify.Dispatcher, stakeConfig liteclient.StakeConfig) error {
+	err := cln.UpdateTonConnection()
+	if err != nil {
+		return fmt.Errorf("UpdateTonConnection: %v", err)
+	}
 
-			log.Println("Active election ID:", activeElectionID)
-			/* minEffStake, maxEffStake, err := cln.GetElectionStackes(currentElectorAddress, stakeConfig.minStake, stakeConfig.maxStake, stakeConfig.maxStakeFactor, 13)
+	activeElectionID, err := cln.GetActiveElectionID(machine.ElectorAddr)
+	if err != nil {
+		return fmt.Errorf("GetActiveElectionID failed: %v", err)
+	}
+	metrics.ActiveElectionID.Set(float64(activeElectionID))
 
-			fmt.Println("minimal effective stake:", formatGrams(minEffStake), "maximal effective stake", formatGrams(maxEffStake)) */
-			election, err := s.GetElection(activeElectionID)
-			if err != nil {
-				log.Println("Failed to get election from db:", err)
+	if activeElectionID != 0 {
+		log.Println("Active election ID:", activeElectionID)
+		election, err := s.GetElection(activeElectionID)
+		if err != nil && err != sql.ErrNoRows {
+			return fmt.Errorf("Failed to get election from db: %v", err)
+		}
+		if election.ElectionID != 0 {
+			log.Println("Election from db", election.ElectionID)
+		} else {
+			election = database.Election{
+				ElectionID:      activeElectionID,
+				StartAt:         activeElectionID - machine.Periods.ElectionsStartBefore,
+				CloseAt:         activeElectionID - machine.Periods.ElectionsEndBefore,
+				NextElectionsAt: activeElectionID + machine.Periods.ElectionsStartBefore,
 			}
-			if election.ElectionID != 0 {
-				log.Println("Election from db", election.ElectionID)
-			} else {
-				election = database.Election{
-					ElectionID:      activeElectionID,
-					StartAt:         activeElectionID - periods.ElectionsStartBefore,
-					CloseAt:         activeElectionID - periods.ElectionsEndBefore,
-					NextElectionsAt: activeElectionID + periods.ElectionsStartBefore,
-				}
-				_, err := s.AddElection(election)
-				if err != nil {
-					log.Fatalln("Failed to add election to db:", err)
-				}
+			if _, err := s.AddElection(election); err != nil {
+				return fmt.Errorf("Failed to add election to db: %v", err)
 			}
 		}
+	}
 
-		wallets, err := s.GetWallets(1)
+	wallets, err := s.GetWallets(1)
+	if err != nil {
+		return err
+	}
+	if len(wallets) == 0 {
+		return fmt.Errorf("No wallets found")
+	}
+	pool := staking.NewPool(workers)
+	var passes []*staking.WalletPass
+	for _, wallet := range wallets {
+		AccountState, err := cln.GetAccountState(*tonlib.NewAccountAddress(wallet.Addr))
 		if err != nil {
-			log.Fatalln(err)
-		}
-		if len(wallets) == 0 {
-			log.Fatalln("No wallets found")
+			log.Println("getAccountState failed", wallet.Addr, err)
+			continue
 		}
-		for _, wallet := range wallets {
-			err = cln.UpdateTonConnection()
-			if err != nil {
-				log.Fatalln("UpdateTonConnection", err)
-			}
-			AccountState, err := cln.GetAccountState(*tonlib.NewAccountAddress(wallet.Addr))
-			if err != nil {
-				log.Println("getAccountState failed", err)
-				os.Exit(1)
-			}
-
-			log.Println("Wallet", wallet.Addr, "balance", utils.FormatGrams(wallet.Balance))
 
-			if wallet.Balance < int64(AccountState.Balance) {
-				log.Printf("Balance changed: %s (+%s)", utils.FormatGrams(int64(AccountState.Balance)), utils.FormatGrams(int64(AccountState.Balance)-wallet.Balance))
-				s.UpdateWalletBalance(wallet.ID, int64(AccountState.Balance))
-			} else if wallet.Balance > int64(AccountState.Balance) {
-				log.Printf("Balance changed: %s (-%s)", utils.FormatGrams(int64(AccountState.Balance)), utils.FormatGrams(wallet.Balance-int64(AccountState.Balance)))
-				s.UpdateWalletBalance(wallet.ID, int64(AccountState.Balance))
-			}
-			err = cln.UpdateTonConnection()
-			if err != nil {
-				log.Fatalln("UpdateTonConnection", err)
-			}
-			unpackedAddress, err := cln.UnpackAccountAddress(wallet.Addr)
+		log.Println("Wallet", wallet.Addr, "balance", utils.FormatGrams(wallet.Balance))
 
-			if err != nil {
-				log.Println("UnpackAccountAddress failed", err)
-				break
-			}
-			err = cln.UpdateTonConnection()
-			if err != nil {
-				log.Fatalln("UpdateTonConnection", err)
-			}
-			reward, _ := cln.CheckReward(utils.PubKeyToHex(unpackedAddress.Addr), currentElectorAddress)
-			if reward != 0 {
-				log.Printf("Sending request to recover %12s GRAMs\n", utils.FormatGrams(reward))
-				err = cln.UpdateTonConnection()
-				if err != nil {
-					log.Fatalln("UpdateTonConnection", err)
-				}
-				recoverSeqno, err := cln.GetWalletSeqno(wallet.Addr)
-				if err != nil {
-					log.Fatalln("GetWalletSeqno failed:", err)
-				}
-				f.RecoverStake(cln, wallet.FilePath, currentElectorAddress, recoverSeqno)
-			}
+		lastTx := AccountState.LastTransactionId
+		change, err := s.UpdateWalletBalance(wallet.ID, int64(AccountState.Balance), int64(lastTx.Lt), lastTx.Hash)
+		if err != nil {
+			log.Println("Failed to save balance of", wallet.Addr, err)
+		} else if change > 0 {
+			log.Printf("Balance changed: %s (+%s)", utils.FormatGrams(int64(AccountState.Balance)), utils.FormatGrams(change))
+		} else if change < 0 {
+			log.Printf("Balance changed: %s (-%s)", utils.FormatGrams(int64(AccountState.Balance)), utils.FormatGrams(-change))
+		}
+		wallet.Balance = int64(AccountState.Balance)
+		metrics.WalletBalance.WithLabelValues(wallet.Addr).Set(metrics.Grams(wallet.Balance))
 
+		canStake := false
+		if activeElectionID != 0 {
 			if wallet.Balance < stakeConfig.MinStake {
-				log.Println("Account balance is too low, can't stake, skipping")
-				continue
+				alerts.Notify(notify.NewEvent(notify.EventLowBalance, wallet.Addr, "Balance %s is below the min stake of %s, can't stake in election %d", utils.FormatGrams(wallet.Balance), utils.FormatGrams(stakeConfig.MinStake), activeElectionID))
+			} else {
+				alerts.Resolve(notify.EventLowBalance, wallet.Addr)
+				canStake = true
 			}
+		}
 
-			if activeElectionID == 0 {
-				continue
-			}
+		pass, err := machine.BeginWallet(wallet, activeElectionID)
+		if err != nil {
+			log.Println("Wallet", wallet.Addr, err)
+			continue
+		}
+		passes = append(passes, pass)
 
-			nodes, err := s.GetNodes(wallet.ID, 1)
-			if err != nil {
-				log.Fatalln(err)
-			}
-			if len(nodes) == 0 {
-				log.Println("No nodes found for wallet", wallet.Addr)
+		nodes, err := s.GetNodes(wallet.ID, 1)
+		if err != nil {
+			log.Println("Failed to get nodes for wallet", wallet.Addr, err)
+		} else if len(nodes) == 0 {
+			log.Println("No nodes found for wallet", wallet.Addr)
+		}
+		enabled := make(map[int]bool)
+		for _, node := range nodes {
+			node := node
+			enabled[node.ID] = true
+			pool.Go(func() {
+				processNode(s, vc, machine, alerts, pass, node, activeElectionID, canStake)
+			})
+		}
+		// disabled nodes still finish the stakes they have sent
+		for _, nodeID := range pass.NodeIDs() {
+			if enabled[nodeID] {
 				continue
 			}
-			for _, node := range nodes {
-				log.Println(node.HostPort)
-				if !vc.CheckNodeSync(node) {
-					fmt.Println("Validator node is out of sync")
-				}
-
-				participates := s.GetParticipates(node.ID, activeElectionID)
-				if len(participates) == 0 {
-					log.Println("Not participating")
-				} else {
-					for i, p := range participates {
-						log.Println("Participate #", i, p)
-					}
-					//continue
-				}
-
-				validatorKey, err := s.GetKey("key", node.ID, activeElectionID)
-				if err != nil {
-					log.Println("Failed to get key from", node.HostPort, err)
-				}
-				if validatorKey.Key == "" {
-					validatorKey, err = vc.ValidatorCreateNewKey(node, activeElectionID)
-					if err != nil {
-						log.Fatalln("validatorCreateNewKey failed", err)
-					}
-					log.Println("Created new key:", validatorKey)
-					keyID, err := s.AddKey(validatorKey)
-					if err != nil {
-						log.Println("failed to save key to db", keyID, err)
-					}
-					if vc.ValidatorAddPermKey(node, validatorKey.Key, activeElectionID) {
-						log.Println("Added permKey", validatorKey.Key, activeElectionID)
-					}
-
-					if vc.ValidatorAddTempKey(node, validatorKey.Key, validatorKey.Key, activeElectionID+int64(periods.ValidatorsElectedFor)+10000) {
-						log.Println("Added tempKey", validatorKey.Key, activeElectionID)
-					}
-				}
-
-				pubKey, err := s.GetKey("pubkey", node.ID, activeElectionID)
-				if err != nil {
-					log.Println("Failed to get pubkey from", node.HostPort, err)
-				}
-				if pubKey.Key == "" {
-					pubKey, err = vc.ValidatorGetPublicKey(node, validatorKey.Key, activeElectionID)
-					if err != nil {
-						log.Fatalln("validatorGetPublicKey failed", node.HostPort, err)
-					}
-					log.Println(pubKey)
-					pubKeyID, err := s.AddKey(pubKey)
-					if err != nil {
-						log.Println("failed to save key to db", pubKeyID, pubKey.Key, err)
-					}
-				}
-				err = cln.UpdateTonConnection()
-				if err != nil {
-					log.Fatalln("UpdateTonConnection", err)
-				}
-				amount, err := cln.CheckParticipatesIn(utils.PubKeyToHex(pubKey.Key), currentElectorAddress)
-				if err != nil {
-					log.Fatalln("CheckParticipatesIn failed:", err)
-				}
-				if amount > 0 {
-					log.Println("Already participating as", utils.PubKeyToHex(pubKey.Key), "with", utils.FormatGrams(amount), "stake")
-					continue
+			nodeID := nodeID
+			pool.Go(func() {
+				if err := machine.AdvanceNode(pass, nodeID); err != nil {
+					log.Println("Node", nodeID, err)
 				}
+			})
+		}
+	}
+	pool.Wait()
 
-				validatorAdnlKey, err := s.GetKey("adnlkey", node.ID, activeElectionID)
-				if err != nil {
-					log.Println("Failed to get adnlkey from", node.HostPort, err)
-				}
-				if validatorAdnlKey.Key == "" {
-
-					validatorAdnlKey, err = vc.ValidatorCreateNewKey(node, activeElectionID)
-					if err != nil {
-						log.Fatalln("adnl validatorCreateNewKey failed", err)
-					}
-					log.Println(validatorAdnlKey.Key)
-					validatorAdnlKey.Type = "adnlkey"
-					keyID, err := s.AddKey(validatorAdnlKey)
-					if err != nil {
-						log.Println("failed to save key to db", keyID, err)
-					}
-					if vc.ValidatorAddAdnl(node, validatorAdnlKey.Key, 0) {
-						log.Println("Added ADNL for key hash:", validatorAdnlKey.Key)
-					}
-
-					if vc.ValidatorAddValidatorAddr(node, validatorKey.Key, validatorAdnlKey.Key, activeElectionID+70000) {
-						log.Println("Added validator addres for key hash:", validatorKey.Key, validatorAdnlKey.Key)
-					}
-				}
+	for _, pass := range passes {
+		if err := machine.EndWallet(pass); err != nil {
+			log.Println("Wallet", pass.Wallet.Addr, err)
+		}
+	}
 
-				log.Println("loaded adnlkey from db", validatorAdnlKey.Key)
+	if n, err := machine.VerifyOutcomes(activeElectionID); err != nil {
+		log.Println("Failed to verify election outcomes:", err)
+	} else if n > 0 {
+		log.Println("Verified outcome of", n, "participations")
+	}
+	return nil
+}
 
-				fiftElectReq, _ := f.FiftValidatorElectReq(wallet.Addr, activeElectionID, maxFactor, validatorAdnlKey.Key)
-				log.Println("fiftelectreq", fiftElectReq)
+//processNode report the node, make sure it has a stake in the active election and advance its stakes
+func processNode(s *database.Store, vc *validator.Config, machine *staking.Machine, alerts *notify.Dispatcher, pass *staking.WalletPass, node database.Node, activeElectionID int64, canStake bool) {
+	reportNode(vc, alerts, node)
+	if canStake {
+		if _, err := machine.Discover(pass.Wallet, node, activeElectionID); err != nil && err != staking.ErrSkipped {
+			log.Println("Failed to discover stake for", node.HostPort, err)
+		}
+	}
+	if err := machine.AdvanceNode(pass, node.ID); err != nil {
+		log.Println("Node", node.HostPort, err)
+	}
+	if _, err := machine.PruneKeys(node, time.Now().Unix(), false); err != nil {
+		log.Println("Failed to remove expired keys from", node.HostPort, err)
+	}
+	var submitted int64
+	if stake, err := s.GetStake(pass.Wallet.ID, node.ID, activeElectionID); err == nil && staking.Submitted(stake.State) {
+		submitted = stake.StakeAmount * 1e9
+	}
+	metrics.StakeSubmitted.WithLabelValues(node.HostPort).Set(metrics.Grams(submitted))
+}
 
-				signature, err := vc.ValidatorSign(node, validatorKey.Key, fiftElectReq)
-				if err != nil {
-					log.Fatalln("validatorSign failed", err)
-				}
+//reportNode export sync lag and health of the node, alerting when it is not healthy
+func reportNode(vc *validator.Config, alerts *notify.Dispatcher, node database.Node) {
+	h := vc.NodeHealth(node, health)
+	if h.Verdict != validator.HealthDown {
+		metrics.NodeSyncLag.WithLabelValues(node.HostPort).Set(float64(h.SyncLag))
+	}
+	switch h.Verdict {
+	case validator.HealthHealthy:
+		metrics.NodeHealth.WithLabelValues(node.HostPort).Set(0)
+		alerts.Resolve(notify.EventNodeOutOfSync, node.HostPort)
+	case validator.HealthDegraded:
+		metrics.NodeHealth.WithLabelValues(node.HostPort).Set(1)
+		alerts.Notify(notify.NewEvent(notify.EventNodeOutOfSync, node.HostPort, "Node is degraded: %s", strings.Join(h.Problems, ", ")))
+	default:
+		metrics.NodeHealth.WithLabelValues(node.HostPort).Set(2)
+		alerts.Notify(notify.NewEvent(notify.EventNodeOutOfSync, node.HostPort, "Node is down: %s", strings.Join(h.Problems, ", ")))
+	}
+}
 
-				f.FiftValidatorElectSigned(wallet.Addr, activeElectionID, maxFactor, validatorAdnlKey.Key, pubKey.Key, signature)
-				err = cln.UpdateTonConnection()
-				if err != nil {
-					log.Fatalln("UpdateTonConnection", err)
-				}
-				seqno, err := cln.GetWalletSeqno(wallet.Addr)
-				if err != nil {
-					log.Fatalln("GetWalletSeqno failed:", err)
-				}
-				walletQueryFile, err := f.FiftWalletQuery(wallet.FilePath, currentElectorAddress, seqno, stakeAmount, "validator-query.boc")
-				if err != nil {
-					log.Println(err)
-				}
-				err = cln.UpdateTonConnection()
-				if err != nil {
-					log.Fatalln("UpdateTonConnection", err)
-				}
-				cln.TonlibSendFile(walletQueryFile)
-				participate := database.Participate{
-					NodeID:      node.ID,
-					ElectionID:  activeElectionID,
-					StakeAmount: int64(stakeAmount),
-					MaxFactor:   maxFactor,
-				}
-				_, err = s.AddParticipate(participate)
-				if err != nil {
-					log.Println("Failed to add participate record to DB:", err)
+//checkKeys make sure the nodes of enabled wallets still have the keys stored for them
+func checkKeys(s *database.Store, machine *staking.Machine, alerts *notify.Dispatcher) {
+	wallets, err := s.GetWallets(1)
+	if err != nil {
+		log.Println("Failed to get wallets:", err)
+		return
+	}
+	for _, wallet := range wallets {
+		nodes, err := s.GetNodes(wallet.ID, 1)
+		if err != nil {
+			log.Println("Failed to get nodes for wallet", wallet.Addr, err)
+			continue
+		}
+		for _, node := range nodes {
+			checks, err := machine.VerifyKeys(node, time.Now().Unix())
+			if err != nil {
+				log.Println("Failed to verify keys of", node.HostPort, err)
+				continue
+			}
+			bad := 0
+			for _, c := range checks {
+				if c.State != staking.KeyOK {
+					bad++
+					log.Printf("Node %s: %s %s of election %d is %s: %s", node.HostPort, c.Type, c.Key, c.ElectionID, c.State, c.Detail)
 				}
-				time.Sleep(10 * time.Second)
 			}
-
+			if bad > 0 {
+				alerts.Notify(notify.NewEvent(notify.EventKeyMissing, node.HostPort, "%d keys stored for the node are missing or differ on it, check with ton-cli node verify %d", bad, node.ID))
+			} else {
+				alerts.Resolve(notify.EventKeyMissing, node.HostPort)
+			}
 		}
 	}
+}
+
+//reconcileMessages match sent messages of enabled wallets with their transactions every interval
+func reconcileMessages(s *database.Store, machine *staking.Machine, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
 
+	for range ticker.C {
+		wallets, err := s.GetWallets(1)
+		if err != nil {
+			log.Println("Failed to get wallets:", err)
+			continue
+		}
+		for _, wallet := range wallets {
+			n, err := machine.Reconcile(wallet)
+			if err != nil {
+				log.Println("Failed to reconcile messages of", wallet.Addr, err)
+			}
+			if n > 0 {
+				log.Println("Matched", n, "messages of", wallet.Addr, "with transactions")
+			}
+		}
+	}
 }
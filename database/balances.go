@@ -0,0 +1,64 @@
+package database
+
+import "database/sql"
+
+//BalanceSnapshot wallet balance after it changed, with the last transaction of the account at that time
+type BalanceSnapshot struct {
+	ID        int64
+	WalletID  int
+	Balance   int64
+	Change    int64
+	TxLt      int64
+	TxHash    string
+	CreatedAt string
+}
+
+//UpdateWalletBalance save the balance of the wallet, the last transaction of its account state
+//is recorded with it in the balance history when the balance changed. The change is returned.
+func (store *Store) UpdateWalletBalance(walletID int, balance, txLt int64, txHash string) (int64, error) {
+	tx, err := store.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	var old sql.NullInt64
+	if err = tx.QueryRow("select balance from wallets where id=?", walletID).Scan(&old); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	change := balance - old.Int64
+	if old.Valid && change == 0 {
+		tx.Rollback()
+		return 0, nil
+	}
+	if _, err = tx.Exec("update wallets set balance=? where id=?", balance, walletID); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	_, err = tx.Exec("INSERT INTO balance_history(wallet_id,balance,change,tx_lt,tx_hash) values(?,?,?,?,?)", walletID, balance, change, txLt, txHash)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	return change, tx.Commit()
+}
+
+//GetBalanceHistory latest limit balance snapshots of the wallet, newest first
+func (store *Store) GetBalanceHistory(walletID, limit int) ([]BalanceSnapshot, error) {
+	rows, err := store.db.Query("select id,wallet_id,balance,change,ifnull(tx_lt,0),ifnull(tx_hash,''),datetime(created_at) from balance_history where wallet_id=? order by id desc limit ?", walletID, limit)
+	if err != nil {
+		return []BalanceSnapshot{}, err
+	}
+	defer rows.Close()
+	var history []BalanceSnapshot
+	for rows.Next() {
+		var b BalanceSnapshot
+		if err = rows.Scan(&b.ID, &b.WalletID, &b.Balance, &b.Change, &b.TxLt, &b.TxHash, &b.CreatedAt); err != nil {
+			return history, err
+		}
+		history = append(history, b)
+	}
+	if err = rows.Err(); err != nil {
+		return []BalanceSnapshot{}, err
+	}
+	return history, nil
+}
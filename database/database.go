@@ -10,17 +10,25 @@ import (
 	tonlib "github.com/mercuryoio/tonlib-go/v2"
 )
 
-type store struct {
+//Store database client
+type Store struct {
 	db *sql.DB
 }
 
-//NewClient init new connection to the database
-func NewClient(pathDB string) (*store, error) {
+//NewClient init new connection to the database and bring its schema up to date
+func NewClient(pathDB string) (*Store, error) {
 	db, err := sql.Open("sqlite3", pathDB)
 	if err != nil {
-		return &store{}, err
+		return &Store{}, err
 	}
-	return &store{db: db}, nil
+	// sqlite takes one writer at a time, bot workers queue for the connection instead of failing
+	db.SetMaxOpenConns(1)
+	s := &Store{db: db}
+	if err = s.Migrate(); err != nil {
+		db.Close()
+		return &Store{}, err
+	}
+	return s, nil
 }
 
 //Node info
@@ -29,16 +37,19 @@ type Node struct {
 	HostPort   string
 	ServerPub  string
 	ClientCert string
+	WalletID   int
 	Enabled    int
 }
 
 //Wallet info
 type Wallet struct {
-	ID       int
-	FilePath string
-	Addr     string
-	Balance  int64
-	Enabled  int
+	ID          int
+	FilePath    string
+	Addr        string
+	Balance     int64
+	Enabled     int
+	Version     int
+	SubwalletID int64
 }
 
 //Election info
@@ -52,22 +63,32 @@ type Election struct {
 
 //Participate record
 type Participate struct {
+	ID          int64
 	NodeID      int
 	ElectionID  int64
 	StakeAmount int64
 	MaxFactor   string
+	//Outcome, EffectiveStake and Weight are set once the election is over, Outcome is empty before
+	Outcome        string
+	EffectiveStake int64
+	Weight         int64
 }
 
 //Key validator keys
 type Key struct {
+	ID         int64
 	Key        string
 	ElectionID int64
 	NodeID     int
 	Type       string
+	//ExpireAt unixtime the node may forget the key, 0 for keys stored before it was tracked
+	ExpireAt int64
+	//DeletedAt when the key was removed from the node, empty while it is there
+	DeletedAt string
 }
 
 //AddNode Add a node info to database
-func (store *store) AddNode(hostPort, serverPub, clientCert string, walletID int) (int64, error) {
+func (store *Store) AddNode(hostPort, serverPub, clientCert string, walletID int) (int64, error) {
 	stmt, err := store.db.Prepare("INSERT INTO nodes(host_port, server_pub, client_cert, wallet_id, enabled) values(?,?,?,?,?)")
 	if err != nil {
 		return 0, err
@@ -86,7 +107,7 @@ func (store *store) AddNode(hostPort, serverPub, clientCert string, walletID int
 }
 
 //DelNode Del node from database
-func (store *store) DelNode(id int) error {
+func (store *Store) DelNode(id int) error {
 	query := fmt.Sprintf("delete from nodes where id = %d", id)
 	_, err := store.db.Exec(query)
 	if err != nil {
@@ -97,13 +118,13 @@ func (store *store) DelNode(id int) error {
 }
 
 //AddWallet Add wallet info to database
-func (store *store) AddWallet(walletFile, walletAddr string) (int64, error) {
-	stmt, err := store.db.Prepare("INSERT INTO wallets(wallet_file, wallet_addr, balance, enabled) values(?,?,?,?)")
+func (store *Store) AddWallet(walletFile, walletAddr string, version int, subwalletID int64) (int64, error) {
+	stmt, err := store.db.Prepare("INSERT INTO wallets(wallet_file, wallet_addr, balance, enabled, version, subwallet_id) values(?,?,?,?,?,?)")
 	if err != nil {
 		return 0, err
 	}
 
-	res, err := stmt.Exec(walletFile, walletAddr, "0", "1")
+	res, err := stmt.Exec(walletFile, walletAddr, "0", "1", version, subwalletID)
 	if err != nil {
 		return 0, err
 	}
@@ -116,23 +137,23 @@ func (store *store) AddWallet(walletFile, walletAddr string) (int64, error) {
 }
 
 //DelWallet Delete wallet by ID
-func (store *store) DelWallet(id int) error {
+func (store *Store) DelWallet(id int) error {
 	query := fmt.Sprintf("delete from wallets where id = %d", id)
 	_, err := store.db.Exec(query)
 	if err != nil {
 		fmt.Printf("Failed to delete wallet with ID: %d: %s\n", id, err)
 		return err
 	}
-	return nil
+	return store.DelWalletKey(id)
 }
 
 //GetWallets Get wallet info
-func (store *store) GetWallets(enabled int) ([]Wallet, error) {
+func (store *Store) GetWallets(enabled int) ([]Wallet, error) {
 	var query string
 	if enabled > 1 {
-		query = "select id,wallet_file,wallet_addr,balance,enabled from wallets"
+		query = "select id,wallet_file,wallet_addr,balance,enabled,version,subwallet_id from wallets"
 	} else {
-		query = fmt.Sprintf("select id,wallet_file,wallet_addr,balance,enabled from wallets where enabled=%d", enabled)
+		query = fmt.Sprintf("select id,wallet_file,wallet_addr,balance,enabled,version,subwallet_id from wallets where enabled=%d", enabled)
 	}
 	rows, err := store.db.Query(query)
 	if err != nil {
@@ -143,7 +164,7 @@ func (store *store) GetWallets(enabled int) ([]Wallet, error) {
 	var wallets []Wallet
 	for rows.Next() {
 		var wallet Wallet
-		err = rows.Scan(&wallet.ID, &wallet.FilePath, &wallet.Addr, &wallet.Balance, &wallet.Enabled)
+		err = rows.Scan(&wallet.ID, &wallet.FilePath, &wallet.Addr, &wallet.Balance, &wallet.Enabled, &wallet.Version, &wallet.SubwalletID)
 		if err != nil {
 			fmt.Println(err)
 		}
@@ -158,33 +179,24 @@ func (store *store) GetWallets(enabled int) ([]Wallet, error) {
 	return wallets, nil
 }
 
-//UpdateWalletBalance update wallet balance by id
-func (store *store) UpdateWalletBalance(walletID int, newBalance int64) error {
-	stmt, err := store.db.Prepare("update wallets set balance=? where id=?")
-	if err != nil {
-		return err
-	}
-
-	res, err := stmt.Exec(newBalance, walletID)
-	if err != nil {
-		return err
-	}
-
-	_, err = res.RowsAffected()
+//GetWallet Get wallet info by ID
+func (store *Store) GetWallet(id int) (Wallet, error) {
+	sqlStmt := "select id,wallet_file,wallet_addr,balance,enabled,version,subwallet_id from wallets where id=?"
+	var wallet Wallet
+	err := store.db.QueryRow(sqlStmt, id).Scan(&wallet.ID, &wallet.FilePath, &wallet.Addr, &wallet.Balance, &wallet.Enabled, &wallet.Version, &wallet.SubwalletID)
 	if err != nil {
-		return err
+		return Wallet{}, err
 	}
-	return nil
-	//fmt.Println(affect)
+	return wallet, nil
 }
 
 //GetNodes Get info about nodes
-func (store *store) GetNodes(walletID, enabled int) ([]Node, error) {
+func (store *Store) GetNodes(walletID, enabled int) ([]Node, error) {
 	var query string
 	if enabled > 1 {
-		query = fmt.Sprintf("select id,host_port,server_pub,client_cert,enabled from nodes where wallet_id=%d", walletID)
+		query = fmt.Sprintf("select id,host_port,server_pub,client_cert,wallet_id,enabled from nodes where wallet_id=%d", walletID)
 	} else {
-		query = fmt.Sprintf("select id,host_port,server_pub,client_cert,enabled from nodes where enabled=%d and wallet_id=%d", enabled, walletID)
+		query = fmt.Sprintf("select id,host_port,server_pub,client_cert,wallet_id,enabled from nodes where enabled=%d and wallet_id=%d", enabled, walletID)
 	}
 	rows, err := store.db.Query(query)
 	if err != nil {
@@ -194,7 +206,7 @@ func (store *store) GetNodes(walletID, enabled int) ([]Node, error) {
 	var nodes []Node
 	for rows.Next() {
 		var node Node
-		err = rows.Scan(&node.ID, &node.HostPort, &node.ServerPub, &node.ClientCert, &node.Enabled)
+		err = rows.Scan(&node.ID, &node.HostPort, &node.ServerPub, &node.ClientCert, &node.WalletID, &node.Enabled)
 		if err != nil {
 			return nodes, err
 		}
@@ -209,8 +221,19 @@ func (store *store) GetNodes(walletID, enabled int) ([]Node, error) {
 	return nodes, nil
 }
 
+//GetNode Get node info by ID
+func (store *Store) GetNode(id int) (Node, error) {
+	sqlStmt := "select id,host_port,server_pub,client_cert,wallet_id,enabled from nodes where id=?"
+	var node Node
+	err := store.db.QueryRow(sqlStmt, id).Scan(&node.ID, &node.HostPort, &node.ServerPub, &node.ClientCert, &node.WalletID, &node.Enabled)
+	if err != nil {
+		return Node{}, err
+	}
+	return node, nil
+}
+
 //GetElection Check if election exists
-func (store *store) GetElection(electionID int64) (Election, error) {
+func (store *Store) GetElection(electionID int64) (Election, error) {
 	sqlStmt := "select id,election_id,start_at,close_at,next_elections_at from elections where election_id=?"
 	var election Election
 	err := store.db.QueryRow(sqlStmt, electionID).Scan(&election.ID, &election.ElectionID, &election.StartAt, &election.CloseAt, &election.NextElectionsAt)
@@ -227,8 +250,80 @@ func (store *store) GetElection(electionID int64) (Election, error) {
 	return election, nil
 }
 
+//GetElections all known elections, the latest first
+func (store *Store) GetElections() ([]Election, error) {
+	rows, err := store.db.Query("select id,election_id,ifnull(start_at,0),ifnull(close_at,0),ifnull(next_elections_at,0) from elections order by election_id desc")
+	if err != nil {
+		return []Election{}, err
+	}
+	defer rows.Close()
+	var elections []Election
+	for rows.Next() {
+		var election Election
+		err = rows.Scan(&election.ID, &election.ElectionID, &election.StartAt, &election.CloseAt, &election.NextElectionsAt)
+		if err != nil {
+			return elections, err
+		}
+		elections = append(elections, election)
+	}
+	err = rows.Err()
+	if err != nil {
+		return []Election{}, err
+	}
+	return elections, nil
+}
+
+const participateColumns = "id,node_id,election_id,ifnull(stake_amount,0),ifnull(max_factor,''),ifnull(outcome,''),ifnull(effective_stake,0),ifnull(weight,0)"
+
+//GetElectionParticipates participate records of all nodes in the election
+func (store *Store) GetElectionParticipates(electionID int64) ([]Participate, error) {
+	rows, err := store.db.Query("select "+participateColumns+" from participate where election_id=? order by node_id", electionID)
+	if err != nil {
+		return []Participate{}, err
+	}
+	return scanParticipates(rows)
+}
+
+//GetUncheckedParticipates participate records whose outcome is not known yet
+func (store *Store) GetUncheckedParticipates() ([]Participate, error) {
+	rows, err := store.db.Query("select " + participateColumns + " from participate where outcome is null order by election_id,node_id")
+	if err != nil {
+		return []Participate{}, err
+	}
+	return scanParticipates(rows)
+}
+
+//SetParticipateOutcome save the outcome of the election for the participate record
+func (store *Store) SetParticipateOutcome(p Participate) error {
+	stmt, err := store.db.Prepare("update participate set outcome=?,effective_stake=?,weight=?,checked_at=CURRENT_TIMESTAMP where id=?")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(p.Outcome, p.EffectiveStake, p.Weight, p.ID)
+	return err
+}
+
+func scanParticipates(rows *sql.Rows) ([]Participate, error) {
+	defer rows.Close()
+	var participates []Participate
+	for rows.Next() {
+		var p Participate
+		err := rows.Scan(&p.ID, &p.NodeID, &p.ElectionID, &p.StakeAmount, &p.MaxFactor, &p.Outcome, &p.EffectiveStake, &p.Weight)
+		if err != nil {
+			return participates, err
+		}
+		participates = append(participates, p)
+	}
+	if err := rows.Err(); err != nil {
+		return []Participate{}, err
+	}
+	return participates, nil
+}
+
 //GetParticipates log
-func (store *store) GetParticipates(nodeID int, electionID int64) []Participate {
+func (store *Store) GetParticipates(nodeID int, electionID int64) []Participate {
 
 	query := fmt.Sprintf("select participate.node_id,participate.election_id,participate.stake_amount,participate.max_factor from participate inner join elections on elections.election_id = participate.election_id where elections.election_id=%d and participate.node_id=%d", electionID, nodeID)
 
@@ -256,31 +351,25 @@ func (store *store) GetParticipates(nodeID int, electionID int64) []Participate
 }
 
 //GetKey from db
-func (store *store) GetKey(keyType string, nodeID int, electionID int64) (Key, error) {
-	sqlStmt := "select key,election_id,node_id,type from keys where election_id=? and node_id=? and type=?"
-	var key Key
-	err := store.db.QueryRow(sqlStmt, electionID, nodeID, keyType).Scan(&key.Key, &key.ElectionID, &key.NodeID, &key.Type)
-	if err != nil {
-		if err != sql.ErrNoRows {
-			// a real error happened! you should change your function return
-			// to "(bool, error)" and return "false, err" here
-			log.Fatalln(err)
-		}
-
-		return key, err
-	}
-
-	return key, nil
+func (store *Store) GetKey(keyType string, nodeID int, electionID int64) (Key, error) {
+	// keys that replaced removed ones win
+	sqlStmt := "select " + keyColumns + " from keys where election_id=? and node_id=? and type=? order by deleted_at is not null, id desc limit 1"
+	key, err := scanKey(store.db.QueryRow(sqlStmt, electionID, nodeID, keyType))
+	if err != nil && err != sql.ErrNoRows {
+		return key, fmt.Errorf("failed to get %s of node %d in election %d: %w", keyType, nodeID, electionID, err)
+	}
+	return key, err
 }
 
 //AddKey add key to db
-func (store *store) AddKey(key Key) (int64, error) {
-	stmt, err := store.db.Prepare("INSERT INTO keys(election_id,key,type,node_id) values(?,?,?,?)")
+func (store *Store) AddKey(key Key) (int64, error) {
+	stmt, err := store.db.Prepare("INSERT INTO keys(election_id,key,type,node_id,expire_at) values(?,?,?,?,?)")
 	if err != nil {
 		return 0, err
 	}
+	defer stmt.Close()
 
-	res, err := stmt.Exec(key.ElectionID, key.Key, key.Type, key.NodeID)
+	res, err := stmt.Exec(key.ElectionID, key.Key, key.Type, key.NodeID, key.ExpireAt)
 	if err != nil {
 		return 0, err
 	}
@@ -293,7 +382,7 @@ func (store *store) AddKey(key Key) (int64, error) {
 }
 
 //AddElection Add new election id
-func (store *store) AddElection(election Election) (int64, error) {
+func (store *Store) AddElection(election Election) (int64, error) {
 	stmt, err := store.db.Prepare("INSERT INTO elections(election_id,start_at,close_at,next_elections_at) values(?,?,?,?)")
 	if err != nil {
 		return 0, err
@@ -312,7 +401,7 @@ func (store *store) AddElection(election Election) (int64, error) {
 }
 
 //AddParticipate log
-func (store *store) AddParticipate(p Participate) (int64, error) {
+func (store *Store) AddParticipate(p Participate) (int64, error) {
 	stmt, err := store.db.Prepare("INSERT INTO participate(node_id,election_id,stake_amount,max_factor) values(?,?,?,?)")
 	if err != nil {
 		return 0, err
@@ -331,7 +420,7 @@ func (store *store) AddParticipate(p Participate) (int64, error) {
 }
 
 //SyncWalletsBalance sync wallets balance to db
-func (store *store) SyncWalletsBalance(cln *tonlib.Client) error {
+func (store *Store) SyncWalletsBalance(cln *tonlib.Client) error {
 	wallets, err := store.GetWallets(1)
 	if err != nil {
 		return err
@@ -346,7 +435,7 @@ func (store *store) SyncWalletsBalance(cln *tonlib.Client) error {
 			return err
 		}
 		walletBalance = int64(AccountState.Balance)
-		err = store.UpdateWalletBalance(wallet.ID, walletBalance)
+		_, err = store.UpdateWalletBalance(wallet.ID, walletBalance, int64(AccountState.LastTransactionId.Lt), AccountState.LastTransactionId.Hash)
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,66 @@
+package database
+
+import "database/sql"
+
+//Earning what a wallet put into one election and got back, in nanograms
+type Earning struct {
+	WalletID      int
+	ElectionID    int64
+	StakeSent     int64
+	StakeReturned int64
+	Bonus         int64
+	//Fees spent on wallet messages sent for the election
+	Fees int64
+}
+
+//Net result of the election for the wallet
+func (e Earning) Net() int64 {
+	return e.StakeReturned + e.Bonus - e.StakeSent - e.Fees
+}
+
+//AddEarning add the amounts of e to the ledger entry of the wallet and election
+func (store *Store) AddEarning(e Earning) error {
+	stmt, err := store.db.Prepare(`INSERT INTO earnings(wallet_id,election_id,stake_sent,stake_returned,bonus,fees) values(?,?,?,?,?,?)
+		ON CONFLICT(wallet_id,election_id) DO UPDATE SET stake_sent=stake_sent+excluded.stake_sent,stake_returned=stake_returned+excluded.stake_returned,
+		bonus=bonus+excluded.bonus,fees=fees+excluded.fees,updated_at=CURRENT_TIMESTAMP`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(e.WalletID, e.ElectionID, e.StakeSent, e.StakeReturned, e.Bonus, e.Fees)
+	return err
+}
+
+//GetEarnings ledger entries of elections with ids between from and to, both unix times, ordered by election.
+//The wallet rows with election id 0 are always included.
+func (store *Store) GetEarnings(from, to int64) ([]Earning, error) {
+	rows, err := store.db.Query("select wallet_id,election_id,stake_sent,stake_returned,bonus,fees from earnings where election_id=0 or (election_id>=? and election_id<=?) order by election_id,wallet_id", from, to)
+	if err != nil {
+		return []Earning{}, err
+	}
+	defer rows.Close()
+	var earnings []Earning
+	for rows.Next() {
+		var e Earning
+		if err = rows.Scan(&e.WalletID, &e.ElectionID, &e.StakeSent, &e.StakeReturned, &e.Bonus, &e.Fees); err != nil {
+			return earnings, err
+		}
+		earnings = append(earnings, e)
+	}
+	if err = rows.Err(); err != nil {
+		return []Earning{}, err
+	}
+	return earnings, nil
+}
+
+//GetEarning ledger entry of the wallet and election, empty if there is none yet
+func (store *Store) GetEarning(walletID int, electionID int64) (Earning, error) {
+	e := Earning{WalletID: walletID, ElectionID: electionID}
+	err := store.db.QueryRow("select stake_sent,stake_returned,bonus,fees from earnings where wallet_id=? and election_id=?", walletID, electionID).
+		Scan(&e.StakeSent, &e.StakeReturned, &e.Bonus, &e.Fees)
+	if err == sql.ErrNoRows {
+		return e, nil
+	}
+	return e, err
+}
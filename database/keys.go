@@ -0,0 +1,42 @@
+package database
+
+const keyColumns = "id,key,election_id,node_id,type,ifnull(expire_at,0),ifnull(deleted_at,'')"
+
+func scanKey(row interface{ Scan(...interface{}) error }) (Key, error) {
+	var k Key
+	err := row.Scan(&k.ID, &k.Key, &k.ElectionID, &k.NodeID, &k.Type, &k.ExpireAt, &k.DeletedAt)
+	return k, err
+}
+
+//GetNodeKeys keys created on the node, oldest election first
+func (store *Store) GetNodeKeys(nodeID int) ([]Key, error) {
+	rows, err := store.db.Query("select "+keyColumns+" from keys where node_id=? order by election_id, id", nodeID)
+	if err != nil {
+		return []Key{}, err
+	}
+	defer rows.Close()
+	var keys []Key
+	for rows.Next() {
+		k, err := scanKey(rows)
+		if err != nil {
+			return keys, err
+		}
+		keys = append(keys, k)
+	}
+	if err = rows.Err(); err != nil {
+		return []Key{}, err
+	}
+	return keys, nil
+}
+
+//SetKeysDeleted note that keys of the node for the election were removed from it
+func (store *Store) SetKeysDeleted(nodeID int, electionID int64) error {
+	stmt, err := store.db.Prepare("update keys set deleted_at=CURRENT_TIMESTAMP where node_id=? and election_id=? and deleted_at is null")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(nodeID, electionID)
+	return err
+}
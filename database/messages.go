@@ -0,0 +1,154 @@
+package database
+
+import "database/sql"
+
+//Message external message sent from a wallet
+type Message struct {
+	ID       int64
+	WalletID int
+	//ElectionID election the message is sent for, 0 if none
+	ElectionID int64
+	//NodeID node the message stakes for, 0 if none
+	NodeID int
+	//QueryID query id of the elector request in the body, the elector answers with it
+	QueryID     uint64
+	Seqno       int64
+	Destination string
+	Amount      int64
+	Purpose     string
+	//Hash and BodyHash hex hashes of the last attempt, every attempt is signed anew
+	Hash        string
+	BodyHash    string
+	State       string
+	Attempts    int
+	Error       string
+	CreatedAt   string
+	SentAt      string
+	ConfirmedAt string
+	//TxLt, TxHash and Fee wallet transaction that took the message and its fees, set by the reconciler
+	TxLt   int64
+	TxHash string
+	Fee    int64
+}
+
+const messageColumns = "id,wallet_id,election_id,seqno,destination,amount,purpose,hash,ifnull(body_hash,''),state,attempts,ifnull(error,''),datetime(created_at),ifnull(sent_at,''),ifnull(confirmed_at,''),ifnull(tx_lt,0),ifnull(tx_hash,''),ifnull(fee,0),node_id,query_id"
+
+func scanMessage(row interface{ Scan(...interface{}) error }) (Message, error) {
+	var m Message
+	var queryID int64
+	err := row.Scan(&m.ID, &m.WalletID, &m.ElectionID, &m.Seqno, &m.Destination, &m.Amount, &m.Purpose, &m.Hash, &m.BodyHash, &m.State, &m.Attempts, &m.Error, &m.CreatedAt, &m.SentAt, &m.ConfirmedAt, &m.TxLt, &m.TxHash, &m.Fee, &m.NodeID, &queryID)
+	m.QueryID = uint64(queryID)
+	return m, err
+}
+
+//AddMessage add message record
+func (store *Store) AddMessage(m Message) (int64, error) {
+	stmt, err := store.db.Prepare("INSERT INTO messages(wallet_id,election_id,node_id,query_id,seqno,destination,amount,purpose,hash,body_hash,state,attempts,error) values(?,?,?,?,?,?,?,?,?,?,?,?,?)")
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	res, err := stmt.Exec(m.WalletID, m.ElectionID, m.NodeID, int64(m.QueryID), m.Seqno, m.Destination, m.Amount, m.Purpose, m.Hash, m.BodyHash, m.State, m.Attempts, m.Error)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+//UpdateMessage save the state of a message
+func (store *Store) UpdateMessage(m Message) error {
+	stmt, err := store.db.Prepare("update messages set hash=?,body_hash=?,state=?,attempts=?,error=?,updated_at=CURRENT_TIMESTAMP where id=?")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(m.Hash, m.BodyHash, m.State, m.Attempts, m.Error, m.ID)
+	return err
+}
+
+//SetMessageSent note the time the message was first sent
+func (store *Store) SetMessageSent(id int64) error {
+	stmt, err := store.db.Prepare("update messages set sent_at=CURRENT_TIMESTAMP,updated_at=CURRENT_TIMESTAMP where id=? and sent_at is null")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(id)
+	return err
+}
+
+//ConfirmMessage mark the message taken by the wallet
+func (store *Store) ConfirmMessage(id int64) error {
+	stmt, err := store.db.Prepare("update messages set state='confirmed',error=null,confirmed_at=ifnull(confirmed_at,CURRENT_TIMESTAMP),updated_at=CURRENT_TIMESTAMP where id=?")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(id)
+	return err
+}
+
+//SetMessageTransaction link the message to the wallet transaction made at utime that took it with fee
+func (store *Store) SetMessageTransaction(id int64, utime int64, txLt int64, txHash string, fee int64) error {
+	stmt, err := store.db.Prepare("update messages set state='confirmed',error=null,confirmed_at=datetime(?,'unixepoch'),tx_lt=?,tx_hash=?,fee=?,updated_at=CURRENT_TIMESTAMP where id=?")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(utime, txLt, txHash, fee, id)
+	return err
+}
+
+//GetMessages get the latest limit messages of the wallet, all wallets if walletID is 0
+func (store *Store) GetMessages(walletID int, limit int) ([]Message, error) {
+	rows, err := store.db.Query("select "+messageColumns+" from messages where (?=0 or wallet_id=?) order by id desc limit ?", walletID, walletID, limit)
+	if err != nil {
+		return []Message{}, err
+	}
+	return scanMessages(rows)
+}
+
+//GetUnreconciledMessages get messages of the wallet created after since unix time that are not linked to a transaction yet
+func (store *Store) GetUnreconciledMessages(walletID int, since int64) ([]Message, error) {
+	rows, err := store.db.Query("select "+messageColumns+" from messages where wallet_id=? and tx_hash is null and body_hash is not null and created_at>=datetime(?,'unixepoch') order by id", walletID, since)
+	if err != nil {
+		return []Message{}, err
+	}
+	return scanMessages(rows)
+}
+
+//GetOpenMessages messages of the wallet with the purpose for the node and election that have not failed.
+//Messages recorded before nodes were tracked count for every node.
+func (store *Store) GetOpenMessages(walletID, nodeID int, electionID int64, purpose string) ([]Message, error) {
+	rows, err := store.db.Query("select "+messageColumns+" from messages where wallet_id=? and election_id=? and node_id in (0,?) and purpose=? and state!='failed' order by id", walletID, electionID, nodeID, purpose)
+	if err != nil {
+		return []Message{}, err
+	}
+	return scanMessages(rows)
+}
+
+func scanMessages(rows *sql.Rows) ([]Message, error) {
+	defer rows.Close()
+	var messages []Message
+	for rows.Next() {
+		m, err := scanMessage(rows)
+		if err != nil {
+			return messages, err
+		}
+		messages = append(messages, m)
+	}
+	if err := rows.Err(); err != nil {
+		return []Message{}, err
+	}
+	return messages, nil
+}
@@ -0,0 +1,106 @@
+package database
+
+import (
+	"embed"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+type migration struct {
+	Version int
+	Name    string
+	SQL     string
+}
+
+//ErrSchemaTooNew database was migrated by a newer binary
+type ErrSchemaTooNew struct {
+	Current int
+	Latest  int
+}
+
+func (e ErrSchemaTooNew) Error() string {
+	return fmt.Sprintf("database schema version %d is newer than supported version %d, upgrade the binary", e.Current, e.Latest)
+}
+
+//loadMigrations read embedded migrations ordered by version
+func loadMigrations() ([]migration, error) {
+	entries, err := migrationFiles.ReadDir("migrations")
+	if err != nil {
+		return nil, err
+	}
+	var migrations []migration
+	for _, entry := range entries {
+		name := entry.Name()
+		i := strings.Index(name, "_")
+		if i <= 0 || !strings.HasSuffix(name, ".sql") {
+			return nil, fmt.Errorf("bad migration file name: %s", name)
+		}
+		version, err := strconv.Atoi(name[:i])
+		if err != nil {
+			return nil, fmt.Errorf("bad migration version in %s: %v", name, err)
+		}
+		data, err := migrationFiles.ReadFile(path.Join("migrations", name))
+		if err != nil {
+			return nil, err
+		}
+		migrations = append(migrations, migration{
+			Version: version,
+			Name:    strings.TrimSuffix(name[i+1:], ".sql"),
+			SQL:     string(data),
+		})
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			return nil, fmt.Errorf("migration %04d_%s is out of sequence, expected version %d", m.Version, m.Name, i+1)
+		}
+	}
+	return migrations, nil
+}
+
+//SchemaVersion current schema version of the database
+func (store *Store) SchemaVersion() (int, error) {
+	var version int
+	err := store.db.QueryRow("PRAGMA user_version").Scan(&version)
+	return version, err
+}
+
+//Migrate apply pending migrations, each one in its own transaction
+func (store *Store) Migrate() error {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return err
+	}
+	current, err := store.SchemaVersion()
+	if err != nil {
+		return err
+	}
+	if current > len(migrations) {
+		return ErrSchemaTooNew{Current: current, Latest: len(migrations)}
+	}
+	for _, m := range migrations[current:] {
+		tx, err := store.db.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err = tx.Exec(m.SQL); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %04d_%s failed: %v", m.Version, m.Name, err)
+		}
+		// PRAGMA does not accept bound parameters
+		if _, err = tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", m.Version)); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err = tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
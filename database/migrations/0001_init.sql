@@ -43,4 +43,4 @@ CREATE TABLE IF NOT EXISTS keys (
     `node_id` INTEGER NOT NULL,
     `created_at` DATETIME DEFAULT CURRENT_TIMESTAMP NOT NULL,
     `expire_at` INTEGER
-)
\ No newline at end of file
+);
@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS stakes (
+    `id` INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    `wallet_id` INTEGER NOT NULL,
+    `node_id` INTEGER NOT NULL,
+    `election_id` INTEGER NOT NULL,
+    `state` VARCHAR(32) NOT NULL,
+    `stake_amount` INTEGER NOT NULL,
+    `max_factor` VARCHAR(16) NOT NULL,
+    `signature` VARCHAR(128),
+    `error` TEXT,
+    `created_at` DATETIME DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    `updated_at` DATETIME DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    UNIQUE (`wallet_id`, `node_id`, `election_id`)
+);
+
+CREATE TABLE IF NOT EXISTS stake_transitions (
+    `id` INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    `stake_id` INTEGER NOT NULL,
+    `from_state` VARCHAR(32),
+    `to_state` VARCHAR(32) NOT NULL,
+    `created_at` DATETIME DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
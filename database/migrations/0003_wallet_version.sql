@@ -0,0 +1,2 @@
+ALTER TABLE wallets ADD COLUMN `version` INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE wallets ADD COLUMN `subwallet_id` INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS recoveries (
+    `id` INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    `wallet_id` INTEGER NOT NULL,
+    `query_id` INTEGER NOT NULL,
+    `result` VARCHAR(32) NOT NULL,
+    `credit` INTEGER NOT NULL,
+    `amount` INTEGER NOT NULL,
+    `tx_lt` INTEGER,
+    `tx_hash` VARCHAR(64),
+    `created_at` DATETIME DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
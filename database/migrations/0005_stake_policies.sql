@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS stake_policies (
+    `id` INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    `wallet_id` INTEGER NOT NULL DEFAULT 0,
+    `node_id` INTEGER NOT NULL DEFAULT 0,
+    `mode` VARCHAR(16) NOT NULL,
+    `amount` INTEGER NOT NULL DEFAULT 0,
+    `percent` REAL NOT NULL DEFAULT 0,
+    `max_factor` VARCHAR(16) NOT NULL,
+    `min_reserve` INTEGER NOT NULL DEFAULT 0,
+    `skip_next` INTEGER NOT NULL DEFAULT 0,
+    `skipped_election` INTEGER NOT NULL DEFAULT 0,
+    `updated_at` DATETIME DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    UNIQUE (`wallet_id`, `node_id`)
+);
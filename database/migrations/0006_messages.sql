@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS messages (
+    `id` INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    `wallet_id` INTEGER NOT NULL,
+    `seqno` INTEGER NOT NULL,
+    `destination` VARCHAR(70) NOT NULL,
+    `amount` INTEGER NOT NULL,
+    `purpose` VARCHAR(16) NOT NULL,
+    `hash` VARCHAR(64) NOT NULL,
+    `state` VARCHAR(16) NOT NULL,
+    `attempts` INTEGER DEFAULT 0 NOT NULL,
+    `error` TEXT,
+    `created_at` DATETIME DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    `updated_at` DATETIME DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS messages_wallet_seqno ON messages (`wallet_id`, `seqno`);
@@ -0,0 +1,5 @@
+ALTER TABLE messages ADD COLUMN `body_hash` VARCHAR(64);
+ALTER TABLE messages ADD COLUMN `sent_at` DATETIME;
+ALTER TABLE messages ADD COLUMN `confirmed_at` DATETIME;
+ALTER TABLE messages ADD COLUMN `tx_lt` INTEGER;
+ALTER TABLE messages ADD COLUMN `tx_hash` VARCHAR(64);
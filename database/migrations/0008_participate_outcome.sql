@@ -0,0 +1,4 @@
+ALTER TABLE participate ADD COLUMN `outcome` VARCHAR(16);
+ALTER TABLE participate ADD COLUMN `effective_stake` INTEGER;
+ALTER TABLE participate ADD COLUMN `weight` INTEGER;
+ALTER TABLE participate ADD COLUMN `checked_at` DATETIME;
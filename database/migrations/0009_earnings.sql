@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS earnings (
+    `id` INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    `wallet_id` INTEGER NOT NULL,
+    `election_id` INTEGER NOT NULL,
+    `stake_sent` INTEGER DEFAULT 0 NOT NULL,
+    `stake_returned` INTEGER DEFAULT 0 NOT NULL,
+    `bonus` INTEGER DEFAULT 0 NOT NULL,
+    `fees` INTEGER DEFAULT 0 NOT NULL,
+    `updated_at` DATETIME DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    UNIQUE (`wallet_id`, `election_id`)
+);
+
+ALTER TABLE messages ADD COLUMN `election_id` INTEGER DEFAULT 0 NOT NULL;
+ALTER TABLE messages ADD COLUMN `fee` INTEGER;
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS balance_history (
+    `id` INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    `wallet_id` INTEGER NOT NULL,
+    `balance` INTEGER NOT NULL,
+    `change` INTEGER NOT NULL,
+    `tx_lt` INTEGER,
+    `tx_hash` VARCHAR(64),
+    `created_at` DATETIME DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS balance_history_wallet ON balance_history (`wallet_id`, `id`);
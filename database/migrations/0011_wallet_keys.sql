@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS wallet_keys (
+    `id` INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    `wallet_id` INTEGER NOT NULL,
+    `kdf` VARCHAR(32) NOT NULL,
+    `salt` BLOB NOT NULL,
+    `nonce` BLOB NOT NULL,
+    `ciphertext` BLOB NOT NULL,
+    `created_at` DATETIME DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    UNIQUE (`wallet_id`)
+);
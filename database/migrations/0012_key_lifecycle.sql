@@ -0,0 +1,2 @@
+ALTER TABLE keys ADD COLUMN `deleted_at` DATETIME;
+CREATE INDEX IF NOT EXISTS keys_node ON keys (`node_id`, `election_id`);
@@ -0,0 +1,2 @@
+ALTER TABLE recoveries ADD COLUMN `sent_lt` INTEGER NOT NULL DEFAULT 0;
+CREATE INDEX IF NOT EXISTS recoveries_wallet ON recoveries (`wallet_id`, `result`);
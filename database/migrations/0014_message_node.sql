@@ -0,0 +1,2 @@
+ALTER TABLE messages ADD COLUMN `node_id` INTEGER DEFAULT 0 NOT NULL;
+CREATE INDEX IF NOT EXISTS messages_wallet_election ON messages (`wallet_id`, `election_id`);
@@ -0,0 +1 @@
+ALTER TABLE messages ADD COLUMN `query_id` INTEGER DEFAULT 0 NOT NULL;
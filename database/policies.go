@@ -0,0 +1,38 @@
+package database
+
+//StakePolicy how a wallet (NodeID 0) or a node (WalletID 0) stakes, amounts are in grams
+type StakePolicy struct {
+	ID              int64
+	WalletID        int
+	NodeID          int
+	Mode            string
+	Amount          int64
+	Percent         float64
+	MaxFactor       string
+	MinReserve      int64
+	SkipNext        bool
+	SkippedElection int64
+}
+
+//GetStakePolicy get policy of the wallet or the node, sql.ErrNoRows if there is none
+func (store *Store) GetStakePolicy(walletID, nodeID int) (StakePolicy, error) {
+	sqlStmt := "select id,wallet_id,node_id,mode,amount,percent,max_factor,min_reserve,skip_next,skipped_election from stake_policies where wallet_id=? and node_id=?"
+	var p StakePolicy
+	err := store.db.QueryRow(sqlStmt, walletID, nodeID).Scan(&p.ID, &p.WalletID, &p.NodeID, &p.Mode, &p.Amount, &p.Percent, &p.MaxFactor, &p.MinReserve, &p.SkipNext, &p.SkippedElection)
+	if err != nil {
+		return StakePolicy{}, err
+	}
+	return p, nil
+}
+
+//SetStakePolicy add or replace policy of the wallet or the node
+func (store *Store) SetStakePolicy(p StakePolicy) error {
+	stmt, err := store.db.Prepare("INSERT OR REPLACE INTO stake_policies(wallet_id,node_id,mode,amount,percent,max_factor,min_reserve,skip_next,skipped_election,updated_at) values(?,?,?,?,?,?,?,?,?,CURRENT_TIMESTAMP)")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(p.WalletID, p.NodeID, p.Mode, p.Amount, p.Percent, p.MaxFactor, p.MinReserve, p.SkipNext, p.SkippedElection)
+	return err
+}
@@ -0,0 +1,80 @@
+package database
+
+import "database/sql"
+
+//Recovery outcome of a recover_stake request sent to the elector
+type Recovery struct {
+	ID       int64
+	WalletID int
+	QueryID  uint64
+	Result   string
+	Credit   int64
+	Amount   int64
+	TxLt     int64
+	TxHash   string
+	//SentLt last wallet transaction before the request was sent, the answer comes after it
+	SentLt int64
+	//SentAt unix time the request was recorded
+	SentAt int64
+}
+
+const recoveryColumns = "id,wallet_id,query_id,result,credit,amount,ifnull(tx_lt,0),ifnull(tx_hash,''),sent_lt,strftime('%s',created_at)"
+
+//AddRecovery add recovery record
+func (store *Store) AddRecovery(r Recovery) (int64, error) {
+	stmt, err := store.db.Prepare("INSERT INTO recoveries(wallet_id,query_id,result,credit,amount,tx_lt,tx_hash,sent_lt) values(?,?,?,?,?,?,?,?)")
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	res, err := stmt.Exec(r.WalletID, int64(r.QueryID), r.Result, r.Credit, r.Amount, r.TxLt, r.TxHash, r.SentLt)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+//GetRecoveries recoveries of the wallet with the result, oldest first
+func (store *Store) GetRecoveries(walletID int, result string) ([]Recovery, error) {
+	rows, err := store.db.Query("select "+recoveryColumns+" from recoveries where wallet_id=? and result=? order by id", walletID, result)
+	if err != nil {
+		return []Recovery{}, err
+	}
+	return scanRecoveries(rows)
+}
+
+//SetRecoveryResult store the result of the recovery and the transaction the elector answered with
+func (store *Store) SetRecoveryResult(r Recovery) error {
+	stmt, err := store.db.Prepare("update recoveries set result=?,amount=?,tx_lt=?,tx_hash=? where id=?")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(r.Result, r.Amount, r.TxLt, r.TxHash, r.ID)
+	return err
+}
+
+func scanRecoveries(rows *sql.Rows) ([]Recovery, error) {
+	defer rows.Close()
+	var recoveries []Recovery
+	for rows.Next() {
+		var r Recovery
+		var queryID int64
+		if err := rows.Scan(&r.ID, &r.WalletID, &queryID, &r.Result, &r.Credit, &r.Amount, &r.TxLt, &r.TxHash, &r.SentLt, &r.SentAt); err != nil {
+			return recoveries, err
+		}
+		r.QueryID = uint64(queryID)
+		recoveries = append(recoveries, r)
+	}
+	if err := rows.Err(); err != nil {
+		return []Recovery{}, err
+	}
+	return recoveries, nil
+}
@@ -0,0 +1,124 @@
+package database
+
+import (
+	"database/sql"
+	"strings"
+)
+
+//Stake progress of one node in one election
+type Stake struct {
+	ID          int64
+	WalletID    int
+	NodeID      int
+	ElectionID  int64
+	State       string
+	StakeAmount int64
+	MaxFactor   string
+	Signature   string
+	Error       string
+}
+
+const stakeColumns = "id,wallet_id,node_id,election_id,state,stake_amount,max_factor,ifnull(signature,''),ifnull(error,'')"
+
+func scanStake(row interface{ Scan(...interface{}) error }) (Stake, error) {
+	var s Stake
+	err := row.Scan(&s.ID, &s.WalletID, &s.NodeID, &s.ElectionID, &s.State, &s.StakeAmount, &s.MaxFactor, &s.Signature, &s.Error)
+	return s, err
+}
+
+//GetStake get stake by wallet, node and election, sql.ErrNoRows if there is none yet
+func (store *Store) GetStake(walletID, nodeID int, electionID int64) (Stake, error) {
+	sqlStmt := "select " + stakeColumns + " from stakes where wallet_id=? and node_id=? and election_id=?"
+	stake, err := scanStake(store.db.QueryRow(sqlStmt, walletID, nodeID, electionID))
+	if err != nil {
+		return Stake{}, err
+	}
+	return stake, nil
+}
+
+//GetStakes get stakes of the wallet being in one of the given states, all wallets if walletID is 0 and all states if none given
+func (store *Store) GetStakes(walletID int, states ...string) ([]Stake, error) {
+	query := "select " + stakeColumns + " from stakes where (?=0 or wallet_id=?)"
+	args := []interface{}{walletID, walletID}
+	if len(states) > 0 {
+		query += " and state in (?" + strings.Repeat(",?", len(states)-1) + ")"
+		for _, state := range states {
+			args = append(args, state)
+		}
+	}
+	query += " order by election_id, node_id"
+	rows, err := store.db.Query(query, args...)
+	if err != nil {
+		return []Stake{}, err
+	}
+	defer rows.Close()
+	var stakes []Stake
+	for rows.Next() {
+		stake, err := scanStake(rows)
+		if err != nil {
+			return stakes, err
+		}
+		stakes = append(stakes, stake)
+	}
+	err = rows.Err()
+	if err != nil {
+		return []Stake{}, err
+	}
+	return stakes, nil
+}
+
+//AddStake add stake in its initial state
+func (store *Store) AddStake(stake Stake) (int64, error) {
+	tx, err := store.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	res, err := tx.Exec("INSERT INTO stakes(wallet_id,node_id,election_id,state,stake_amount,max_factor) values(?,?,?,?,?,?)",
+		stake.WalletID, stake.NodeID, stake.ElectionID, stake.State, stake.StakeAmount, stake.MaxFactor)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	if err = addStakeTransition(tx, id, "", stake.State); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+//UpdateStake save stake and log the state transition if the state changed
+func (store *Store) UpdateStake(stake Stake) error {
+	tx, err := store.db.Begin()
+	if err != nil {
+		return err
+	}
+	var state string
+	err = tx.QueryRow("select state from stakes where id=?", stake.ID).Scan(&state)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	_, err = tx.Exec("update stakes set state=?,stake_amount=?,max_factor=?,signature=?,error=?,updated_at=CURRENT_TIMESTAMP where id=?",
+		stake.State, stake.StakeAmount, stake.MaxFactor, stake.Signature, stake.Error, stake.ID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if state != stake.State {
+		if err = addStakeTransition(tx, stake.ID, state, stake.State); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func addStakeTransition(tx *sql.Tx, stakeID int64, from, to string) error {
+	_, err := tx.Exec("INSERT INTO stake_transitions(stake_id,from_state,to_state) values(?,?,?)", stakeID, from, to)
+	return err
+}
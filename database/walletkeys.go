@@ -0,0 +1,56 @@
+package database
+
+//WalletKey encrypted private key of a wallet
+type WalletKey struct {
+	WalletID int
+	//KDF key derivation function and its parameters, e.g. scrypt:32768:8:1
+	KDF        string
+	Salt       []byte
+	Nonce      []byte
+	Ciphertext []byte
+}
+
+//SetWalletKey save the encrypted key of the wallet, replacing the one it had
+func (store *Store) SetWalletKey(k WalletKey) error {
+	stmt, err := store.db.Prepare("INSERT OR REPLACE INTO wallet_keys(wallet_id,kdf,salt,nonce,ciphertext) values(?,?,?,?,?)")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(k.WalletID, k.KDF, k.Salt, k.Nonce, k.Ciphertext)
+	return err
+}
+
+//GetWalletKeys get encrypted keys of all wallets
+func (store *Store) GetWalletKeys() ([]WalletKey, error) {
+	rows, err := store.db.Query("select wallet_id,kdf,salt,nonce,ciphertext from wallet_keys order by wallet_id")
+	if err != nil {
+		return []WalletKey{}, err
+	}
+	defer rows.Close()
+	var keys []WalletKey
+	for rows.Next() {
+		var k WalletKey
+		if err = rows.Scan(&k.WalletID, &k.KDF, &k.Salt, &k.Nonce, &k.Ciphertext); err != nil {
+			return keys, err
+		}
+		keys = append(keys, k)
+	}
+	if err = rows.Err(); err != nil {
+		return []WalletKey{}, err
+	}
+	return keys, nil
+}
+
+//DelWalletKey delete the encrypted key of the wallet
+func (store *Store) DelWalletKey(walletID int) error {
+	_, err := store.db.Exec("delete from wallet_keys where wallet_id=?", walletID)
+	return err
+}
+
+//ClearWalletFile forget the key file path of a wallet whose key moved to the keystore
+func (store *Store) ClearWalletFile(walletID int) error {
+	_, err := store.db.Exec("update wallets set wallet_file='' where id=?", walletID)
+	return err
+}
@@ -0,0 +1,161 @@
+package keystore
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/mercuryoio/ton-validator/database"
+	"golang.org/x/crypto/scrypt"
+)
+
+//scrypt parameters of newly encrypted keys
+const (
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+//ErrWrongPassphrase a stored key does not open with the passphrase
+var ErrWrongPassphrase = errors.New("wrong keystore passphrase")
+
+//ErrLocked the keystore has to be unlocked first
+var ErrLocked = errors.New("keystore is locked")
+
+//Keystore wallet private keys kept in the database encrypted with a passphrase,
+//using a key derived with scrypt and AES-256-GCM
+type Keystore struct {
+	store *database.Store
+
+	mu         sync.RWMutex
+	passphrase []byte
+	keys       map[int]ed25519.PrivateKey
+}
+
+//New locked keystore over the wallet keys of the database
+func New(store *database.Store) *Keystore {
+	return &Keystore{store: store}
+}
+
+//Empty no wallet keys are stored yet
+func (k *Keystore) Empty() (bool, error) {
+	keys, err := k.store.GetWalletKeys()
+	if err != nil {
+		return false, err
+	}
+	return len(keys) == 0, nil
+}
+
+//Unlock decrypt all wallet keys with passphrase, ErrWrongPassphrase if any of them does not open with it
+func (k *Keystore) Unlock(passphrase []byte) error {
+	if len(passphrase) == 0 {
+		return fmt.Errorf("empty keystore passphrase")
+	}
+	stored, err := k.store.GetWalletKeys()
+	if err != nil {
+		return err
+	}
+	keys := make(map[int]ed25519.PrivateKey, len(stored))
+	for _, s := range stored {
+		key, err := open(s, passphrase)
+		if err != nil {
+			return fmt.Errorf("wallet %d: %w", s.WalletID, err)
+		}
+		keys[s.WalletID] = key
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.passphrase = passphrase
+	k.keys = keys
+	return nil
+}
+
+//Import encrypt the private key of the wallet with the keystore passphrase and store it
+func (k *Keystore) Import(walletID int, key ed25519.PrivateKey) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.keys == nil {
+		return ErrLocked
+	}
+	sealed, err := seal(walletID, key, k.passphrase)
+	if err != nil {
+		return err
+	}
+	if err = k.store.SetWalletKey(sealed); err != nil {
+		return err
+	}
+	k.keys[walletID] = key
+	return nil
+}
+
+//Key private key of the wallet, false if the keystore is locked or has none for it
+func (k *Keystore) Key(walletID int) (ed25519.PrivateKey, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	key, ok := k.keys[walletID]
+	return key, ok
+}
+
+//seal encrypt the seed of key, the wallet id is authenticated with it so keys can't be swapped
+func seal(walletID int, key ed25519.PrivateKey, passphrase []byte) (database.WalletKey, error) {
+	sealed := database.WalletKey{
+		WalletID: walletID,
+		KDF:      fmt.Sprintf("scrypt:%d:%d:%d", scryptN, scryptR, scryptP),
+		Salt:     make([]byte, 16),
+	}
+	if _, err := rand.Read(sealed.Salt); err != nil {
+		return sealed, err
+	}
+	aead, err := newAEAD(sealed, passphrase)
+	if err != nil {
+		return sealed, err
+	}
+	sealed.Nonce = make([]byte, aead.NonceSize())
+	if _, err = rand.Read(sealed.Nonce); err != nil {
+		return sealed, err
+	}
+	sealed.Ciphertext = aead.Seal(nil, sealed.Nonce, key.Seed(), additionalData(walletID))
+	return sealed, nil
+}
+
+//open decrypt the key sealed with passphrase
+func open(sealed database.WalletKey, passphrase []byte) (ed25519.PrivateKey, error) {
+	aead, err := newAEAD(sealed, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	seed, err := aead.Open(nil, sealed.Nonce, sealed.Ciphertext, additionalData(sealed.WalletID))
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+	if len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("bad key length %d", len(seed))
+	}
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+//newAEAD AES-256-GCM with the key derived from passphrase as the sealed key says
+func newAEAD(sealed database.WalletKey, passphrase []byte) (cipher.AEAD, error) {
+	var n, r, p int
+	if _, err := fmt.Sscanf(sealed.KDF, "scrypt:%d:%d:%d", &n, &r, &p); err != nil {
+		return nil, fmt.Errorf("unsupported kdf %q", sealed.KDF)
+	}
+	key, err := scrypt.Key(passphrase, sealed.Salt, n, r, p, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func additionalData(walletID int) []byte {
+	return []byte("wallet:" + strconv.Itoa(walletID))
+}
@@ -0,0 +1,62 @@
+package keystore
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"errors"
+	"testing"
+
+	"github.com/mercuryoio/ton-validator/database"
+)
+
+func TestSealOpen(t *testing.T) {
+	key := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{0x01}, 32))
+	passphrase := []byte("correct horse")
+	sealed, err := seal(7, key, passphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(sealed.Ciphertext, key.Seed()) {
+		t.Fatalf("seed stored in the clear")
+	}
+
+	tampered := sealed
+	tampered.Ciphertext = append([]byte(nil), sealed.Ciphertext...)
+	tampered.Ciphertext[0] ^= 1
+	swapped := sealed
+	swapped.WalletID = 8
+
+	tests := []struct {
+		name       string
+		sealed     database.WalletKey
+		passphrase []byte
+		err        error
+	}{
+		{"round trip", sealed, passphrase, nil},
+		{"wrong passphrase", sealed, []byte("battery staple"), ErrWrongPassphrase},
+		{"tampered ciphertext", tampered, passphrase, ErrWrongPassphrase},
+		{"other wallet id", swapped, passphrase, ErrWrongPassphrase},
+	}
+	for _, tt := range tests {
+		got, err := open(tt.sealed, tt.passphrase)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s: open() error %v, want %v", tt.name, err, tt.err)
+			continue
+		}
+		if err == nil && !bytes.Equal(got, key) {
+			t.Errorf("%s: open() gives another key", tt.name)
+		}
+	}
+}
+
+func TestOpenUnsupportedKDF(t *testing.T) {
+	key := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{0x01}, 32))
+	sealed, err := seal(1, key, []byte("pass"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed.KDF = "argon2id"
+	if _, err = open(sealed, []byte("pass")); err == nil {
+		t.Errorf("unknown kdf accepted")
+	}
+}
@@ -0,0 +1,52 @@
+package keystore
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"golang.org/x/term"
+)
+
+//PassphraseEnv environment variable the passphrase is taken from when no file is given
+const PassphraseEnv = "TON_KEYSTORE_PASSPHRASE"
+
+//ReadPassphrase keystore passphrase from file, the environment or, in a terminal, a prompt.
+//With confirm the prompt asks for it twice.
+func ReadPassphrase(file string, confirm bool) ([]byte, error) {
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		return bytes.TrimRight(data, "\r\n"), nil
+	}
+	if p := os.Getenv(PassphraseEnv); p != "" {
+		return []byte(p), nil
+	}
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return nil, fmt.Errorf("no keystore passphrase: pass a passphrase file or set %s", PassphraseEnv)
+	}
+	p, err := prompt(fd, "Keystore passphrase: ")
+	if err != nil {
+		return nil, err
+	}
+	if confirm {
+		again, err := prompt(fd, "Repeat passphrase: ")
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(p, again) {
+			return nil, fmt.Errorf("passphrases do not match")
+		}
+	}
+	return p, nil
+}
+
+func prompt(fd int, text string) ([]byte, error) {
+	fmt.Fprint(os.Stderr, text)
+	p, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	return p, err
+}
@@ -0,0 +1,92 @@
+package message
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	"github.com/mercuryoio/ton-validator/cell"
+)
+
+//Address std smart contract address
+type Address struct {
+	Workchain int32
+	Hash      []byte
+}
+
+//String raw form, e.g. -1:3333...3333
+func (a Address) String() string {
+	return fmt.Sprintf("%d:%s", a.Workchain, strings.ToUpper(hex.EncodeToString(a.Hash)))
+}
+
+//Equal same workchain and account id
+func (a Address) Equal(b Address) bool {
+	return a.Workchain == b.Workchain && bytes.Equal(a.Hash, b.Hash)
+}
+
+//ParseAddress parse raw (wc:hex) or user-friendly (base64 or base64url) address
+func ParseAddress(s string) (Address, error) {
+	if i := strings.IndexByte(s, ':'); i >= 0 {
+		wc, err := strconv.ParseInt(s[:i], 10, 8)
+		if err != nil {
+			return Address{}, fmt.Errorf("bad workchain in address %s", s)
+		}
+		hash, err := hex.DecodeString(s[i+1:])
+		if err != nil || len(hash) != 32 {
+			return Address{}, fmt.Errorf("bad account id in address %s", s)
+		}
+		return Address{Workchain: int32(wc), Hash: hash}, nil
+	}
+	if len(s) != 48 {
+		return Address{}, fmt.Errorf("bad address %s", s)
+	}
+	data, err := base64.URLEncoding.DecodeString(strings.NewReplacer("+", "-", "/", "_").Replace(s))
+	if err != nil {
+		return Address{}, fmt.Errorf("bad address %s: %v", s, err)
+	}
+	if crc16(data[:34]) != binary.BigEndian.Uint16(data[34:]) {
+		return Address{}, fmt.Errorf("bad checksum of address %s", s)
+	}
+	if data[0]&0x3f != 0x11 {
+		return Address{}, fmt.Errorf("bad tag of address %s", s)
+	}
+	return Address{Workchain: int32(int8(data[1])), Hash: data[2:34]}, nil
+}
+
+//ReadAddressFile read .addr file: 32 bytes account id and big endian workchain
+func ReadAddressFile(path string) (Address, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Address{}, err
+	}
+	if len(data) != 36 {
+		return Address{}, fmt.Errorf("%s: bad address file length %d", path, len(data))
+	}
+	return Address{Workchain: int32(binary.BigEndian.Uint32(data[32:])), Hash: data[:32]}, nil
+}
+
+//storeAddress addr_std$10 anycast:nothing workchain_id:int8 address:bits256
+func storeAddress(b *cell.Builder, a Address) *cell.Builder {
+	return b.StoreUint(0x4, 3).StoreInt(int64(a.Workchain), 8).StoreBytes(a.Hash)
+}
+
+//crc16 CRC-16/XMODEM used by user-friendly addresses
+func crc16(data []byte) uint16 {
+	var crc uint16
+	for _, b := range data {
+		crc ^= uint16(b) << 8
+		for i := 0; i < 8; i++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
@@ -0,0 +1,111 @@
+package message
+
+import (
+	"crypto/ed25519"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/mercuryoio/ton-validator/cell"
+)
+
+//Elector operations, see elector-code.fc
+const (
+	opNewStake     = 0x4e73744b
+	opRecoverStake = 0x47657424
+	electRequestID = 0x654c5074
+)
+
+//ParseMaxFactor max factor as validator-elect-req.fif takes it: 1.0..100.0, scaled by 65536
+func ParseMaxFactor(s string) (uint32, error) {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("bad max factor %s", s)
+	}
+	if f < 1 || f > 100 {
+		return 0, fmt.Errorf("max factor %s must be in range 1.0..100.0", s)
+	}
+	return uint32(math.Round(f * 65536)), nil
+}
+
+//ElectRequest data the validator key signs to take part in the election
+func ElectRequest(wallet Address, electionID int64, maxFactor uint32, adnlAddr []byte) ([]byte, error) {
+	if wallet.Workchain != -1 {
+		return nil, fmt.Errorf("only masterchain wallets may participate in validator elections, got %s", wallet)
+	}
+	if len(adnlAddr) != 32 {
+		return nil, fmt.Errorf("bad adnl address length %d", len(adnlAddr))
+	}
+	req := make([]byte, 12, 12+64)
+	binary.BigEndian.PutUint32(req, electRequestID)
+	binary.BigEndian.PutUint32(req[4:], uint32(electionID))
+	binary.BigEndian.PutUint32(req[8:], maxFactor)
+	req = append(req, wallet.Hash...)
+	return append(req, adnlAddr...), nil
+}
+
+//NewStake new_stake body for the elector from a request built by ElectRequest and its signature
+func NewStake(req []byte, pubKey ed25519.PublicKey, signature []byte, queryID uint64) (*cell.Cell, error) {
+	if len(req) != 12+64 || binary.BigEndian.Uint32(req) != electRequestID {
+		return nil, fmt.Errorf("bad election request")
+	}
+	if len(pubKey) != ed25519.PublicKeySize || !ed25519.Verify(pubKey, req, signature) {
+		return nil, fmt.Errorf("signature of the election request does not match validator public key")
+	}
+	sig, err := cell.BeginCell().StoreBytes(signature).EndCell()
+	if err != nil {
+		return nil, err
+	}
+	// stake_at and max_factor, the wallet is the sender, then the adnl address
+	return cell.BeginCell().
+		StoreUint(opNewStake, 32).
+		StoreUint(queryID, 64).
+		StoreBytes(pubKey).
+		StoreBytes(req[4:12]).
+		StoreBytes(req[44:]).
+		StoreRef(sig).
+		EndCell()
+}
+
+//RecoverStake recover_stake body for the elector
+func RecoverStake(queryID uint64) (*cell.Cell, error) {
+	return cell.BeginCell().StoreUint(opRecoverStake, 32).StoreUint(queryID, 64).EndCell()
+}
+
+//Elector answers to recover_stake, see elector-code.fc
+const (
+	OpRecoverStakeOk    = 0xf96f7324
+	OpRecoverStakeError = 0xfffffffe
+	opBounced           = 0xffffffff
+)
+
+//Elector answers to new_stake, see elector-code.fc
+const (
+	OpNewStakeOk  = 0xf374484c
+	OpReturnStake = 0xee6f454c
+)
+
+//Answer start of a message body sent back by the elector
+type Answer struct {
+	Op      uint32
+	QueryID uint64
+	//Bounced the elector failed to process our message, Op is the op we sent
+	Bounced bool
+}
+
+//ParseAnswer op and query id from a message body BOC
+func ParseAnswer(boc []byte) (Answer, error) {
+	body, err := cell.FromBOCSingle(boc)
+	if err != nil {
+		return Answer{}, err
+	}
+	s := body.BeginParse()
+	answer := Answer{Op: uint32(s.LoadUint(32))}
+	if answer.Op == opBounced {
+		answer.Bounced = true
+		answer.Op = uint32(s.LoadUint(32))
+	}
+	answer.QueryID = s.LoadUint(64)
+	return answer, s.Err()
+}
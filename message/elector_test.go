@@ -0,0 +1,63 @@
+package message
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/hex"
+	"testing"
+
+	"github.com/mercuryoio/ton-validator/cell"
+)
+
+var (
+	testWallet  = Address{Workchain: -1, Hash: bytes.Repeat([]byte{0x11}, 32)}
+	testElector = Address{Workchain: -1, Hash: bytes.Repeat([]byte{0x33}, 32)}
+)
+
+//testStake new_stake body of testWallet signed by a validator key with a fixed seed
+func testStake(t *testing.T) *cell.Cell {
+	t.Helper()
+	key := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{0x02}, 32))
+	req, err := ElectRequest(testWallet, 1600000000, 3<<16, bytes.Repeat([]byte{0x22}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := NewStake(req, key.Public().(ed25519.PublicKey), ed25519.Sign(key, req), 1600000000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return body
+}
+
+func TestElectorBodies(t *testing.T) {
+	recoverBody, err := RecoverStake(1600000001)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		body *cell.Cell
+		hash string
+	}{
+		{"new_stake", testStake(t), "00729ca68d8fc83f8920646bc2612ae308b6df5eeff9a70fbf660119912d41c5"},
+		{"recover_stake", recoverBody, "abd50a76537f753d38d71a09889285db94de79dc9558fba0f411d3692a61e5ec"},
+	}
+	for _, tt := range tests {
+		if got := hex.EncodeToString(tt.body.Hash()); got != tt.hash {
+			t.Errorf("%s: hash %s, want %s", tt.name, got, tt.hash)
+		}
+	}
+}
+
+func TestNewStakeBadSignature(t *testing.T) {
+	key := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{0x02}, 32))
+	req, err := ElectRequest(testWallet, 1600000000, 3<<16, bytes.Repeat([]byte{0x22}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature := ed25519.Sign(key, req)
+	signature[0] ^= 1
+	if _, err = NewStake(req, key.Public().(ed25519.PublicKey), signature, 1); err == nil {
+		t.Errorf("request with a bad signature accepted")
+	}
+}
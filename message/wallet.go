@@ -0,0 +1,173 @@
+package message
+
+import (
+	"crypto/ed25519"
+	"fmt"
+	"time"
+
+	"github.com/mercuryoio/ton-validator/cell"
+)
+
+//Wallet contract versions, as created by new-wallet.fif, new-wallet-v2.fif and new-wallet-v3.fif
+const (
+	WalletV1 = 1
+	WalletV2 = 2
+	WalletV3 = 3
+)
+
+//DefaultSubwalletID subwallet id of v3 wallets in workchain 0, other workchains add their number
+const DefaultSubwalletID = 698983191
+
+//sendMode pay transfer fees separately and ignore errors, as wallet.fif does
+const sendMode = 3
+
+//validFor lifetime of v2 and v3 messages, the -t default of the fift scripts
+const validFor = 60 * time.Second
+
+//Signer signs the part of wallet messages whose signature the wallet contract checks
+type Signer interface {
+	Sign(wallet Address, signed *cell.Cell) ([]byte, error)
+}
+
+//Wallet simple wallet contract and the signer of its messages
+type Wallet struct {
+	Address     Address
+	Version     int
+	SubwalletID uint32
+	signer      Signer
+}
+
+//NewWallet wallet at addr with messages signed by signer.
+//A zero subwalletID means the default one for the wallet's workchain.
+func NewWallet(addr Address, version int, subwalletID uint32, signer Signer) (*Wallet, error) {
+	if version < WalletV1 || version > WalletV3 {
+		return nil, fmt.Errorf("unsupported wallet version %d", version)
+	}
+	if subwalletID == 0 {
+		subwalletID = uint32(DefaultSubwalletID + addr.Workchain)
+	}
+	return &Wallet{Address: addr, Version: version, SubwalletID: subwalletID, signer: signer}, nil
+}
+
+//Transfer external message asking the wallet to send amount nanograms with body to dest.
+//A nil body sends an empty one.
+func (w *Wallet) Transfer(dest Address, amount int64, bounce bool, body *cell.Cell, seqno uint32) (*cell.Cell, error) {
+	return w.transfer(dest, amount, bounce, body, seqno, uint64(time.Now().Add(validFor).Unix()))
+}
+
+func (w *Wallet) transfer(dest Address, amount int64, bounce bool, body *cell.Cell, seqno uint32, validUntil uint64) (*cell.Cell, error) {
+	internal, err := internalMessage(dest, amount, bounce, body)
+	if err != nil {
+		return nil, err
+	}
+	b := cell.BeginCell()
+	switch w.Version {
+	case WalletV1:
+		b.StoreUint(uint64(seqno), 32)
+	case WalletV2:
+		b.StoreUint(uint64(seqno), 32).StoreUint(validUntil, 32)
+	case WalletV3:
+		b.StoreUint(uint64(w.SubwalletID), 32).StoreUint(validUntil, 32).StoreUint(uint64(seqno), 32)
+	default:
+		return nil, fmt.Errorf("unsupported wallet version %d", w.Version)
+	}
+	signed, err := b.StoreUint(sendMode, 8).StoreRef(internal).EndCell()
+	if err != nil {
+		return nil, err
+	}
+	signature, err := w.signer.Sign(w.Address, signed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign: %w", err)
+	}
+	if len(signature) != ed25519.SignatureSize {
+		return nil, fmt.Errorf("bad signature length %d", len(signature))
+	}
+
+	// ext_in_msg_info$10 src:addr_none dest import_fee:0, no state init, body inline
+	ext := cell.BeginCell().StoreUint(0x2, 2).StoreUint(0, 2)
+	storeAddress(ext, w.Address).StoreGrams(0).StoreUint(0, 2)
+	return ext.StoreBytes(signature).StoreSlice(signed.BeginParse()).EndCell()
+}
+
+//Outgoing internal message a signed wallet message makes the wallet send
+type Outgoing struct {
+	Dest   Address
+	Amount int64
+	Bounce bool
+	Mode   int
+}
+
+//ParseOutgoing what the part of a wallet message built by Transfer for signing sends.
+//Only messages sending exactly one internal message without extra currencies are accepted.
+func ParseOutgoing(signed *cell.Cell) (Outgoing, error) {
+	var out Outgoing
+	// seqno, valid_until and subwallet_id go before the mode, depending on the version
+	switch signed.Bits() {
+	case 32 + 8, 64 + 8, 96 + 8:
+	default:
+		return out, fmt.Errorf("unexpected wallet message of %d bits", signed.Bits())
+	}
+	if signed.RefsNum() != 1 {
+		return out, fmt.Errorf("wallet message sends %d messages, expected 1", signed.RefsNum())
+	}
+	s := signed.BeginParse()
+	s.LoadBits(signed.Bits() - 8)
+	out.Mode = int(s.LoadUint(8))
+
+	m := s.LoadRef().BeginParse()
+	if m.LoadBit() {
+		return out, fmt.Errorf("not an internal message")
+	}
+	m.LoadBit() // ihr_disabled
+	out.Bounce = m.LoadBit()
+	m.LoadBit() // bounced
+	if src := m.LoadUint(2); src != 0 {
+		return out, fmt.Errorf("unexpected source address")
+	}
+	// addr_std$10 anycast:nothing
+	if tag := m.LoadUint(3); tag != 0x4 {
+		return out, fmt.Errorf("unsupported destination address")
+	}
+	out.Dest.Workchain = int32(m.LoadInt(8))
+	out.Dest.Hash = m.LoadBits(256)
+	out.Amount = m.LoadGrams()
+	if m.LoadBit() {
+		return out, fmt.Errorf("extra currencies are not supported")
+	}
+	if err := m.Err(); err != nil {
+		return out, err
+	}
+	return out, s.Err()
+}
+
+//internalMessage int_msg_info with zero fees and times that the wallet fills in
+func internalMessage(dest Address, amount int64, bounce bool, body *cell.Cell) (*cell.Cell, error) {
+	if body == nil {
+		var err error
+		if body, err = cell.BeginCell().EndCell(); err != nil {
+			return nil, err
+		}
+	}
+	// int_msg_info$0 ihr_disabled:1 bounce bounced:0 src:addr_none
+	b := cell.BeginCell().StoreUint(0x1, 2).StoreBit(bounce).StoreUint(0, 3)
+	storeAddress(b, dest).StoreGrams(amount)
+	// no extra currencies, ihr_fee, fwd_fee, created_lt, created_at, no state init
+	b.StoreUint(0, 1+4+4).StoreUint(0, 64).StoreUint(0, 32).StoreUint(0, 1)
+	// body inline when it fits, as wallet.fif does
+	if b.BitsLeft() > body.Bits() && b.RefsLeft() >= body.RefsNum() {
+		return b.StoreBit(false).StoreSlice(body.BeginParse()).EndCell()
+	}
+	return b.StoreBit(true).StoreRef(body).EndCell()
+}
+
+//extHeaderBits ext_in_msg_info$10 with addr_none source, std destination and zero import fee,
+//then no state init and an inline body, as Transfer builds it
+const extHeaderBits = 2 + 2 + 267 + 4 + 2
+
+//ExternalBody body of an external message built by Transfer. Its hash is the body hash
+//tonlib reports for the inbound message of the wallet transaction.
+func ExternalBody(ext *cell.Cell) (*cell.Cell, error) {
+	s := ext.BeginParse()
+	s.LoadBits(extHeaderBits)
+	return cell.BeginCell().StoreSlice(s).EndCell()
+}
@@ -0,0 +1,61 @@
+package message
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/hex"
+	"testing"
+
+	"github.com/mercuryoio/ton-validator/cell"
+)
+
+//keySigner signs with the key, as signer.Local does
+type keySigner ed25519.PrivateKey
+
+func (k keySigner) Sign(_ Address, signed *cell.Cell) ([]byte, error) {
+	return ed25519.Sign(ed25519.PrivateKey(k), signed.Hash()), nil
+}
+
+func TestWalletV3Transfer(t *testing.T) {
+	key := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{0x01}, 32))
+	w, err := NewWallet(testWallet, WalletV3, 0, keySigner(key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ext, err := w.transfer(testElector, 10001e9, true, testStake(t), 5, 1600000060)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := hex.EncodeToString(ext.Hash()), "25cb20fb991e026bc609999fd3113e525780d7b766ac99bfe0f9e06a94af58a7"; got != want {
+		t.Errorf("external message hash %s, want %s", got, want)
+	}
+
+	body, err := ExternalBody(ext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := hex.EncodeToString(body.Hash()), "b5c438bf31d5ed495a5b38c5488a5472f4355ba0df8550dda1d39f8e464a16b1"; got != want {
+		t.Errorf("body hash %s, want %s", got, want)
+	}
+	s := body.BeginParse()
+	signature := s.LoadBits(512)
+	signed, err := cell.BeginCell().StoreSlice(s).EndCell()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// subwallet_id, valid_until, seqno and mode with the internal message to the elector
+	if got, want := hex.EncodeToString(signed.Hash()), "183006a303d991230f31aa258ddf44fc7d0fad3bae78a630b96eb55485d74360"; got != want {
+		t.Errorf("signed part hash %s, want %s", got, want)
+	}
+	if !ed25519.Verify(key.Public().(ed25519.PublicKey), signed.Hash(), signature) {
+		t.Errorf("signature does not match the signed part")
+	}
+
+	out, err := ParseOutgoing(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !out.Dest.Equal(testElector) || out.Amount != 10001e9 || !out.Bounce || out.Mode != sendMode {
+		t.Errorf("ParseOutgoing() = %+v", out)
+	}
+}
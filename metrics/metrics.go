@@ -0,0 +1,125 @@
+package metrics
+
+import (
+	"log"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "ton"
+
+var (
+	//WalletBalance balance of the wallet in grams as stored in the db
+	WalletBalance = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "wallet_balance_grams",
+		Help:      "Wallet balance in grams.",
+	}, []string{"wallet"})
+
+	//PendingReward stake and bonuses the elector is ready to return to the wallet
+	PendingReward = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "pending_reward_grams",
+		Help:      "Grams the elector holds for the wallet and is ready to return.",
+	}, []string{"wallet"})
+
+	//NodeSyncLag seconds between the node's clock and its last masterchain block
+	NodeSyncLag = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "node_sync_lag_seconds",
+		Help:      "Node unixtime minus masterchainblocktime from getstats.",
+	}, []string{"node"})
+
+	//NodeHealth verdict of the node's health check: 0 healthy, 1 degraded, 2 down
+	NodeHealth = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "node_health",
+		Help:      "Node health: 0 healthy, 1 degraded, 2 down.",
+	}, []string{"node"})
+
+	//ActiveElectionID id of the open election, 0 when there is none
+	ActiveElectionID = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "active_election_id",
+		Help:      "ID of the open election, 0 when there is none.",
+	})
+
+	//StakeSubmitted stake the node sent to the active election
+	StakeSubmitted = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "stake_submitted_grams",
+		Help:      "Stake the node submitted to the active election, 0 if none.",
+	}, []string{"node"})
+
+	commandRuns = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "command_runs_total",
+		Help:      "External command runs by result.",
+	}, []string{"command", "result"})
+
+	commandDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "command_duration_seconds",
+		Help:      "Duration of external command runs.",
+		Buckets:   prometheus.ExponentialBuckets(0.01, 2, 12),
+	}, []string{"command"})
+
+	queries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "adnl_queries_total",
+		Help:      "ADNL queries to lite servers and validator engines by result.",
+	}, []string{"server", "result"})
+
+	queryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "adnl_query_duration_seconds",
+		Help:      "Duration of ADNL queries to lite servers and validator engines.",
+		Buckets:   prometheus.ExponentialBuckets(0.01, 2, 12),
+	}, []string{"server"})
+)
+
+func init() {
+	prometheus.MustRegister(WalletBalance, PendingReward, NodeSyncLag, NodeHealth, ActiveElectionID, StakeSubmitted, commandRuns, commandDuration, queries, queryDuration)
+}
+
+//Grams nanograms as grams for gauges
+func Grams(nanograms int64) float64 {
+	return float64(nanograms) / 1e9
+}
+
+//ObserveCommand count the run of command started at start, the binary name is used for paths
+func ObserveCommand(command string, start time.Time, err error) {
+	command = filepath.Base(command)
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	commandRuns.WithLabelValues(command, result).Inc()
+	commandDuration.WithLabelValues(command).Observe(time.Since(start).Seconds())
+}
+
+//ObserveQuery count the ADNL query to server started at start
+func ObserveQuery(server string, start time.Time, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	queries.WithLabelValues(server, result).Inc()
+	queryDuration.WithLabelValues(server).Observe(time.Since(start).Seconds())
+}
+
+//Serve expose /metrics on addr in the background
+func Serve(addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	go func() {
+		log.Println("Serving metrics on", addr)
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			log.Println("Metrics server stopped:", err)
+		}
+	}()
+}
@@ -0,0 +1,146 @@
+package notify
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+//Event kinds
+const (
+	EventNodeOutOfSync   = "node_out_of_sync"
+	EventLowBalance      = "low_balance"
+	EventStakeSent       = "stake_sent"
+	EventStakeRejected   = "stake_rejected"
+	EventStakeFailed     = "stake_failed"
+	EventRewardRecovered = "reward_recovered"
+	EventKeyMissing      = "key_missing"
+	EventBotError        = "bot_error"
+)
+
+//httpClient client for webhook and Telegram requests
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+//Event something the operator should know about. Kind and Subject identify it for de-duplication.
+type Event struct {
+	Kind    string    `json:"kind"`
+	Subject string    `json:"subject"`
+	Message string    `json:"message"`
+	Time    time.Time `json:"time"`
+}
+
+//NewEvent event happening now
+func NewEvent(kind, subject, format string, args ...interface{}) Event {
+	return Event{
+		Kind:    kind,
+		Subject: subject,
+		Message: fmt.Sprintf(format, args...),
+		Time:    time.Now(),
+	}
+}
+
+//Notifier delivers events to the operator
+type Notifier interface {
+	Notify(e Event) error
+}
+
+//Config notifier settings, a notifier is enabled by its address
+type Config struct {
+	WebhookURL     string
+	SMTPAddr       string
+	SMTPUser       string
+	SMTPPassword   string
+	SMTPFrom       string
+	SMTPTo         string
+	TelegramToken  string
+	TelegramChatID string
+	//RepeatAfter how long an event of the same kind and subject stays silenced
+	RepeatAfter time.Duration
+	//MaxPerHour events delivered per hour, the rest is only logged
+	MaxPerHour int
+}
+
+//Dispatcher de-duplicates and rate-limits events and hands them to every notifier
+type Dispatcher struct {
+	Notifiers   []Notifier
+	RepeatAfter time.Duration
+	MaxPerHour  int
+
+	mu        sync.Mutex
+	firing    map[string]time.Time
+	delivered []time.Time
+}
+
+//NewDispatcher dispatcher for the notifiers enabled in config
+func NewDispatcher(config Config) *Dispatcher {
+	d := &Dispatcher{
+		RepeatAfter: config.RepeatAfter,
+		MaxPerHour:  config.MaxPerHour,
+	}
+	if config.WebhookURL != "" {
+		d.Notifiers = append(d.Notifiers, &Webhook{URL: config.WebhookURL})
+	}
+	if config.SMTPAddr != "" {
+		d.Notifiers = append(d.Notifiers, &SMTP{
+			Addr:     config.SMTPAddr,
+			Username: config.SMTPUser,
+			Password: config.SMTPPassword,
+			From:     config.SMTPFrom,
+			To:       strings.Split(config.SMTPTo, ","),
+		})
+	}
+	if config.TelegramToken != "" {
+		d.Notifiers = append(d.Notifiers, &Telegram{Token: config.TelegramToken, ChatID: config.TelegramChatID})
+	}
+	return d
+}
+
+//Notify log the event and deliver it in the background unless it is a repeat or over the rate limit
+func (d *Dispatcher) Notify(e Event) error {
+	log.Printf("Alert %s %s: %s", e.Kind, e.Subject, e.Message)
+	if !d.admit(e) {
+		return nil
+	}
+	for _, n := range d.Notifiers {
+		go func(n Notifier) {
+			if err := n.Notify(e); err != nil {
+				log.Printf("Failed to deliver %s alert with %T: %v", e.Kind, n, err)
+			}
+		}(n)
+	}
+	return nil
+}
+
+//Resolve forget the event of kind and subject, so it is delivered again as soon as it recurs
+func (d *Dispatcher) Resolve(kind, subject string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.firing, kind+"/"+subject)
+}
+
+//admit whether the event is new enough and within the hourly budget
+func (d *Dispatcher) admit(e Event) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	key := e.Kind + "/" + e.Subject
+	if last, ok := d.firing[key]; ok && e.Time.Sub(last) < d.RepeatAfter {
+		return false
+	}
+	hourAgo := e.Time.Add(-time.Hour)
+	for len(d.delivered) > 0 && d.delivered[0].Before(hourAgo) {
+		d.delivered = d.delivered[1:]
+	}
+	if d.MaxPerHour > 0 && len(d.delivered) >= d.MaxPerHour {
+		log.Println("Alert rate limit reached, not delivering", e.Kind, e.Subject)
+		return false
+	}
+	if d.firing == nil {
+		d.firing = make(map[string]time.Time)
+	}
+	d.firing[key] = e.Time
+	d.delivered = append(d.delivered, e.Time)
+	return true
+}
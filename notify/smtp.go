@@ -0,0 +1,32 @@
+package notify
+
+import (
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+)
+
+//SMTP mails events through the server at Addr, with PLAIN auth when Username is set
+type SMTP struct {
+	Addr     string
+	Username string
+	Password string
+	From     string
+	To       []string
+}
+
+//Notify mail the event
+func (s *SMTP) Notify(e Event) error {
+	var auth smtp.Auth
+	if s.Username != "" {
+		host, _, err := net.SplitHostPort(s.Addr)
+		if err != nil {
+			return err
+		}
+		auth = smtp.PlainAuth("", s.Username, s.Password, host)
+	}
+	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: [ton-validator] %s %s\r\n\r\n%s\r\n%s\r\n",
+		s.From, strings.Join(s.To, ", "), e.Kind, e.Subject, e.Message, e.Time.UTC().Format("2006-01-02 15:04:05 MST"))
+	return smtp.SendMail(s.Addr, auth, s.From, s.To, []byte(msg))
+}
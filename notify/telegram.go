@@ -0,0 +1,43 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+)
+
+//telegramAPI Bot API endpoint, token and method are appended
+const telegramAPI = "https://api.telegram.org/bot"
+
+//Telegram sends events as messages from the bot with Token to ChatID
+type Telegram struct {
+	Token  string
+	ChatID string
+}
+
+//Notify send the event to the chat
+func (t *Telegram) Notify(e Event) error {
+	resp, err := httpClient.PostForm(telegramAPI+t.Token+"/sendMessage", url.Values{
+		"chat_id": {t.ChatID},
+		"text":    {fmt.Sprintf("%s %s\n%s", e.Kind, e.Subject, e.Message)},
+	})
+	if ue, ok := err.(*url.Error); ok {
+		// keep the token in the URL out of the logs
+		return fmt.Errorf("sendMessage: %v", ue.Err)
+	}
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	var answer struct {
+		OK          bool   `json:"ok"`
+		Description string `json:"description"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&answer); err != nil {
+		return fmt.Errorf("sendMessage: %v", err)
+	}
+	if !answer.OK {
+		return fmt.Errorf("sendMessage: %s", answer.Description)
+	}
+	return nil
+}
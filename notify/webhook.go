@@ -0,0 +1,29 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+//Webhook posts events as JSON to URL
+type Webhook struct {
+	URL string
+}
+
+//Notify post the event
+func (w *Webhook) Notify(e Event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	resp, err := httpClient.Post(w.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook answered %s", resp.Status)
+	}
+	return nil
+}
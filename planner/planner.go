@@ -0,0 +1,173 @@
+package planner
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/mercuryoio/ton-validator/wrappers/liteclient"
+)
+
+//Planner simulates the active election the way the elector runs it when the election closes
+type Planner struct {
+	Participants []liteclient.Participant
+	Limits       liteclient.ValidatorsConfig
+	StakeConfig  liteclient.StakeConfig
+}
+
+//Election outcome of a simulated election
+type Election struct {
+	//Validators number of elected participants, 0 when the election fails
+	Validators int
+	//MinStake smallest stake among the elected, everybody's effective stake is capped by it times max factor
+	MinStake   int64
+	TotalStake int64
+	//Elected participants in elector order with their effective stakes
+	Elected []liteclient.Participant
+}
+
+//Cap largest stake that counts in full with maxFactor times 65536
+func (e Election) Cap(maxFactor int64) int64 {
+	return effective(liteclient.Participant{Stake: math.MaxInt64, MaxFactor: maxFactor}, e.MinStake)
+}
+
+//Plan what a stake would get in the election
+type Plan struct {
+	Stake     int64
+	MaxFactor int64
+	Elected   bool
+	//Effective part of the stake the elector keeps, the rest is returned after the election
+	Effective int64
+	//MinWinning smallest stake with the same max factor that still gets elected, 0 if none does
+	MinWinning int64
+	Election   Election
+}
+
+//Load planner with the current participants and network config
+func Load(lc *liteclient.Config, elector string, limits liteclient.ValidatorsConfig, stakeConfig liteclient.StakeConfig) (*Planner, error) {
+	participants, err := lc.GetParticipants(elector)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get participants: %v", err)
+	}
+	return &Planner{
+		Participants: participants,
+		Limits:       limits,
+		StakeConfig:  stakeConfig,
+	}, nil
+}
+
+//Simulate run the election over the participants and extra stakes, which lose ties as the later ones
+func (p *Planner) Simulate(extra ...liteclient.Participant) Election {
+	all := append(p.Participants[:len(p.Participants):len(p.Participants)], extra...)
+	list := make([]liteclient.Participant, 0, len(all))
+	for _, c := range all {
+		if c.Stake < p.StakeConfig.MinStake {
+			continue
+		}
+		if c.Stake > p.StakeConfig.MaxStake {
+			c.Stake = p.StakeConfig.MaxStake
+		}
+		if c.MaxFactor == 0 || c.MaxFactor > p.StakeConfig.MaxStakeFactor {
+			c.MaxFactor = p.StakeConfig.MaxStakeFactor
+		}
+		list = append(list, c)
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].Stake > list[j].Stake })
+
+	n := len(list)
+	if n > p.Limits.MaxValidators {
+		n = p.Limits.MaxValidators
+	}
+	var best Election
+	for i := p.Limits.MinValidators; i <= n; i++ {
+		if i == 0 {
+			continue
+		}
+		minStake := list[i-1].Stake
+		total := totalStake(list[:i], minStake)
+		if total > best.TotalStake {
+			best = Election{Validators: i, MinStake: minStake, TotalStake: total}
+		}
+	}
+	if best.Validators == 0 || best.TotalStake < p.StakeConfig.MinTotalStake {
+		return Election{}
+	}
+	best.Elected = list[:best.Validators]
+	for i := range best.Elected {
+		best.Elected[i].Stake = effective(best.Elected[i], best.MinStake)
+	}
+	return best
+}
+
+//Plan simulate the election with stake nanograms and maxFactor times 65536 of publicKey added.
+//publicKey may be nil for a stake whose validator key is not created yet.
+func (p *Planner) Plan(publicKey []byte, stake, maxFactor int64) Plan {
+	plan := Plan{
+		Stake:      stake,
+		MaxFactor:  maxFactor,
+		MinWinning: p.MinWinningStake(publicKey, maxFactor),
+	}
+	plan.Election = p.Simulate(liteclient.Participant{PublicKey: publicKey, Stake: stake, MaxFactor: maxFactor})
+	for _, e := range plan.Election.Elected {
+		if bytes.Equal(e.PublicKey, publicKey) {
+			plan.Elected = true
+			plan.Effective = e.Stake
+		}
+	}
+	return plan
+}
+
+//MinWinningStake smallest stake in whole grams that gets publicKey elected with maxFactor, 0 if no stake does
+func (p *Planner) MinWinningStake(publicKey []byte, maxFactor int64) int64 {
+	elected := func(grams int64) bool {
+		election := p.Simulate(liteclient.Participant{PublicKey: publicKey, Stake: grams * 1e9, MaxFactor: maxFactor})
+		for _, e := range election.Elected {
+			if bytes.Equal(e.PublicKey, publicKey) {
+				return true
+			}
+		}
+		return false
+	}
+	lo, hi := (p.StakeConfig.MinStake+1e9-1)/1e9, p.StakeConfig.MaxStake/1e9
+	if lo > hi || !elected(hi) {
+		return 0
+	}
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		if elected(mid) {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+	return lo * 1e9
+}
+
+//Size stake nanograms to send out of budget nanograms: the part of the budget the elector
+//would return anyway stays on the wallet
+func (p *Planner) Size(publicKey []byte, budget, maxFactor int64) (int64, Plan) {
+	plan := p.Plan(publicKey, budget, maxFactor)
+	if !plan.Elected || plan.Effective >= budget {
+		return budget, plan
+	}
+	return plan.Effective, plan
+}
+
+//totalStake stakes of the elected counted up to their max factor times minStake
+func totalStake(elected []liteclient.Participant, minStake int64) int64 {
+	var total int64
+	for _, c := range elected {
+		total += effective(c, minStake)
+	}
+	return total
+}
+
+//effective min(stake, max_factor * minStake / 65536) without overflowing int64
+func effective(c liteclient.Participant, minStake int64) int64 {
+	limit := (minStake>>16)*c.MaxFactor + (minStake&0xffff)*c.MaxFactor>>16
+	if c.Stake < limit {
+		return c.Stake
+	}
+	return limit
+}
@@ -0,0 +1,70 @@
+package signer
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mercuryoio/ton-validator/cell"
+	"github.com/mercuryoio/ton-validator/message"
+)
+
+//signRequest body of POST /sign
+type signRequest struct {
+	//Wallet raw address of the wallet, e.g. -1:3333...3333
+	Wallet string `json:"wallet"`
+	//Message base64 bag of cells with the part of the wallet message to sign
+	Message string `json:"message"`
+}
+
+//signResponse answer to POST /sign, either the base64 signature or the error
+type signResponse struct {
+	Signature string `json:"signature,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+//Remote asks a signer server holding the wallet keys to sign
+type Remote struct {
+	URL string
+	//Token sent as a bearer token, may be empty
+	Token  string
+	client *http.Client
+}
+
+//NewRemote signer server at url
+func NewRemote(url, token string, timeout time.Duration) *Remote {
+	return &Remote{URL: strings.TrimSuffix(url, "/"), Token: token, client: &http.Client{Timeout: timeout}}
+}
+
+//Sign ask the server to sign the cell for wallet
+func (r *Remote) Sign(wallet message.Address, signed *cell.Cell) ([]byte, error) {
+	body, err := json.Marshal(signRequest{Wallet: wallet.String(), Message: base64.StdEncoding.EncodeToString(signed.ToBOC())})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, r.URL+"/sign", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if r.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+r.Token)
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var answer signResponse
+	if err = json.NewDecoder(resp.Body).Decode(&answer); err != nil {
+		return nil, fmt.Errorf("remote signer answered %s: %v", resp.Status, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("remote signer refused: %s", answer.Error)
+	}
+	return base64.StdEncoding.DecodeString(answer.Signature)
+}
@@ -0,0 +1,114 @@
+package signer
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/mercuryoio/ton-validator/cell"
+	"github.com/mercuryoio/ton-validator/message"
+	"github.com/mercuryoio/ton-validator/utils"
+)
+
+//Policy what the signer server agrees to sign
+type Policy struct {
+	//Allow destinations wallets may send to
+	Allow []message.Address
+	//MaxAmount most nanograms one message may send
+	MaxAmount int64
+}
+
+//Check the message sends an allowed amount to an allowed destination without touching the rest of the balance
+func (p Policy) Check(out message.Outgoing) error {
+	allowed := false
+	for _, a := range p.Allow {
+		if a.Equal(out.Dest) {
+			allowed = true
+		}
+	}
+	if !allowed {
+		return fmt.Errorf("destination %s is not allowed", out.Dest)
+	}
+	if out.Amount > p.MaxAmount {
+		return fmt.Errorf("amount %s is over the limit of %s", utils.FormatGrams(out.Amount), utils.FormatGrams(p.MaxAmount))
+	}
+	// 128 and 64 would send the whole balance or the inbound value instead of the amount
+	if out.Mode&^3 != 0 {
+		return fmt.Errorf("send mode %d is not allowed", out.Mode)
+	}
+	return nil
+}
+
+//Server signs wallet messages the policy allows with the signers of the wallets, keyed by raw address
+type Server struct {
+	Signers map[string]message.Signer
+	Policy  Policy
+	//Token bearer token requests must carry, empty to accept all
+	Token string
+}
+
+//ServeHTTP handle POST /sign
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/sign" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		reply(w, http.StatusMethodNotAllowed, signResponse{Error: "POST only"})
+		return
+	}
+	if s.Token != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+s.Token)) != 1 {
+		reply(w, http.StatusUnauthorized, signResponse{Error: "bad token"})
+		return
+	}
+	var req signRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<10)).Decode(&req); err != nil {
+		reply(w, http.StatusBadRequest, signResponse{Error: err.Error()})
+		return
+	}
+	signature, err := s.sign(req)
+	if err != nil {
+		log.Println("Refused to sign for", req.Wallet, err)
+		reply(w, http.StatusForbidden, signResponse{Error: err.Error()})
+		return
+	}
+	reply(w, http.StatusOK, signResponse{Signature: base64.StdEncoding.EncodeToString(signature)})
+}
+
+//sign check the request against the policy and sign it
+func (s *Server) sign(req signRequest) ([]byte, error) {
+	wallet, err := message.ParseAddress(req.Wallet)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := s.Signers[wallet.String()]
+	if !ok {
+		return nil, fmt.Errorf("unknown wallet %s", req.Wallet)
+	}
+	boc, err := base64.StdEncoding.DecodeString(req.Message)
+	if err != nil {
+		return nil, err
+	}
+	signed, err := cell.FromBOCSingle(boc)
+	if err != nil {
+		return nil, err
+	}
+	out, err := message.ParseOutgoing(signed)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.Policy.Check(out); err != nil {
+		return nil, err
+	}
+	log.Println("Signing", utils.FormatGrams(out.Amount), "from", wallet, "to", out.Dest)
+	return signer.Sign(wallet, signed)
+}
+
+func reply(w http.ResponseWriter, status int, resp signResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
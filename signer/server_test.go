@@ -0,0 +1,77 @@
+package signer_test
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mercuryoio/ton-validator/message"
+	"github.com/mercuryoio/ton-validator/signer"
+	"github.com/mercuryoio/ton-validator/signer/signertest"
+)
+
+var (
+	testWallet  = message.Address{Workchain: -1, Hash: bytes.Repeat([]byte{0x11}, 32)}
+	testElector = message.Address{Workchain: -1, Hash: bytes.Repeat([]byte{0x33}, 32)}
+	testOther   = message.Address{Workchain: 0, Hash: bytes.Repeat([]byte{0x44}, 32)}
+)
+
+func TestPolicyCheck(t *testing.T) {
+	policy := signer.Policy{Allow: []message.Address{testElector}, MaxAmount: 10001e9}
+	tests := []struct {
+		name string
+		out  message.Outgoing
+		ok   bool
+	}{
+		{"allowed", message.Outgoing{Dest: testElector, Amount: 10001e9, Bounce: true, Mode: 3}, true},
+		{"destination not allowed", message.Outgoing{Dest: testOther, Amount: 1e9, Mode: 3}, false},
+		{"amount over the cap", message.Outgoing{Dest: testElector, Amount: 10001e9 + 1, Mode: 3}, false},
+		{"whole balance", message.Outgoing{Dest: testElector, Amount: 1e9, Mode: 128}, false},
+		{"inbound value", message.Outgoing{Dest: testElector, Amount: 1e9, Mode: 64 + 3}, false},
+	}
+	for _, tt := range tests {
+		if err := policy.Check(tt.out); (err == nil) != tt.ok {
+			t.Errorf("%s: Check() = %v, want ok %v", tt.name, err, tt.ok)
+		}
+	}
+}
+
+func TestServer(t *testing.T) {
+	stub, err := signertest.NewStub()
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(&signer.Server{
+		Signers: map[string]message.Signer{testWallet.String(): stub},
+		Policy:  signer.Policy{Allow: []message.Address{testElector}, MaxAmount: 10001e9},
+		Token:   "secret",
+	})
+	defer server.Close()
+
+	tests := []struct {
+		name   string
+		token  string
+		dest   message.Address
+		amount int64
+		ok     bool
+	}{
+		{"allowed", "secret", testElector, 10001e9, true},
+		{"bad token", "wrong", testElector, 1e9, false},
+		{"destination not allowed", "secret", testOther, 1e9, false},
+		{"amount over the cap", "secret", testElector, 10002e9, false},
+	}
+	for _, tt := range tests {
+		wallet, err := message.NewWallet(testWallet, message.WalletV3, 0, signer.NewRemote(server.URL, tt.token, time.Second))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = wallet.Transfer(tt.dest, tt.amount, true, nil, 1)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: Transfer() = %v, want ok %v", tt.name, err, tt.ok)
+		}
+	}
+	if signed := stub.Signed(); len(signed) != 1 || !signed[0].Dest.Equal(testElector) || signed[0].Amount != 10001e9 {
+		t.Errorf("signed %v, want one message of 10001 to the elector", signed)
+	}
+}
@@ -0,0 +1,83 @@
+package signer
+
+import (
+	"crypto/ed25519"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mercuryoio/ton-validator/adnl"
+	"github.com/mercuryoio/ton-validator/cell"
+	"github.com/mercuryoio/ton-validator/keystore"
+	"github.com/mercuryoio/ton-validator/message"
+)
+
+//remoteTimeout how long a remote signer may take to answer
+const remoteTimeout = 30 * time.Second
+
+//New signer of all wallets from spec, the http(s) URL of a remote signer.
+//An empty spec gives nil, wallets are signed with their own keys then.
+func New(spec, token string) (message.Signer, error) {
+	switch {
+	case spec == "":
+		return nil, nil
+	case strings.HasPrefix(spec, "http://") || strings.HasPrefix(spec, "https://"):
+		return NewRemote(spec, token, remoteTimeout), nil
+	}
+	return nil, fmt.Errorf("unknown signer %q, expected a http(s) URL", spec)
+}
+
+//Local signs with a private key in memory
+type Local struct {
+	key ed25519.PrivateKey
+}
+
+//NewLocal signer with key
+func NewLocal(key ed25519.PrivateKey) *Local {
+	return &Local{key: key}
+}
+
+//LoadFile signer with the key of <base>.pk and the wallet address from <base>.addr,
+//the base may be given with the .pk extension
+func LoadFile(base string) (*Local, message.Address, error) {
+	base = strings.TrimSuffix(base, ".pk")
+	key, err := adnl.ReadPrivateKey(base + ".pk")
+	if err != nil {
+		return nil, message.Address{}, err
+	}
+	addr, err := message.ReadAddressFile(base + ".addr")
+	if err != nil {
+		return nil, message.Address{}, err
+	}
+	return NewLocal(key), addr, nil
+}
+
+//Sign sign the hash of the cell
+func (l *Local) Sign(_ message.Address, signed *cell.Cell) ([]byte, error) {
+	return ed25519.Sign(l.key, signed.Hash()), nil
+}
+
+//PublicKey public key of the signer
+func (l *Local) PublicKey() ed25519.PublicKey {
+	return l.key.Public().(ed25519.PublicKey)
+}
+
+//Keystore signs with the key of a wallet in the keystore
+type Keystore struct {
+	keys     *keystore.Keystore
+	walletID int
+}
+
+//NewKeystore signer with the key of wallet walletID from keys
+func NewKeystore(keys *keystore.Keystore, walletID int) *Keystore {
+	return &Keystore{keys: keys, walletID: walletID}
+}
+
+//Sign sign the hash of the cell, the keystore must be unlocked
+func (k *Keystore) Sign(_ message.Address, signed *cell.Cell) ([]byte, error) {
+	key, ok := k.keys.Key(k.walletID)
+	if !ok {
+		return nil, fmt.Errorf("wallet %d: %w", k.walletID, keystore.ErrLocked)
+	}
+	return ed25519.Sign(key, signed.Hash()), nil
+}
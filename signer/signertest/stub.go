@@ -0,0 +1,52 @@
+package signertest
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"sync"
+
+	"github.com/mercuryoio/ton-validator/cell"
+	"github.com/mercuryoio/ton-validator/message"
+	"github.com/mercuryoio/ton-validator/signer"
+)
+
+//Stub signs with a key of its own and remembers what it signed
+type Stub struct {
+	local *signer.Local
+
+	mu     sync.Mutex
+	signed []message.Outgoing
+}
+
+//NewStub stub signer with a new random key
+func NewStub() (*Stub, error) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return &Stub{local: signer.NewLocal(key)}, nil
+}
+
+//Sign sign a message that passes ParseOutgoing and remember what it sends
+func (s *Stub) Sign(wallet message.Address, signed *cell.Cell) ([]byte, error) {
+	out, err := message.ParseOutgoing(signed)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.signed = append(s.signed, out)
+	s.mu.Unlock()
+	return s.local.Sign(wallet, signed)
+}
+
+//Signed messages signed so far, oldest first
+func (s *Stub) Signed() []message.Outgoing {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]message.Outgoing(nil), s.signed...)
+}
+
+//PublicKey public key of the stub
+func (s *Stub) PublicKey() ed25519.PublicKey {
+	return s.local.PublicKey()
+}
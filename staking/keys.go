@@ -0,0 +1,114 @@
+package staking
+
+import (
+	"errors"
+	"log"
+
+	"github.com/mercuryoio/ton-validator/database"
+	"github.com/mercuryoio/ton-validator/wrappers/validator"
+)
+
+//KeySet keys created on a node for one election
+type KeySet struct {
+	ElectionID int64
+	ExpireAt   int64
+	Keys       []database.Key
+}
+
+//Key hash or public key of the given type, empty if the set has none. Keys that replaced removed ones come first.
+func (s KeySet) Key(keyType string) string {
+	key := ""
+	for _, k := range s.Keys {
+		if k.Type == keyType && (key == "" || k.DeletedAt == "") {
+			key = k.Key
+		}
+	}
+	return key
+}
+
+//Deleted all keys of the set are removed from the node
+func (s KeySet) Deleted() bool {
+	for _, k := range s.Keys {
+		if k.DeletedAt == "" {
+			return false
+		}
+	}
+	return true
+}
+
+//NodeKeys keys of the node by election, oldest first. Keys stored before expiries were
+//tracked expire when KeyExpireAt says.
+func (m *Machine) NodeKeys(nodeID int) ([]KeySet, error) {
+	keys, err := m.Store.GetNodeKeys(nodeID)
+	if err != nil {
+		return nil, err
+	}
+	var sets []KeySet
+	for _, k := range keys {
+		if len(sets) == 0 || sets[len(sets)-1].ElectionID != k.ElectionID {
+			sets = append(sets, KeySet{ElectionID: k.ElectionID})
+		}
+		set := &sets[len(sets)-1]
+		expireAt := k.ExpireAt
+		if expireAt == 0 {
+			expireAt = m.Periods.KeyExpireAt(k.ElectionID)
+		}
+		if expireAt > set.ExpireAt {
+			set.ExpireAt = expireAt
+		}
+		set.Keys = append(set.Keys, k)
+	}
+	return sets, nil
+}
+
+//PruneKeys remove the keys of the node that expired by now from it: the temp and perm keys and the ADNL address.
+//With dryRun nothing is removed. The sets that expired are returned.
+func (m *Machine) PruneKeys(node database.Node, now int64, dryRun bool) ([]KeySet, error) {
+	sets, err := m.NodeKeys(node.ID)
+	if err != nil {
+		return nil, err
+	}
+	var pruned []KeySet
+	for _, set := range sets {
+		if set.ExpireAt > now || set.Deleted() {
+			continue
+		}
+		if !dryRun {
+			if err = m.deleteKeys(node, set); err != nil {
+				return pruned, err
+			}
+			log.Println("Removed keys of election", set.ElectionID, "from node", node.HostPort)
+		}
+		pruned = append(pruned, set)
+	}
+	return pruned, nil
+}
+
+//deleteKeys remove the set from the node. Keys the node refuses to remove are taken as removed
+//already, e.g. by an earlier prune that failed half way.
+func (m *Machine) deleteKeys(node database.Node, set KeySet) error {
+	if key := set.Key("key"); key != "" {
+		if err := gone(m.Validator.ValidatorDelTempKey(node, key, key)); err != nil {
+			return err
+		}
+		if err := gone(m.Validator.ValidatorDelPermKey(node, key)); err != nil {
+			return err
+		}
+	}
+	if adnlKey := set.Key("adnlkey"); adnlKey != "" {
+		if err := gone(m.Validator.ValidatorDelAdnl(node, adnlKey)); err != nil {
+			return err
+		}
+	}
+	return m.Store.SetKeysDeleted(node.ID, set.ElectionID)
+}
+
+//gone nil for errors of the engine refusing the query
+func gone(err error) error {
+	var ce validator.ControlError
+	if errors.As(err, &ce) {
+		log.Println("Key is not on the node:", ce.Message)
+		return nil
+	}
+	return err
+}
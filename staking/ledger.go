@@ -0,0 +1,160 @@
+package staking
+
+import (
+	"sort"
+
+	"github.com/mercuryoio/ton-validator/database"
+)
+
+//WalletLedgerID election id of the wallet's ledger row that gets credit no election can be credited with
+const WalletLedgerID = 0
+
+//claim what the stakes of the wallet in one election still expect back from a recovered credit, in nanograms
+type claim struct {
+	ElectionID int64
+	//Due stake not booked as returned yet
+	Due int64
+	//Weight share of the bonuses, 0 if the stake earns none
+	Weight int64
+	//Excess the part of a frozen stake the elector returned at conduct, paid before everything else
+	Excess bool
+}
+
+//claims of the frozen stakes for their excess over the effective stake and of the closing stakes for the rest.
+//Stake already booked as returned in an election counts against its excess first.
+func (m *Machine) claims(wallet database.Wallet, frozen, closing []database.Stake, effective map[int64]int64) ([]claim, error) {
+	elections := make(map[int64]bool)
+	for _, stake := range append(append([]database.Stake(nil), frozen...), closing...) {
+		elections[stake.ElectionID] = true
+	}
+	ids := make([]int64, 0, len(elections))
+	for id := range elections {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	var claims []claim
+	for _, electionID := range ids {
+		earning, err := m.Store.GetEarning(wallet.ID, electionID)
+		if err != nil {
+			return nil, err
+		}
+		booked := earning.StakeReturned
+		for _, stake := range frozen {
+			if stake.ElectionID != electionID {
+				continue
+			}
+			excess := stake.StakeAmount*1e9 - effective[stake.ID]
+			if excess <= 0 {
+				continue
+			}
+			paid := min64(booked, excess)
+			booked -= paid
+			if excess > paid {
+				claims = append(claims, claim{ElectionID: electionID, Due: excess - paid, Excess: true})
+			}
+		}
+		for _, stake := range closing {
+			if stake.ElectionID != electionID {
+				continue
+			}
+			paid := min64(booked, stake.StakeAmount*1e9)
+			booked -= paid
+			due := stake.StakeAmount*1e9 - paid
+			weight, err := m.bonusWeight(stake, due)
+			if err != nil {
+				return nil, err
+			}
+			claims = append(claims, claim{ElectionID: electionID, Due: due, Weight: weight})
+		}
+	}
+	return claims, nil
+}
+
+//bonusWeight share of the bonuses of a closing stake: its effective stake if it was elected,
+//nothing if the election went without it, its due stake while the outcome is unknown
+func (m *Machine) bonusWeight(stake database.Stake, due int64) (int64, error) {
+	participates, err := m.Store.GetElectionParticipates(stake.ElectionID)
+	if err != nil {
+		return 0, err
+	}
+	for _, p := range participates {
+		if p.NodeID != stake.NodeID {
+			continue
+		}
+		switch p.Outcome {
+		case OutcomeElected:
+			if p.EffectiveStake > 0 {
+				return p.EffectiveStake, nil
+			}
+		case OutcomeNotElected, OutcomeReturned:
+			return 0, nil
+		}
+	}
+	return due, nil
+}
+
+//bookCredit split the credit between the claims: the excess of frozen stakes first, then the due
+//stakes as returned and what is left as bonuses in proportion to the weights. A credit below the
+//due stakes is booked as returned stakes in proportion and short is set. Credit left with no claim
+//to take it is returned as unattributed.
+func bookCredit(credit int64, claims []claim) (earnings []database.Earning, unattributed int64, short bool) {
+	rest := credit
+	var due, weights int64
+	for _, c := range claims {
+		if !c.Excess {
+			due += c.Due
+			weights += c.Weight
+			continue
+		}
+		paid := min64(rest, c.Due)
+		if paid > 0 {
+			earnings = append(earnings, database.Earning{ElectionID: c.ElectionID, StakeReturned: paid})
+			rest -= paid
+		}
+	}
+	if due == 0 {
+		return earnings, rest, false
+	}
+
+	short = rest < due
+	returns, bonuses := due, rest-due
+	if short {
+		returns, bonuses = rest, 0
+	}
+	if weights == 0 {
+		unattributed, bonuses = bonuses, 0
+	}
+	var returned, shared int64
+	last, lastWeighted := -1, -1
+	for _, c := range claims {
+		if c.Excess {
+			continue
+		}
+		e := database.Earning{ElectionID: c.ElectionID, StakeReturned: c.Due}
+		if short {
+			e.StakeReturned = muldiv(returns, c.Due, due)
+		}
+		e.Bonus = muldiv(bonuses, c.Weight, weights)
+		returned += e.StakeReturned
+		shared += e.Bonus
+		earnings = append(earnings, e)
+		last = len(earnings) - 1
+		if c.Weight > 0 {
+			lastWeighted = last
+		}
+	}
+	// rounding leftovers go to the last stake taking a share
+	earnings[last].StakeReturned += returns - returned
+	if lastWeighted >= 0 {
+		earnings[lastWeighted].Bonus += bonuses - shared
+	}
+	return earnings, unattributed, short
+}
+
+func min64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
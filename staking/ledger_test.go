@@ -0,0 +1,107 @@
+package staking
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mercuryoio/ton-validator/database"
+)
+
+const gram = 1e9
+
+func TestBookCredit(t *testing.T) {
+	tests := []struct {
+		name         string
+		credit       int64
+		claims       []claim
+		earnings     []database.Earning
+		unattributed int64
+		short        bool
+	}{
+		{
+			name:   "excess returned at conduct",
+			credit: 2000 * gram,
+			claims: []claim{{ElectionID: 100, Due: 2000 * gram, Excess: true}},
+			earnings: []database.Earning{
+				{ElectionID: 100, StakeReturned: 2000 * gram},
+			},
+		},
+		{
+			name:   "excess with credit of no election",
+			credit: 2100 * gram,
+			claims: []claim{{ElectionID: 100, Due: 2000 * gram, Excess: true}},
+			earnings: []database.Earning{
+				{ElectionID: 100, StakeReturned: 2000 * gram},
+			},
+			unattributed: 100 * gram,
+		},
+		{
+			name:   "frozen part with bonus after the excess",
+			credit: 8100 * gram,
+			claims: []claim{{ElectionID: 100, Due: 8000 * gram, Weight: 8000 * gram}},
+			earnings: []database.Earning{
+				{ElectionID: 100, StakeReturned: 8000 * gram, Bonus: 100 * gram},
+			},
+		},
+		{
+			name:   "short credit",
+			credit: 9000 * gram,
+			claims: []claim{{ElectionID: 100, Due: 10000 * gram, Weight: 10000 * gram}},
+			earnings: []database.Earning{
+				{ElectionID: 100, StakeReturned: 9000 * gram},
+			},
+			short: true,
+		},
+		{
+			name:   "short credit over two elections",
+			credit: 10,
+			claims: []claim{{ElectionID: 100, Due: 10, Weight: 10}, {ElectionID: 200, Due: 20, Weight: 20}},
+			earnings: []database.Earning{
+				{ElectionID: 100, StakeReturned: 3},
+				{ElectionID: 200, StakeReturned: 7},
+			},
+			short: true,
+		},
+		{
+			name:   "bonus by weight with rounding",
+			credit: 40 + 10,
+			claims: []claim{{ElectionID: 100, Due: 10, Weight: 10}, {ElectionID: 200, Due: 30, Weight: 20}},
+			earnings: []database.Earning{
+				{ElectionID: 100, StakeReturned: 10, Bonus: 3},
+				{ElectionID: 200, StakeReturned: 30, Bonus: 7},
+			},
+		},
+		{
+			name:   "not elected earns no bonus",
+			credit: 10050 * gram,
+			claims: []claim{{ElectionID: 100, Due: 10000 * gram}},
+			earnings: []database.Earning{
+				{ElectionID: 100, StakeReturned: 10000 * gram},
+			},
+			unattributed: 50 * gram,
+		},
+		{
+			name:   "excess and frozen part together",
+			credit: 10000*gram + 500*gram,
+			claims: []claim{
+				{ElectionID: 100, Due: 1500 * gram, Excess: true},
+				{ElectionID: 200, Due: 9000 * gram, Weight: 9000 * gram},
+			},
+			earnings: []database.Earning{
+				{ElectionID: 100, StakeReturned: 1500 * gram},
+				{ElectionID: 200, StakeReturned: 9000 * gram},
+			},
+		},
+		{
+			name:         "nothing to claim",
+			credit:       5 * gram,
+			unattributed: 5 * gram,
+		},
+	}
+	for _, tt := range tests {
+		earnings, unattributed, short := bookCredit(tt.credit, tt.claims)
+		if !reflect.DeepEqual(earnings, tt.earnings) || unattributed != tt.unattributed || short != tt.short {
+			t.Errorf("%s: bookCredit() = %+v, %d, %v, want %+v, %d, %v", tt.name, earnings, unattributed, short, tt.earnings, tt.unattributed, tt.short)
+		}
+	}
+}
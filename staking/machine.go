@@ -0,0 +1,742 @@
+package staking
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/mercuryoio/ton-validator/adnl"
+	"github.com/mercuryoio/ton-validator/database"
+	"github.com/mercuryoio/ton-validator/keystore"
+	"github.com/mercuryoio/ton-validator/message"
+	"github.com/mercuryoio/ton-validator/metrics"
+	"github.com/mercuryoio/ton-validator/notify"
+	"github.com/mercuryoio/ton-validator/utils"
+	"github.com/mercuryoio/ton-validator/wrappers/liteclient"
+	"github.com/mercuryoio/ton-validator/wrappers/validator"
+	tonlib "github.com/mercuryoio/tonlib-go/v2"
+)
+
+//Stake states, every stake moves along this list or ends up failed
+const (
+	StateDiscovered    = "discovered"
+	StateKeysCreated   = "keys_created"
+	StateRequestSigned = "request_signed"
+	StateStakeSent     = "stake_sent"
+	StateConfirmed     = "confirmed"
+	StateFrozen        = "frozen"
+	StateRecoverable   = "recoverable"
+	StateRecovered     = "recovered"
+	StateFailed        = "failed"
+)
+
+//OpenStates states that still need work from the bot
+var OpenStates = []string{
+	StateDiscovered,
+	StateKeysCreated,
+	StateRequestSigned,
+	StateStakeSent,
+	StateConfirmed,
+	StateFrozen,
+	StateRecoverable,
+}
+
+//Submitted the stake has been sent to the elector
+func Submitted(state string) bool {
+	switch state {
+	case StateStakeSent, StateConfirmed, StateFrozen, StateRecoverable, StateRecovered:
+		return true
+	}
+	return false
+}
+
+//Machine moves stakes from one state to the next and stores every transition
+type Machine struct {
+	Store       *database.Store
+	Ton         *tonlib.Client
+	Validator   *validator.Config
+	ElectorAddr string
+	Periods     liteclient.ElectionPeriods
+	StakeConfig liteclient.StakeConfig
+	//Lite and Limits let the stake planner size new stakes, without Lite the policy amount is staked as is
+	Lite   *liteclient.Config
+	Limits liteclient.ValidatorsConfig
+	//Notifier gets stake events, may be nil
+	Notifier notify.Notifier
+	//Keys unlocked keystore with the wallet keys, may be nil when all wallets still use key files
+	Keys *keystore.Keystore
+	//Signer signs messages of all wallets instead of their own keys, e.g. a remote signer, may be nil
+	Signer message.Signer
+
+	//locks serialize stake planning per wallet
+	locks sync.Map
+	//senders of the wallets by wallet id
+	senders sync.Map
+	//StakeAmount and MaxFactor are used for wallets and nodes without a stake policy
+	StakeAmount int
+	MaxFactor   string
+}
+
+//Discover get the stake of the node in the election, creating it on first sight
+//as the stake policy says. ErrSkipped is returned when the policy skips the election.
+func (m *Machine) Discover(wallet database.Wallet, node database.Node, electionID int64) (database.Stake, error) {
+	stake, err := m.Store.GetStake(wallet.ID, node.ID, electionID)
+	if err == nil {
+		return stake, nil
+	}
+	if err != sql.ErrNoRows {
+		return database.Stake{}, err
+	}
+	// stakes of other nodes count against the wallet balance, so plan one at a time
+	defer m.lock(fmt.Sprintf("plan/%d", wallet.ID))()
+	amount, maxFactor, err := m.planStake(wallet, node, electionID)
+	if err != nil {
+		return database.Stake{}, err
+	}
+	stake = database.Stake{
+		WalletID:    wallet.ID,
+		NodeID:      node.ID,
+		ElectionID:  electionID,
+		State:       StateDiscovered,
+		StakeAmount: amount,
+		MaxFactor:   maxFactor,
+	}
+	stake.ID, err = m.Store.AddStake(stake)
+	if err != nil {
+		return database.Stake{}, err
+	}
+	log.Println("Discovered election", electionID, "for node", node.HostPort)
+	return stake, nil
+}
+
+//WalletPass network state of a wallet shared by its nodes during one pass of the bot
+type WalletPass struct {
+	Wallet database.Wallet
+	t      tick
+	nodes  []int
+}
+
+//BeginWallet start a pass over the wallet's nodes
+func (m *Machine) BeginWallet(wallet database.Wallet, activeElectionID int64) (*WalletPass, error) {
+	credit, err := m.Credit(wallet)
+	if err != nil {
+		return nil, err
+	}
+	past, err := m.pastElections()
+	if err != nil {
+		return nil, err
+	}
+	if err = m.settleRecoveries(wallet, credit, past); err != nil {
+		log.Println("Failed to settle recoveries of wallet", wallet.Addr, err)
+	}
+	stakes, err := m.Store.GetStakes(wallet.ID, OpenStates...)
+	if err != nil {
+		return nil, err
+	}
+	p := &WalletPass{
+		Wallet: wallet,
+		t: tick{
+			now:              time.Now().Unix(),
+			activeElectionID: activeElectionID,
+			credit:           credit,
+			past:             past,
+		},
+	}
+	seen := make(map[int]bool)
+	for _, stake := range stakes {
+		if !seen[stake.NodeID] {
+			seen[stake.NodeID] = true
+			p.nodes = append(p.nodes, stake.NodeID)
+		}
+	}
+	return p, nil
+}
+
+//NodeIDs nodes with unfinished stakes when the pass began, disabled nodes included
+func (p *WalletPass) NodeIDs() []int {
+	return p.nodes
+}
+
+//AdvanceNode move every unfinished stake of the node as far as the network allows.
+//Nodes of one wallet can be advanced concurrently, their sends are serialized.
+func (m *Machine) AdvanceNode(p *WalletPass, nodeID int) error {
+	stakes, err := m.Store.GetStakes(p.Wallet.ID, OpenStates...)
+	if err != nil {
+		return err
+	}
+	for _, stake := range stakes {
+		if stake.NodeID != nodeID {
+			continue
+		}
+		if err := m.advance(p.Wallet, stake, p.t); err != nil {
+			log.Printf("Stake of node %d in election %d stuck in %s: %v", stake.NodeID, stake.ElectionID, stake.State, err)
+			// other stakes of an unreachable node wait for the next pass
+			var ue validator.UnreachableError
+			if errors.As(err, &ue) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+//EndWallet finish the pass once all nodes are advanced, recovering the credit if there is one
+func (m *Machine) EndWallet(p *WalletPass) error {
+	if p.t.credit > 0 {
+		return m.recover(p.Wallet, p.t.credit)
+	}
+	return nil
+}
+
+//lock take the named lock, the returned func releases it
+func (m *Machine) lock(name string) func() {
+	mu, _ := m.locks.LoadOrStore(name, &sync.Mutex{})
+	mu.(*sync.Mutex).Lock()
+	return mu.(*sync.Mutex).Unlock
+}
+
+//Credit stake and bonuses the elector is ready to return to the wallet
+func (m *Machine) Credit(wallet database.Wallet) (int64, error) {
+	unpackedAddress, err := m.Ton.UnpackAccountAddress(wallet.Addr)
+	if err != nil {
+		return 0, fmt.Errorf("UnpackAccountAddress failed: %w", err)
+	}
+	addr, err := utils.PubKeyToHex(unpackedAddress.Addr)
+	if err != nil {
+		return 0, err
+	}
+	credit, err := m.Ton.CheckReward(addr, m.ElectorAddr)
+	if err != nil {
+		return 0, fmt.Errorf("CheckReward failed: %w", err)
+	}
+	metrics.PendingReward.WithLabelValues(wallet.Addr).Set(metrics.Grams(credit))
+	return credit, nil
+}
+
+//tick network state shared by all stakes of a wallet during one pass
+type tick struct {
+	now              int64
+	activeElectionID int64
+	credit           int64
+	//past elections the elector still holds stakes of
+	past map[int64]liteclient.PastElection
+}
+
+//advance run steps until the stake has to wait for the network
+func (m *Machine) advance(wallet database.Wallet, stake database.Stake, t tick) error {
+	node, err := m.Store.GetNode(stake.NodeID)
+	if err != nil {
+		return err
+	}
+	for {
+		next, err := m.step(wallet, node, stake, t)
+		if err != nil {
+			stake.Error = err.Error()
+			if saveErr := m.Store.UpdateStake(stake); saveErr != nil {
+				log.Println("Failed to save stake:", saveErr)
+			}
+			return err
+		}
+		if next.State == stake.State {
+			return nil
+		}
+		next.Error = ""
+		if err = m.Store.UpdateStake(next); err != nil {
+			return err
+		}
+		log.Printf("Stake of node %d in election %d: %s -> %s", stake.NodeID, stake.ElectionID, stake.State, next.State)
+		stake = next
+	}
+}
+
+//step do the work of the current state and return the stake in its next state
+func (m *Machine) step(wallet database.Wallet, node database.Node, stake database.Stake, t tick) (database.Stake, error) {
+	active := stake.ElectionID == t.activeElectionID
+	switch stake.State {
+	case StateDiscovered:
+		if !active {
+			return m.fail(stake, "election closed before keys were created")
+		}
+		if err := m.createKeys(node, stake.ElectionID); err != nil {
+			return stake, err
+		}
+		stake.State = StateKeysCreated
+	case StateKeysCreated:
+		if !active {
+			return m.fail(stake, "election closed before request was signed")
+		}
+		signature, err := m.signRequest(wallet, node, stake)
+		if err != nil {
+			return stake, err
+		}
+		stake.Signature = signature
+		stake.State = StateRequestSigned
+	case StateRequestSigned:
+		if !active {
+			return m.fail(stake, "election closed before stake was sent")
+		}
+		// a crash right after sending leaves us here, so look at the elector and the journal first
+		amount, err := m.participatesIn(node, stake.ElectionID)
+		if err != nil {
+			return stake, err
+		}
+		sent, err := m.Store.GetOpenMessages(wallet.ID, node.ID, stake.ElectionID, PurposeStake)
+		if err != nil {
+			return stake, err
+		}
+		if amount == 0 && len(sent) > 0 {
+			log.Printf("Stake of node %d in election %d is in the journal as message %d, waiting for the elector", node.ID, stake.ElectionID, sent[0].ID)
+		}
+		if amount == 0 && len(sent) == 0 {
+			if err = m.sendStake(wallet, node, stake); err != nil {
+				return stake, err
+			}
+		}
+		stake.State = StateStakeSent
+	case StateStakeSent:
+		if !active {
+			return m.fail(stake, "stake was not seen by the elector before elections closed")
+		}
+		amount, err := m.participatesIn(node, stake.ElectionID)
+		if err != nil {
+			return stake, err
+		}
+		if amount > 0 {
+			log.Println("Participating in election", stake.ElectionID, "from", node.HostPort, "with", utils.FormatGrams(amount), "stake")
+			stake.State = StateConfirmed
+		}
+	case StateConfirmed:
+		if !active {
+			stake.State = StateFrozen
+		}
+	case StateFrozen:
+		// the elector drops the stake from the election's frozen list once it is credited back
+		frozen, err := m.stillFrozen(wallet, stake, t.past)
+		if err != nil {
+			return stake, err
+		}
+		if !frozen {
+			stake.State = StateRecoverable
+		}
+	}
+	return stake, nil
+}
+
+//pastElections elections whose stakes the elector still holds, by election id
+func (m *Machine) pastElections() (map[int64]liteclient.PastElection, error) {
+	elections, err := m.Lite.GetPastElections(m.ElectorAddr)
+	if err != nil {
+		return nil, err
+	}
+	past := make(map[int64]liteclient.PastElection, len(elections))
+	for _, e := range elections {
+		past[e.ElectionID] = e
+	}
+	return past, nil
+}
+
+//stillFrozen the stake is in the frozen list of its election
+func (m *Machine) stillFrozen(wallet database.Wallet, stake database.Stake, past map[int64]liteclient.PastElection) (bool, error) {
+	_, frozen, err := m.frozenEntry(wallet, stake, past)
+	return frozen, err
+}
+
+//frozenEntry the stake's entry in the frozen list of its election, found by the node's key
+//or, when the key is gone, by the wallet address
+func (m *Machine) frozenEntry(wallet database.Wallet, stake database.Stake, past map[int64]liteclient.PastElection) (liteclient.FrozenStake, bool, error) {
+	election, ok := past[stake.ElectionID]
+	if !ok {
+		return liteclient.FrozenStake{}, false, nil
+	}
+	pubKey, err := m.Store.GetKey("pubkey", stake.NodeID, stake.ElectionID)
+	if err == nil {
+		key, err := utils.PubKeyToHex(pubKey.Key)
+		if err != nil {
+			return liteclient.FrozenStake{}, false, err
+		}
+		frozen, ok := election.Frozen[key]
+		return frozen, ok, nil
+	}
+	if err != sql.ErrNoRows {
+		return liteclient.FrozenStake{}, false, err
+	}
+	addr, err := message.ParseAddress(wallet.Addr)
+	if err != nil {
+		return liteclient.FrozenStake{}, false, err
+	}
+	for _, frozen := range election.Frozen {
+		if bytes.Equal(frozen.WalletAddr, addr.Hash) {
+			return frozen, true, nil
+		}
+	}
+	return liteclient.FrozenStake{}, false, nil
+}
+
+func (m *Machine) fail(stake database.Stake, reason string) (database.Stake, error) {
+	kind := notify.EventStakeFailed
+	if stake.State == StateStakeSent {
+		kind = notify.EventStakeRejected
+	}
+	stake.State = StateFailed
+	stake.Error = reason
+	log.Printf("Stake of node %d in election %d failed: %s", stake.NodeID, stake.ElectionID, reason)
+	m.notify(notify.NewEvent(kind, fmt.Sprintf("node %d election %d", stake.NodeID, stake.ElectionID), "Stake of %d failed: %s", stake.StakeAmount, reason))
+	return stake, nil
+}
+
+//notify hand the event to the notifier if there is one
+func (m *Machine) notify(e notify.Event) {
+	if m.Notifier != nil {
+		m.Notifier.Notify(e)
+	}
+}
+
+//createKeys create validator and adnl keys on the node, keys already stored and not removed are reused
+func (m *Machine) createKeys(node database.Node, electionID int64) error {
+	expireAt := m.Periods.KeyExpireAt(electionID)
+	validatorKey, err := m.Store.GetKey("key", node.ID, electionID)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if validatorKey.Key == "" || validatorKey.DeletedAt != "" {
+		validatorKey, err = m.Validator.ValidatorCreateNewKey(node, electionID)
+		if err != nil {
+			return fmt.Errorf("validatorCreateNewKey failed: %w", err)
+		}
+		validatorKey.ExpireAt = expireAt
+		log.Println("Created new key:", validatorKey.Key)
+		if err = m.Validator.ValidatorAddPermKey(node, validatorKey.Key, electionID, expireAt); err != nil {
+			return fmt.Errorf("failed to add permKey %s: %w", validatorKey.Key, err)
+		}
+		log.Println("Added permKey", validatorKey.Key, electionID)
+		if err = m.Validator.ValidatorAddTempKey(node, validatorKey.Key, validatorKey.Key, expireAt); err != nil {
+			return fmt.Errorf("failed to add tempKey %s: %w", validatorKey.Key, err)
+		}
+		log.Println("Added tempKey", validatorKey.Key, electionID)
+		// stored only once the node knows the key, so a retry starts from scratch
+		if _, err = m.Store.AddKey(validatorKey); err != nil {
+			return fmt.Errorf("failed to save key to db: %w", err)
+		}
+	}
+
+	pubKey, err := m.Store.GetKey("pubkey", node.ID, electionID)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if pubKey.Key == "" || pubKey.DeletedAt != "" {
+		pubKey, err = m.Validator.ValidatorGetPublicKey(node, validatorKey.Key, electionID)
+		if err != nil {
+			return fmt.Errorf("validatorGetPublicKey failed: %w", err)
+		}
+		pubKey.ExpireAt = expireAt
+		if _, err = m.Store.AddKey(pubKey); err != nil {
+			return fmt.Errorf("failed to save pubkey to db: %w", err)
+		}
+	}
+
+	adnlKey, err := m.Store.GetKey("adnlkey", node.ID, electionID)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if adnlKey.Key == "" || adnlKey.DeletedAt != "" {
+		adnlKey, err = m.Validator.ValidatorCreateNewKey(node, electionID)
+		if err != nil {
+			return fmt.Errorf("adnl validatorCreateNewKey failed: %w", err)
+		}
+		adnlKey.Type = "adnlkey"
+		adnlKey.ExpireAt = expireAt
+		if err = m.Validator.ValidatorAddAdnl(node, adnlKey.Key, 0); err != nil {
+			return fmt.Errorf("failed to add ADNL %s: %w", adnlKey.Key, err)
+		}
+		log.Println("Added ADNL for key hash:", adnlKey.Key)
+		if err = m.Validator.ValidatorAddValidatorAddr(node, validatorKey.Key, adnlKey.Key, expireAt); err != nil {
+			return fmt.Errorf("failed to add validator address %s: %w", adnlKey.Key, err)
+		}
+		log.Println("Added validator addres for key hash:", validatorKey.Key, adnlKey.Key)
+		if _, err = m.Store.AddKey(adnlKey); err != nil {
+			return fmt.Errorf("failed to save adnlkey to db: %w", err)
+		}
+	}
+	return nil
+}
+
+//signRequest build the election request and sign it with the validator key
+func (m *Machine) signRequest(wallet database.Wallet, node database.Node, stake database.Stake) (string, error) {
+	validatorKey, err := m.Store.GetKey("key", node.ID, stake.ElectionID)
+	if err != nil {
+		return "", err
+	}
+	adnlKey, err := m.Store.GetKey("adnlkey", node.ID, stake.ElectionID)
+	if err != nil {
+		return "", err
+	}
+	req, err := m.electRequest(wallet, stake, adnlKey.Key)
+	if err != nil {
+		return "", err
+	}
+	signature, err := m.Validator.ValidatorSign(node, validatorKey.Key, hex.EncodeToString(req))
+	if err != nil {
+		return "", fmt.Errorf("validatorSign failed: %w", err)
+	}
+	return signature, nil
+}
+
+//sendStake send the signed request to the elector and record the participation
+func (m *Machine) sendStake(wallet database.Wallet, node database.Node, stake database.Stake) error {
+	pubKey, err := m.Store.GetKey("pubkey", node.ID, stake.ElectionID)
+	if err != nil {
+		return err
+	}
+	adnlKey, err := m.Store.GetKey("adnlkey", node.ID, stake.ElectionID)
+	if err != nil {
+		return err
+	}
+	req, err := m.electRequest(wallet, stake, adnlKey.Key)
+	if err != nil {
+		return err
+	}
+	pub, err := base64.StdEncoding.DecodeString(pubKey.Key)
+	if err != nil {
+		return utils.ParseError{What: "public key", Value: pubKey.Key, Err: err}
+	}
+	validatorPub, err := adnl.ParsePublicKey(pub)
+	if err != nil {
+		return err
+	}
+	signature, err := base64.StdEncoding.DecodeString(stake.Signature)
+	if err != nil {
+		return utils.ParseError{What: "signature", Value: stake.Signature, Err: err}
+	}
+	queryID := uint64(time.Now().Unix())
+	body, err := message.NewStake(req, validatorPub, signature, queryID)
+	if err != nil {
+		return err
+	}
+	msg := database.Message{Purpose: PurposeStake, Amount: stake.StakeAmount * 1e9, ElectionID: stake.ElectionID, NodeID: node.ID, QueryID: queryID}
+	if _, err = m.sender(wallet).Send(msg, body); err != nil {
+		return err
+	}
+	if err = m.Store.AddEarning(database.Earning{WalletID: wallet.ID, ElectionID: stake.ElectionID, StakeSent: msg.Amount}); err != nil {
+		log.Println("Failed to add stake to earnings:", err)
+	}
+	log.Println("Sent stake of", stake.StakeAmount, "to election", stake.ElectionID, "from", node.HostPort)
+	m.notify(notify.NewEvent(notify.EventStakeSent, fmt.Sprintf("%s election %d", node.HostPort, stake.ElectionID), "Sent stake of %d with max factor %s from %s", stake.StakeAmount, stake.MaxFactor, wallet.Addr))
+	participate := database.Participate{
+		NodeID:      node.ID,
+		ElectionID:  stake.ElectionID,
+		StakeAmount: stake.StakeAmount,
+		MaxFactor:   stake.MaxFactor,
+	}
+	if _, err = m.Store.AddParticipate(participate); err != nil {
+		log.Println("Failed to add participate record to DB:", err)
+	}
+	return nil
+}
+
+//electRequest election request of the stake for the validator key to sign
+func (m *Machine) electRequest(wallet database.Wallet, stake database.Stake, adnlKey string) ([]byte, error) {
+	walletAddr, err := message.ParseAddress(wallet.Addr)
+	if err != nil {
+		return nil, err
+	}
+	maxFactor, err := message.ParseMaxFactor(stake.MaxFactor)
+	if err != nil {
+		return nil, err
+	}
+	adnlAddr, err := hex.DecodeString(adnlKey)
+	if err != nil {
+		return nil, utils.ParseError{What: "adnl key", Value: adnlKey, Err: err}
+	}
+	return message.ElectRequest(walletAddr, stake.ElectionID, maxFactor, adnlAddr)
+}
+
+//participatesIn stake the elector holds for the node's key in the election
+func (m *Machine) participatesIn(node database.Node, electionID int64) (int64, error) {
+	pubKey, err := m.Store.GetKey("pubkey", node.ID, electionID)
+	if err != nil {
+		return 0, err
+	}
+	key, err := utils.PubKeyToHex(pubKey.Key)
+	if err != nil {
+		return 0, err
+	}
+	amount, err := m.Ton.CheckParticipatesIn(key, m.ElectorAddr)
+	if err != nil {
+		return 0, fmt.Errorf("CheckParticipatesIn failed: %w", err)
+	}
+	return amount, nil
+}
+
+//recoverAnswerTimeout how long a recover_stake request waits for the elector's answer before another one is sent
+const recoverAnswerTimeout = time.Hour
+
+//recover ask the elector to return the wallet's credit unless a request is still waiting for its answer.
+//The stakes stay recoverable until settleRecoveries sees the credit paid.
+func (m *Machine) recover(wallet database.Wallet, credit int64) error {
+	pending, err := m.Store.GetRecoveries(wallet.ID, RecoveryPending)
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		return nil
+	}
+	state, err := m.Ton.GetAccountState(*tonlib.NewAccountAddress(wallet.Addr))
+	if err != nil {
+		return fmt.Errorf("getAccountState failed: %w", err)
+	}
+	log.Printf("Sending request to recover %12s GRAMs\n", utils.FormatGrams(credit))
+	queryID := uint64(time.Now().Unix())
+	body, err := message.RecoverStake(queryID)
+	if err != nil {
+		return err
+	}
+	msg, err := m.recoverMessage(wallet)
+	if err != nil {
+		return err
+	}
+	msg.QueryID = queryID
+	if _, err = m.sender(wallet).Send(msg, body); err != nil {
+		return fmt.Errorf("RecoverStake failed: %w", err)
+	}
+	_, err = m.Store.AddRecovery(database.Recovery{
+		WalletID: wallet.ID,
+		QueryID:  queryID,
+		Result:   RecoveryPending,
+		Credit:   credit,
+		SentLt:   int64(state.LastTransactionId.Lt),
+	})
+	return err
+}
+
+//settleRecoveries look for the elector's answers to the wallet's pending recover_stake requests.
+//Stakes are closed once the credit is paid, as seen in the answer or in a drop of the credit.
+func (m *Machine) settleRecoveries(wallet database.Wallet, credit int64, past map[int64]liteclient.PastElection) error {
+	pending, err := m.Store.GetRecoveries(wallet.ID, RecoveryPending)
+	if err != nil || len(pending) == 0 {
+		return err
+	}
+	addr := *tonlib.NewAccountAddress(wallet.Addr)
+	for _, r := range pending {
+		since := tonlib.InternalTransactionId{Lt: tonlib.JSONInt64(r.SentLt)}
+		found, err := m.findElectorAnswer(addr, since, &r)
+		if err != nil {
+			return err
+		}
+		switch {
+		case found:
+		case credit < r.Credit:
+			// the answer was not seen but the elector no longer owes the credit
+			r.Result = RecoveryCredited
+		case time.Now().Unix() >= r.SentAt+int64(recoverAnswerTimeout/time.Second):
+			r.Result = RecoveryTimeout
+		default:
+			continue
+		}
+		if err = m.Store.SetRecoveryResult(r); err != nil {
+			return err
+		}
+		log.Printf("Recover stake request %d of wallet %s: %s", r.QueryID, wallet.Addr, r.Result)
+		if r.Result != RecoveryCredited {
+			continue
+		}
+		m.notify(notify.NewEvent(notify.EventRewardRecovered, wallet.Addr, "Recovered %s GRAMs from the elector", utils.FormatGrams(r.Credit)))
+		if err = m.closeUnfrozen(wallet, r.Credit, past); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//recoverMessage journal entry of a recover_stake request, its fees go to the oldest recoverable election
+func (m *Machine) recoverMessage(wallet database.Wallet) (database.Message, error) {
+	stakes, err := m.Store.GetStakes(wallet.ID, StateRecoverable)
+	if err != nil {
+		return database.Message{}, err
+	}
+	// the elector sends the credit back together with what is left of the gram
+	msg := database.Message{Purpose: PurposeRecover, Amount: 1e9}
+	if len(stakes) > 0 {
+		msg.ElectionID = stakes[0].ElectionID
+	}
+	return msg, nil
+}
+
+//closeUnfrozen close the wallet's frozen and recoverable stakes the elector no longer holds with the recovered credit.
+//Stakes that stay frozen claim the excess over their effective stake, which the elector returned at conduct.
+func (m *Machine) closeUnfrozen(wallet database.Wallet, credit int64, past map[int64]liteclient.PastElection) error {
+	stakes, err := m.Store.GetStakes(wallet.ID, StateFrozen)
+	if err != nil {
+		return err
+	}
+	var frozen []database.Stake
+	effective := make(map[int64]int64)
+	for _, stake := range stakes {
+		entry, ok, err := m.frozenEntry(wallet, stake, past)
+		if err != nil {
+			return err
+		}
+		if ok {
+			frozen = append(frozen, stake)
+			effective[stake.ID] = entry.Stake
+			continue
+		}
+		stake.State = StateRecoverable
+		stake.Error = ""
+		if err = m.Store.UpdateStake(stake); err != nil {
+			return err
+		}
+		log.Printf("Stake of node %d in election %d: %s -> %s", stake.NodeID, stake.ElectionID, StateFrozen, StateRecoverable)
+	}
+	return m.closeRecoverable(wallet, credit, frozen, effective)
+}
+
+//closeRecoverable move recoverable stakes of the wallet to recovered and book the credit they were recovered
+//with in the ledger, see bookCredit. effective holds the effective stakes of the frozen stakes by stake id.
+func (m *Machine) closeRecoverable(wallet database.Wallet, credit int64, frozen []database.Stake, effective map[int64]int64) error {
+	closing, err := m.Store.GetStakes(wallet.ID, StateRecoverable)
+	if err != nil {
+		return err
+	}
+	claims, err := m.claims(wallet, frozen, closing, effective)
+	if err != nil {
+		return err
+	}
+	earnings, unattributed, short := bookCredit(credit, claims)
+	if short {
+		log.Printf("Credit %s of wallet %s is below the stakes it returns, booked as returned stakes without bonuses", utils.FormatGrams(credit), wallet.Addr)
+	}
+	if unattributed != 0 {
+		log.Printf("%s of the credit of wallet %s matches no election, booked to the wallet", utils.FormatGrams(unattributed), wallet.Addr)
+		earnings = append(earnings, database.Earning{ElectionID: WalletLedgerID, Bonus: unattributed})
+	}
+	for _, e := range earnings {
+		e.WalletID = wallet.ID
+		if err = m.Store.AddEarning(e); err != nil {
+			log.Println("Failed to add recovered stake to earnings:", err)
+		}
+	}
+	for _, stake := range closing {
+		stake.State = StateRecovered
+		if err = m.Store.UpdateStake(stake); err != nil {
+			return err
+		}
+		log.Printf("Stake of node %d in election %d: %s -> %s", stake.NodeID, stake.ElectionID, StateRecoverable, StateRecovered)
+	}
+	return nil
+}
+
+//muldiv a*b/c without overflowing int64 in between
+func muldiv(a, b, c int64) int64 {
+	if c == 0 {
+		return 0
+	}
+	r := new(big.Int).Mul(big.NewInt(a), big.NewInt(b))
+	return r.Quo(r, big.NewInt(c)).Int64()
+}
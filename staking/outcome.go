@@ -0,0 +1,178 @@
+package staking
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/mercuryoio/ton-validator/database"
+	"github.com/mercuryoio/ton-validator/message"
+	"github.com/mercuryoio/ton-validator/utils"
+	"github.com/mercuryoio/ton-validator/wrappers/liteclient"
+	tonlib "github.com/mercuryoio/tonlib-go/v2"
+)
+
+//Outcomes of a participation once the election is over
+const (
+	//OutcomeElected the key got into the validator set
+	OutcomeElected = "elected"
+	//OutcomeNotElected the validator set of the election is in force without the key
+	OutcomeNotElected = "not_elected"
+	//OutcomeReturned the elector did not keep the stake: it answered with return_stake, the stake bounced
+	//or the election was conducted without freezing it and credited it back
+	OutcomeReturned = "returned"
+	//OutcomeUnknown the election was forgotten by the elector before the outcome was checked
+	OutcomeUnknown = "unknown"
+)
+
+//VerifyOutcomes check participations in finished elections against the current validator set
+//(config param 34) and the elector's past elections, and record their outcome
+func (m *Machine) VerifyOutcomes(activeElectionID int64) (int, error) {
+	if m.Lite == nil {
+		return 0, fmt.Errorf("no lite server connection to verify outcomes")
+	}
+	participates, err := m.Store.GetUncheckedParticipates()
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now().Unix()
+	var due []database.Participate
+	for _, p := range participates {
+		// the set elected in an election starts validating at the election id
+		if p.ElectionID != activeElectionID && now >= p.ElectionID {
+			due = append(due, p)
+		}
+	}
+	if len(due) == 0 {
+		return 0, nil
+	}
+
+	set, err := m.Lite.GetValidatorSet()
+	if err != nil {
+		return 0, err
+	}
+	past, err := m.pastElections()
+	if err != nil {
+		return 0, err
+	}
+
+	checked := 0
+	for _, p := range due {
+		if err = m.outcome(&p, set, past, now); err != nil {
+			log.Printf("Failed to check outcome of node %d in election %d: %v", p.NodeID, p.ElectionID, err)
+			continue
+		}
+		if p.Outcome == "" {
+			continue
+		}
+		if err = m.Store.SetParticipateOutcome(p); err != nil {
+			return checked, err
+		}
+		log.Printf("Node %d in election %d: %s, effective stake %s, weight %d", p.NodeID, p.ElectionID, p.Outcome, utils.FormatGrams(p.EffectiveStake), p.Weight)
+		checked++
+	}
+	return checked, nil
+}
+
+//outcome fill in the outcome of the participation, it stays empty while the election is not over.
+//Without the node's key the stake is found by the wallet address in the frozen list.
+func (m *Machine) outcome(p *database.Participate, set liteclient.ValidatorSet, past map[int64]liteclient.PastElection, now int64) error {
+	node, err := m.Store.GetNode(p.NodeID)
+	if err != nil {
+		return err
+	}
+	wallet, err := m.Store.GetWallet(node.WalletID)
+	if err != nil {
+		return err
+	}
+	stake := database.Stake{WalletID: wallet.ID, NodeID: p.NodeID, ElectionID: p.ElectionID}
+	frozen, elected, err := m.frozenEntry(wallet, stake, past)
+	if err != nil {
+		return err
+	}
+	if elected {
+		p.Outcome = OutcomeElected
+		p.EffectiveStake = frozen.Stake
+		p.Weight = int64(frozen.Weight)
+		if frozen.Stake < p.StakeAmount*1e9 {
+			log.Printf("Node %d in election %d: stake trimmed to %s, the rest is credited back", p.NodeID, p.ElectionID, utils.FormatGrams(frozen.Stake))
+		}
+		return nil
+	}
+
+	returned, err := m.stakeReturned(wallet, p)
+	if err != nil {
+		return err
+	}
+	if _, held := past[p.ElectionID]; held || returned {
+		// conduct credits the stakes it does not freeze back to the wallet right away
+		p.Outcome = OutcomeReturned
+		return nil
+	}
+	key, err := m.participantKey(p)
+	if err != nil {
+		return err
+	}
+	if set.UtimeSince == p.ElectionID && key != nil {
+		p.Outcome = OutcomeNotElected
+		for _, v := range set.Validators {
+			if bytes.Equal(v.PublicKey, key) {
+				p.Outcome = OutcomeElected
+				p.Weight = int64(v.Weight)
+			}
+		}
+		return nil
+	}
+	if now < p.ElectionID+m.Periods.ValidatorsElectedFor+m.Periods.StakeHeldFor {
+		return nil
+	}
+	p.Outcome = OutcomeUnknown
+	// a sent stake fails when the elector never lists it
+	if stake, err = m.Store.GetStake(wallet.ID, p.NodeID, p.ElectionID); err == nil && stake.State == StateFailed {
+		p.Outcome = OutcomeReturned
+	}
+	return nil
+}
+
+//participantKey public key of the node in the election, nil when it is gone
+func (m *Machine) participantKey(p *database.Participate) ([]byte, error) {
+	pubKey, err := m.Store.GetKey("pubkey", p.NodeID, p.ElectionID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	key, err := utils.PubKeyToHex(pubKey.Key)
+	if err != nil {
+		return nil, err
+	}
+	return hex.DecodeString(key)
+}
+
+//stakeReturned the elector answered the node's stake in the election with return_stake, or the stake bounced.
+//Only stakes whose wallet transaction is reconciled are looked up, it tells where the answer can be.
+func (m *Machine) stakeReturned(wallet database.Wallet, p *database.Participate) (bool, error) {
+	sent, err := m.Store.GetOpenMessages(wallet.ID, p.NodeID, p.ElectionID, PurposeStake)
+	if err != nil {
+		return false, err
+	}
+	addr := *tonlib.NewAccountAddress(wallet.Addr)
+	for _, msg := range sent {
+		if msg.QueryID == 0 || msg.TxLt == 0 {
+			continue
+		}
+		since := tonlib.InternalTransactionId{Lt: tonlib.JSONInt64(msg.TxLt)}
+		answer, _, found, err := m.electorAnswer(addr, since, msg.QueryID, message.OpNewStakeOk, message.OpReturnStake)
+		if err != nil {
+			return false, err
+		}
+		if found && (answer.Bounced || answer.Op == message.OpReturnStake) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
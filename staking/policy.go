@@ -0,0 +1,158 @@
+package staking
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/mercuryoio/ton-validator/database"
+	"github.com/mercuryoio/ton-validator/message"
+	"github.com/mercuryoio/ton-validator/planner"
+	"github.com/mercuryoio/ton-validator/utils"
+)
+
+//Stake policy modes
+const (
+	PolicyFixed         = "fixed"
+	PolicyPercent       = "percent"
+	PolicyAllButReserve = "all-but-reserve"
+)
+
+//feeReserve grams always left on the wallet to pay for sending the stake
+const feeReserve = 1
+
+//ErrSkipped the stake policy tells to sit this election out
+var ErrSkipped = errors.New("election skipped by stake policy")
+
+//CheckPolicy validate policy before it is saved
+func CheckPolicy(p database.StakePolicy) error {
+	if (p.WalletID == 0) == (p.NodeID == 0) {
+		return fmt.Errorf("policy must be set either for a wallet or for a node")
+	}
+	switch p.Mode {
+	case PolicyFixed:
+		if p.Amount <= 0 {
+			return fmt.Errorf("fixed policy needs a positive amount")
+		}
+	case PolicyPercent:
+		if p.Percent <= 0 || p.Percent > 100 {
+			return fmt.Errorf("percent must be in range (0, 100]")
+		}
+	case PolicyAllButReserve:
+	default:
+		return fmt.Errorf("unknown policy mode %q, use %s, %s or %s", p.Mode, PolicyFixed, PolicyPercent, PolicyAllButReserve)
+	}
+	if p.MinReserve < 0 {
+		return fmt.Errorf("min reserve can't be negative")
+	}
+	_, err := message.ParseMaxFactor(p.MaxFactor)
+	return err
+}
+
+//Policy policy of the node, falling back to the wallet's and then to the machine defaults
+func (m *Machine) Policy(wallet database.Wallet, node database.Node) (database.StakePolicy, error) {
+	p, err := m.Store.GetStakePolicy(0, node.ID)
+	if err != sql.ErrNoRows {
+		return p, err
+	}
+	p, err = m.Store.GetStakePolicy(wallet.ID, 0)
+	if err != sql.ErrNoRows {
+		return p, err
+	}
+	return database.StakePolicy{
+		WalletID:  wallet.ID,
+		Mode:      PolicyFixed,
+		Amount:    int64(m.StakeAmount),
+		MaxFactor: m.MaxFactor,
+	}, nil
+}
+
+//PolicyAmount grams to stake under the policy from balance nanograms when committed grams
+//are already promised to other stakes
+func PolicyAmount(p database.StakePolicy, balance, committed int64) (int64, error) {
+	available := balance/1e9 - committed - p.MinReserve - feeReserve
+	var amount int64
+	switch p.Mode {
+	case PolicyFixed:
+		amount = p.Amount
+	case PolicyPercent:
+		amount = int64(float64(balance/1e9) * p.Percent / 100)
+		if amount > available {
+			amount = available
+		}
+	case PolicyAllButReserve:
+		amount = available
+	default:
+		return 0, fmt.Errorf("unknown policy mode %q", p.Mode)
+	}
+	if amount <= 0 || amount > available {
+		return 0, fmt.Errorf("balance leaves %d grams above reserve, can't stake %d", available, amount)
+	}
+	return amount, nil
+}
+
+//planStake amount and max factor for a new stake of the node, ErrSkipped if the policy skips the election
+func (m *Machine) planStake(wallet database.Wallet, node database.Node, electionID int64) (int64, string, error) {
+	p, err := m.Policy(wallet, node)
+	if err != nil {
+		return 0, "", err
+	}
+	if p.SkippedElection == electionID {
+		return 0, "", ErrSkipped
+	}
+	if p.SkipNext {
+		p.SkipNext = false
+		p.SkippedElection = electionID
+		if err = m.Store.SetStakePolicy(p); err != nil {
+			return 0, "", err
+		}
+		log.Println("Skipping election", electionID, "for node", node.HostPort, "as the stake policy asks")
+		return 0, "", ErrSkipped
+	}
+
+	stakes, err := m.Store.GetStakes(wallet.ID, StateDiscovered, StateKeysCreated, StateRequestSigned)
+	if err != nil {
+		return 0, "", err
+	}
+	var committed int64
+	for _, stake := range stakes {
+		committed += stake.StakeAmount
+	}
+	amount, err := PolicyAmount(p, wallet.Balance, committed)
+	if err != nil {
+		return 0, "", err
+	}
+	amount = m.sizeStake(node, amount, p.MaxFactor)
+	if amount*1e9 < m.StakeConfig.MinStake {
+		return 0, "", fmt.Errorf("stake of %d grams is below the network minimum", amount)
+	}
+	return amount, p.MaxFactor, nil
+}
+
+//sizeStake grams out of amount the simulated election would keep, the rest stays on the wallet
+func (m *Machine) sizeStake(node database.Node, amount int64, maxFactor string) int64 {
+	if m.Lite == nil {
+		return amount
+	}
+	factor, err := message.ParseMaxFactor(maxFactor)
+	if err != nil {
+		return amount
+	}
+	pl, err := planner.Load(m.Lite, m.ElectorAddr, m.Limits, m.StakeConfig)
+	if err != nil {
+		log.Println("Stake planner unavailable for node", node.HostPort, err)
+		return amount
+	}
+	sized, plan := pl.Size(nil, amount*1e9, int64(factor))
+	switch {
+	case !plan.Elected && plan.MinWinning == 0:
+		log.Printf("No stake gets node %s elected with max factor %s yet, staking %d anyway", node.HostPort, maxFactor, amount)
+	case !plan.Elected:
+		log.Printf("Stake of %d for node %s is not elected yet, it takes %s", amount, node.HostPort, utils.FormatGrams(plan.MinWinning))
+	case sized < amount*1e9:
+		log.Printf("Stake of node %s trimmed from %d to %s, the elector would return the rest", node.HostPort, amount, utils.FormatGrams(sized))
+		return sized / 1e9
+	}
+	return amount
+}
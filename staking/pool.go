@@ -0,0 +1,35 @@
+package staking
+
+import "sync"
+
+//Pool runs jobs concurrently, at most size of them at a time
+type Pool struct {
+	sem chan struct{}
+	wg  sync.WaitGroup
+}
+
+//NewPool pool of size workers, at least one
+func NewPool(size int) *Pool {
+	if size < 1 {
+		size = 1
+	}
+	return &Pool{sem: make(chan struct{}, size)}
+}
+
+//Go run job as soon as a worker is free
+func (p *Pool) Go(job func()) {
+	p.wg.Add(1)
+	p.sem <- struct{}{}
+	go func() {
+		defer func() {
+			<-p.sem
+			p.wg.Done()
+		}()
+		job()
+	}()
+}
+
+//Wait wait for all started jobs
+func (p *Pool) Wait() {
+	p.wg.Wait()
+}
@@ -0,0 +1,77 @@
+package staking
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/mercuryoio/ton-validator/database"
+	tonlib "github.com/mercuryoio/tonlib-go/v2"
+)
+
+//reconcileWindow how far back messages and wallet transactions are matched
+const reconcileWindow = 24 * time.Hour
+
+//Reconcile link the wallet's recent messages to the wallet transactions that took them.
+//A message found on chain is confirmed even if the sender gave up waiting for it.
+func (m *Machine) Reconcile(wallet database.Wallet) (int, error) {
+	since := time.Now().Add(-reconcileWindow).Unix()
+	messages, err := m.Store.GetUnreconciledMessages(wallet.ID, since)
+	if err != nil {
+		return 0, err
+	}
+	if len(messages) == 0 {
+		return 0, nil
+	}
+	byBodyHash := make(map[string]database.Message, len(messages))
+	for _, msg := range messages {
+		byBodyHash[msg.BodyHash] = msg
+	}
+
+	addr := *tonlib.NewAccountAddress(wallet.Addr)
+	state, err := m.Ton.GetAccountState(addr)
+	if err != nil {
+		return 0, fmt.Errorf("getAccountState failed: %w", err)
+	}
+	matched := 0
+	next := state.LastTransactionId
+	for next.Lt > 0 && len(byBodyHash) > 0 {
+		txs, err := m.Ton.RawGetTransactions(addr, next)
+		if err != nil {
+			return matched, fmt.Errorf("RawGetTransactions failed: %w", err)
+		}
+		if len(txs.Transactions) == 0 {
+			break
+		}
+		for _, tx := range txs.Transactions {
+			if tx.Utime < since {
+				return matched, nil
+			}
+			// external messages come without a source
+			if tx.InMsg == nil || tx.InMsg.Source.AccountAddress != "" {
+				continue
+			}
+			bodyHash, err := base64.StdEncoding.DecodeString(tx.InMsg.BodyHash)
+			if err != nil {
+				continue
+			}
+			msg, ok := byBodyHash[hex.EncodeToString(bodyHash)]
+			if !ok {
+				continue
+			}
+			if err = m.Store.SetMessageTransaction(msg.ID, tx.Utime, int64(tx.TransactionId.Lt), tx.TransactionId.Hash, int64(tx.Fee)); err != nil {
+				return matched, err
+			}
+			if msg.ElectionID != 0 {
+				if err = m.Store.AddEarning(database.Earning{WalletID: wallet.ID, ElectionID: msg.ElectionID, Fees: int64(tx.Fee)}); err != nil {
+					return matched, err
+				}
+			}
+			delete(byBodyHash, msg.BodyHash)
+			matched++
+		}
+		next = txs.PreviousTransactionId
+	}
+	return matched, nil
+}
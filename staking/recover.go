@@ -0,0 +1,202 @@
+package staking
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/mercuryoio/ton-validator/cell"
+	"github.com/mercuryoio/ton-validator/database"
+	"github.com/mercuryoio/ton-validator/message"
+	"github.com/mercuryoio/ton-validator/notify"
+	"github.com/mercuryoio/ton-validator/utils"
+	tonlib "github.com/mercuryoio/tonlib-go/v2"
+)
+
+//Results of a recover_stake request
+const (
+	RecoveryPending  = "pending"
+	RecoveryCredited = "credited"
+	RecoveryRejected = "rejected"
+	RecoveryBounced  = "bounced"
+	RecoveryTimeout  = "timeout"
+)
+
+//recoverPollInterval how often wallet transactions are checked for the elector's answer
+const recoverPollInterval = 5 * time.Second
+
+//unsigned signs with a zero signature, the wallet rejects what it signs
+type unsigned struct{}
+
+func (unsigned) Sign(message.Address, *cell.Cell) ([]byte, error) {
+	return make([]byte, ed25519.SignatureSize), nil
+}
+
+//RecoverMessage wallet message asking the elector to return the credit, and its query id. The message
+//is only for showing, it carries a zero signature and can't be sent.
+func (m *Machine) RecoverMessage(wallet database.Wallet) (*cell.Cell, uint64, error) {
+	queryID := uint64(time.Now().Unix())
+	body, err := message.RecoverStake(queryID)
+	if err != nil {
+		return nil, 0, err
+	}
+	// the elector sends the credit back together with what is left of the gram
+	seqno, err := m.Ton.GetWalletSeqno(wallet.Addr)
+	if err != nil {
+		return nil, 0, fmt.Errorf("GetWalletSeqno failed: %w", err)
+	}
+	addr, err := message.ParseAddress(wallet.Addr)
+	if err != nil {
+		return nil, 0, err
+	}
+	elector, err := message.ParseAddress(m.ElectorAddr)
+	if err != nil {
+		return nil, 0, err
+	}
+	w, err := message.NewWallet(addr, wallet.Version, uint32(wallet.SubwalletID), unsigned{})
+	if err != nil {
+		return nil, 0, err
+	}
+	msg, err := w.Transfer(elector, 1e9, true, body, uint32(seqno))
+	if err != nil {
+		return nil, 0, err
+	}
+	return msg, queryID, nil
+}
+
+//RecoverAndWait ask the elector to return the wallet's credit and wait up to timeout for the answer.
+//The outcome is stored as a recovery record. Once the credit arrives every frozen or recoverable
+//stake whose election no longer lists it as frozen is closed. Without an answer in time the record
+//stays pending and the bot settles it when the answer comes.
+func (m *Machine) RecoverAndWait(wallet database.Wallet, timeout time.Duration) (database.Recovery, error) {
+	credit, err := m.Credit(wallet)
+	if err != nil {
+		return database.Recovery{}, err
+	}
+	addr := *tonlib.NewAccountAddress(wallet.Addr)
+	state, err := m.Ton.GetAccountState(addr)
+	if err != nil {
+		return database.Recovery{}, fmt.Errorf("getAccountState failed: %w", err)
+	}
+	queryID := uint64(time.Now().Unix())
+	body, err := message.RecoverStake(queryID)
+	if err != nil {
+		return database.Recovery{}, err
+	}
+	msg, err := m.recoverMessage(wallet)
+	if err != nil {
+		return database.Recovery{}, err
+	}
+	msg.QueryID = queryID
+	if _, err = m.sender(wallet).Send(msg, body); err != nil {
+		return database.Recovery{}, err
+	}
+
+	recovery := database.Recovery{
+		WalletID: wallet.ID,
+		QueryID:  queryID,
+		Result:   RecoveryPending,
+		Credit:   credit,
+		SentLt:   int64(state.LastTransactionId.Lt),
+	}
+	for deadline := time.Now().Add(timeout); time.Now().Before(deadline); {
+		time.Sleep(recoverPollInterval)
+		found, err := m.findElectorAnswer(addr, state.LastTransactionId, &recovery)
+		if err != nil {
+			log.Println("Failed to check wallet transactions:", err)
+			continue
+		}
+		if found {
+			break
+		}
+	}
+	if recovery.ID, err = m.Store.AddRecovery(recovery); err != nil {
+		return recovery, fmt.Errorf("failed to save recovery: %w", err)
+	}
+	if recovery.Result == RecoveryCredited {
+		m.notify(notify.NewEvent(notify.EventRewardRecovered, wallet.Addr, "Recovered %s GRAMs in transaction %s", utils.FormatGrams(recovery.Amount), recovery.TxHash))
+		past, err := m.pastElections()
+		if err != nil {
+			return recovery, err
+		}
+		if err = m.closeUnfrozen(wallet, credit, past); err != nil {
+			return recovery, err
+		}
+	}
+	return recovery, nil
+}
+
+//findElectorAnswer look through wallet transactions newer than since for the elector's answer to the recover_stake query
+func (m *Machine) findElectorAnswer(addr tonlib.AccountAddress, since tonlib.InternalTransactionId, recovery *database.Recovery) (bool, error) {
+	answer, tx, found, err := m.electorAnswer(addr, since, recovery.QueryID, message.OpRecoverStakeOk, message.OpRecoverStakeError)
+	if err != nil || !found {
+		return false, err
+	}
+	switch {
+	case answer.Bounced:
+		recovery.Result = RecoveryBounced
+	case answer.Op == message.OpRecoverStakeOk:
+		recovery.Result = RecoveryCredited
+	default:
+		recovery.Result = RecoveryRejected
+	}
+	recovery.Amount = int64(tx.InMsg.Value)
+	recovery.TxLt = int64(tx.TransactionId.Lt)
+	recovery.TxHash = tx.TransactionId.Hash
+	return true, nil
+}
+
+//electorAnswer look through wallet transactions newer than since for the elector's answer to the query
+//with one of ops or a bounce, and the transaction that brought it
+func (m *Machine) electorAnswer(addr tonlib.AccountAddress, since tonlib.InternalTransactionId, queryID uint64, ops ...uint32) (message.Answer, tonlib.RawTransaction, bool, error) {
+	elector, err := message.ParseAddress(m.ElectorAddr)
+	if err != nil {
+		return message.Answer{}, tonlib.RawTransaction{}, false, err
+	}
+	state, err := m.Ton.GetAccountState(addr)
+	if err != nil {
+		return message.Answer{}, tonlib.RawTransaction{}, false, fmt.Errorf("getAccountState failed: %w", err)
+	}
+	next := state.LastTransactionId
+	for next.Lt > since.Lt {
+		txs, err := m.Ton.RawGetTransactions(addr, next)
+		if err != nil {
+			return message.Answer{}, tonlib.RawTransaction{}, false, fmt.Errorf("RawGetTransactions failed: %w", err)
+		}
+		if len(txs.Transactions) == 0 {
+			break
+		}
+		for _, tx := range txs.Transactions {
+			if tx.TransactionId.Lt <= since.Lt {
+				return message.Answer{}, tonlib.RawTransaction{}, false, nil
+			}
+			if tx.InMsg == nil || tx.InMsg.MsgData.Body == "" {
+				continue
+			}
+			src, err := message.ParseAddress(tx.InMsg.Source.AccountAddress)
+			if err != nil || !src.Equal(elector) {
+				continue
+			}
+			body, err := base64.StdEncoding.DecodeString(tx.InMsg.MsgData.Body)
+			if err != nil {
+				continue
+			}
+			answer, err := message.ParseAnswer(body)
+			if err != nil || answer.QueryID != queryID {
+				continue
+			}
+			if answer.Bounced {
+				return answer, tx, true, nil
+			}
+			for _, op := range ops {
+				if answer.Op == op {
+					return answer, tx, true, nil
+				}
+			}
+		}
+		next = txs.PreviousTransactionId
+	}
+	return message.Answer{}, tonlib.RawTransaction{}, false, nil
+}
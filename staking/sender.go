@@ -0,0 +1,204 @@
+package staking
+
+import (
+	"encoding/hex"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/mercuryoio/ton-validator/cell"
+	"github.com/mercuryoio/ton-validator/database"
+	"github.com/mercuryoio/ton-validator/message"
+	"github.com/mercuryoio/ton-validator/signer"
+)
+
+//Purposes of outgoing messages
+const (
+	PurposeStake    = "stake"
+	PurposeRecover  = "recover"
+	PurposeTransfer = "transfer"
+)
+
+//States of outgoing messages
+const (
+	MessageSent      = "sent"
+	MessageConfirmed = "confirmed"
+	MessageFailed    = "failed"
+)
+
+//seqnoTimeout how long a sent message may take to reach the wallet, a bit over its validity
+const seqnoTimeout = 90 * time.Second
+
+//seqnoPollInterval how often the wallet seqno is checked after a send
+const seqnoPollInterval = 2 * time.Second
+
+//sendRetries how many times a message the wallet did not take is signed and sent again
+const sendRetries = 2
+
+//Sender sends the messages of one wallet one at a time. A message keeps its seqno
+//through retries, so at most one of its attempts can be taken by the wallet.
+type Sender struct {
+	m      *Machine
+	wallet database.Wallet
+
+	mu sync.Mutex
+	//next seqno after the last confirmed message, the wallet may be further ahead
+	next int64
+}
+
+//sender the sender of the wallet, shared by all its nodes
+func (m *Machine) sender(wallet database.Wallet) *Sender {
+	s, _ := m.senders.LoadOrStore(wallet.ID, &Sender{m: m, wallet: wallet})
+	return s.(*Sender)
+}
+
+//Send transfer msg.Amount nanograms with body to the elector and wait until the wallet takes it.
+//msg tells the purpose and election of the message, it is recorded before it is sent
+//and marked confirmed or failed.
+func (s *Sender) Send(msg database.Message, body *cell.Cell) (database.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seqno, err := s.reserve()
+	if err != nil {
+		return msg, err
+	}
+	msg.WalletID = s.wallet.ID
+	msg.Seqno = seqno
+	msg.Destination = s.m.ElectorAddr
+	msg.State = MessageSent
+	for msg.Attempts <= sendRetries {
+		// expired attempts are signed again with a fresh valid_until
+		ext, err := s.m.walletMessage(s.wallet, msg.Amount, body, seqno)
+		if err != nil {
+			return msg, s.failed(msg, err)
+		}
+		extBody, err := message.ExternalBody(ext)
+		if err != nil {
+			return msg, s.failed(msg, err)
+		}
+		msg.Hash = hex.EncodeToString(ext.Hash())
+		msg.BodyHash = hex.EncodeToString(extBody.Hash())
+		msg.Attempts++
+		if msg.ID == 0 {
+			msg.ID, err = s.m.Store.AddMessage(msg)
+		} else {
+			err = s.m.Store.UpdateMessage(msg)
+		}
+		if err != nil {
+			return msg, fmt.Errorf("failed to record message: %w", err)
+		}
+
+		if _, err = s.m.Ton.RawSendMessage(ext.ToBOC()); err != nil {
+			// the wallet may have taken an earlier attempt, the seqno tells
+			log.Printf("Wallet %s: RawSendMessage of seqno %d failed: %v", s.wallet.Addr, seqno, err)
+			msg.Error = err.Error()
+		} else if err = s.m.Store.SetMessageSent(msg.ID); err != nil {
+			log.Println("Failed to update message record:", err)
+		}
+		if s.confirmed(seqno) {
+			s.next = seqno + 1
+			msg.State = MessageConfirmed
+			msg.Error = ""
+			if err = s.m.Store.ConfirmMessage(msg.ID); err != nil {
+				log.Println("Failed to update message record:", err)
+			}
+			return msg, nil
+		}
+		log.Printf("Wallet %s did not take message with seqno %d, attempt %d", s.wallet.Addr, seqno, msg.Attempts)
+	}
+	return msg, s.failed(msg, fmt.Errorf("wallet %s did not take message with seqno %d in %d attempts", s.wallet.Addr, seqno, msg.Attempts))
+}
+
+//reserve seqno for the next message
+func (s *Sender) reserve() (int64, error) {
+	seqno, err := s.m.Ton.GetWalletSeqno(s.wallet.Addr)
+	if err != nil {
+		return 0, fmt.Errorf("GetWalletSeqno failed: %w", err)
+	}
+	if seqno < s.next {
+		// the lite server lags behind the last confirmation
+		seqno = s.next
+	}
+	return seqno, nil
+}
+
+//confirmed wait until the wallet seqno moves past seqno or seqnoTimeout expires
+func (s *Sender) confirmed(seqno int64) bool {
+	for deadline := time.Now().Add(seqnoTimeout); time.Now().Before(deadline); {
+		time.Sleep(seqnoPollInterval)
+		current, err := s.m.Ton.GetWalletSeqno(s.wallet.Addr)
+		if err != nil {
+			log.Println("GetWalletSeqno failed:", err)
+			continue
+		}
+		if current > seqno {
+			return true
+		}
+	}
+	return false
+}
+
+//failed mark the message failed with err, the error is returned
+func (s *Sender) failed(msg database.Message, err error) error {
+	if msg.ID != 0 {
+		msg.State = MessageFailed
+		msg.Error = err.Error()
+		if uerr := s.m.Store.UpdateMessage(msg); uerr != nil {
+			log.Println("Failed to update message record:", uerr)
+		}
+	}
+	return err
+}
+
+//walletMessage external message from the wallet with seqno sending amount nanograms with body to the elector
+func (m *Machine) walletMessage(wallet database.Wallet, amount int64, body *cell.Cell, seqno int64) (*cell.Cell, error) {
+	w, err := m.loadWallet(wallet)
+	if err != nil {
+		return nil, err
+	}
+	elector, err := message.ParseAddress(m.ElectorAddr)
+	if err != nil {
+		return nil, err
+	}
+	return w.Transfer(elector, amount, true, body, uint32(seqno))
+}
+
+//loadWallet wallet signing with the machine's signer, its key from the keystore or,
+//for wallets not imported yet, its key file
+func (m *Machine) loadWallet(wallet database.Wallet) (*message.Wallet, error) {
+	addr, err := message.ParseAddress(wallet.Addr)
+	if err != nil {
+		return nil, err
+	}
+	s, err := m.walletSigner(wallet, addr)
+	if err != nil {
+		return nil, err
+	}
+	return message.NewWallet(addr, wallet.Version, uint32(wallet.SubwalletID), s)
+}
+
+//walletSigner signer of the wallet's messages, key files of wallets not imported yet are used with a warning
+func (m *Machine) walletSigner(wallet database.Wallet, addr message.Address) (message.Signer, error) {
+	if m.Signer != nil {
+		return m.Signer, nil
+	}
+	if m.Keys != nil {
+		if _, ok := m.Keys.Key(wallet.ID); ok {
+			return signer.NewKeystore(m.Keys, wallet.ID), nil
+		}
+	}
+	if wallet.FilePath == "" {
+		return nil, fmt.Errorf("no key of wallet %d, is the keystore unlocked?", wallet.ID)
+	}
+	log.Printf("Warning: wallet %d signs with the unencrypted key file %s, import it with `ton-cli wallet import %d`", wallet.ID, wallet.FilePath, wallet.ID)
+	local, fileAddr, err := signer.LoadFile(wallet.FilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load wallet %s: %w", wallet.FilePath, err)
+	}
+	if !fileAddr.Equal(addr) {
+		return nil, fmt.Errorf("wallet file %s is for address %s", wallet.FilePath, fileAddr)
+	}
+	return local, nil
+}
@@ -0,0 +1,102 @@
+package staking
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mercuryoio/ton-validator/cell"
+	"github.com/mercuryoio/ton-validator/database"
+	"github.com/mercuryoio/ton-validator/message"
+	"github.com/mercuryoio/ton-validator/signer"
+	"github.com/mercuryoio/ton-validator/signer/signertest"
+)
+
+func TestSignerMessages(t *testing.T) {
+	walletAddr := message.Address{Workchain: -1, Hash: bytes.Repeat([]byte{0x11}, 32)}
+	elector := message.Address{Workchain: -1, Hash: bytes.Repeat([]byte{0x33}, 32)}
+	other := message.Address{Workchain: 0, Hash: bytes.Repeat([]byte{0x44}, 32)}
+
+	stub, err := signertest.NewStub()
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(&signer.Server{
+		Signers: map[string]message.Signer{walletAddr.String(): stub},
+		Policy:  signer.Policy{Allow: []message.Address{elector}, MaxAmount: 10001e9},
+	})
+	defer server.Close()
+	m := &Machine{ElectorAddr: elector.String(), Signer: signer.NewRemote(server.URL, "", time.Second)}
+	wallet := database.Wallet{ID: 1, Addr: walletAddr.String(), Version: message.WalletV3}
+
+	pub, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := message.ElectRequest(walletAddr, 1600000000, 3<<16, bytes.Repeat([]byte{0x22}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stake, err := message.NewStake(req, pub, ed25519.Sign(key, req), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recoverBody, err := message.RecoverStake(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := m.loadWallet(wallet)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		send func() (*cell.Cell, error)
+		ok   bool
+	}{
+		{"stake", func() (*cell.Cell, error) { return m.walletMessage(wallet, 10001e9, stake, 1) }, true},
+		{"recover", func() (*cell.Cell, error) { return m.walletMessage(wallet, 1e9, recoverBody, 2) }, true},
+		{"stake over the cap", func() (*cell.Cell, error) { return m.walletMessage(wallet, 10002e9, stake, 3) }, false},
+		{"transfer to the elector", func() (*cell.Cell, error) { return w.Transfer(elector, 5e9, true, nil, 3) }, true},
+		{"transfer elsewhere", func() (*cell.Cell, error) { return w.Transfer(other, 1e9, false, nil, 4) }, false},
+	}
+	for _, tt := range tests {
+		ext, err := tt.send()
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: got %v, want ok %v", tt.name, err, tt.ok)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		// the wallet checks the signature over the rest of the body
+		body, err := message.ExternalBody(ext)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := body.BeginParse()
+		signature := s.LoadBits(512)
+		signed, err := cell.BeginCell().StoreSlice(s).EndCell()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ed25519.Verify(stub.PublicKey(), signed.Hash(), signature) {
+			t.Errorf("%s: signature does not match the signer key", tt.name)
+		}
+	}
+
+	signed := stub.Signed()
+	want := []int64{10001e9, 1e9, 5e9}
+	if len(signed) != len(want) {
+		t.Fatalf("signed %d messages, want %d", len(signed), len(want))
+	}
+	for i, out := range signed {
+		if !out.Dest.Equal(elector) || out.Amount != want[i] || !out.Bounce {
+			t.Errorf("message %d: %+v, want %d to the elector with bounce", i, out, want[i])
+		}
+	}
+}
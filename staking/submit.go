@@ -0,0 +1,61 @@
+package staking
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/mercuryoio/ton-validator/database"
+)
+
+//Submit stake from the wallet for the node in the active election right away,
+//with the given amount and max factor instead of the machine defaults.
+//A failed stake of the node in this election is started over.
+func (m *Machine) Submit(wallet database.Wallet, node database.Node, activeElectionID int64, amount int64, maxFactor string) (database.Stake, error) {
+	if activeElectionID == 0 {
+		return database.Stake{}, fmt.Errorf("no active election")
+	}
+	if node.WalletID != wallet.ID {
+		return database.Stake{}, fmt.Errorf("node %d belongs to wallet %d, not %d", node.ID, node.WalletID, wallet.ID)
+	}
+	stake, err := m.Store.GetStake(wallet.ID, node.ID, activeElectionID)
+	switch {
+	case err == sql.ErrNoRows:
+		stake = database.Stake{
+			WalletID:    wallet.ID,
+			NodeID:      node.ID,
+			ElectionID:  activeElectionID,
+			State:       StateDiscovered,
+			StakeAmount: amount,
+			MaxFactor:   maxFactor,
+		}
+		if stake.ID, err = m.Store.AddStake(stake); err != nil {
+			return database.Stake{}, err
+		}
+	case err != nil:
+		return database.Stake{}, err
+	default:
+		switch stake.State {
+		case StateFailed:
+			stake.State = StateDiscovered
+		case StateRequestSigned:
+			// the signature covers the max factor, sign again
+			stake.State = StateKeysCreated
+		case StateDiscovered, StateKeysCreated:
+		default:
+			return stake, fmt.Errorf("stake of node %d in election %d is already %s", node.ID, activeElectionID, stake.State)
+		}
+		stake.StakeAmount = amount
+		stake.MaxFactor = maxFactor
+		stake.Error = ""
+		if err = m.Store.UpdateStake(stake); err != nil {
+			return database.Stake{}, err
+		}
+	}
+
+	t := tick{now: time.Now().Unix(), activeElectionID: activeElectionID}
+	if err = m.advance(wallet, stake, t); err != nil {
+		return stake, err
+	}
+	return m.Store.GetStake(wallet.ID, node.ID, activeElectionID)
+}
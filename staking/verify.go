@@ -0,0 +1,101 @@
+package staking
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/mercuryoio/ton-validator/database"
+	"github.com/mercuryoio/ton-validator/wrappers/validator"
+)
+
+//Results of checking a stored key against the node
+const (
+	KeyOK       = "ok"
+	KeyMissing  = "missing"
+	KeyMismatch = "mismatch"
+)
+
+//KeyCheck what the node has for a stored key
+type KeyCheck struct {
+	ElectionID int64
+	Type       string
+	Key        string
+	State      string
+	//Detail engine error of a missing key, or the public key the node has for a mismatched one
+	Detail string
+}
+
+//VerifyKeys export the public keys of the node's keys that are neither removed nor expired.
+//The validator key must match the stored public key, the ADNL key only has to exist.
+func (m *Machine) VerifyKeys(node database.Node, now int64) ([]KeyCheck, error) {
+	sets, err := m.NodeKeys(node.ID)
+	if err != nil {
+		return nil, err
+	}
+	var checks []KeyCheck
+	for _, set := range sets {
+		if set.Deleted() || set.ExpireAt <= now {
+			continue
+		}
+		for _, keyType := range []string{"key", "adnlkey"} {
+			hash := set.Key(keyType)
+			if hash == "" {
+				continue
+			}
+			check := KeyCheck{ElectionID: set.ElectionID, Type: keyType, Key: hash, State: KeyOK}
+			pub, err := m.Validator.ValidatorGetPublicKey(node, hash, set.ElectionID)
+			var ce validator.ControlError
+			if errors.As(err, &ce) {
+				check.State = KeyMissing
+				check.Detail = ce.Message
+			} else if err != nil {
+				return checks, err
+			} else if stored := set.Key("pubkey"); keyType == "key" && stored != "" && pub.Key != stored {
+				check.State = KeyMismatch
+				check.Detail = pub.Key
+			}
+			checks = append(checks, check)
+		}
+	}
+	return checks, nil
+}
+
+//RecreateKeys replace the keys of the node for the open election with new ones, for a node that lost them.
+//It is only possible before the stake is sent, the elector keeps a sent stake under the old public key.
+func (m *Machine) RecreateKeys(node database.Node, electionID, activeElectionID int64) error {
+	if electionID != activeElectionID {
+		return fmt.Errorf("election %d is closed, its keys can't be replaced", electionID)
+	}
+	stake, err := m.Store.GetStake(node.WalletID, node.ID, electionID)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if err == nil && Submitted(stake.State) {
+		return fmt.Errorf("stake of election %d was sent with the lost key, restore the node keyring from a backup before the election closes, the validator is punished if elected without it", electionID)
+	}
+	sets, err := m.NodeKeys(node.ID)
+	if err != nil {
+		return err
+	}
+	for _, set := range sets {
+		if set.ElectionID == electionID {
+			// whatever the node still has of the old keys goes away
+			if err = m.deleteKeys(node, set); err != nil {
+				return err
+			}
+		}
+	}
+	if err = m.createKeys(node, electionID); err != nil {
+		return err
+	}
+	log.Println("Recreated keys of election", electionID, "on node", node.HostPort)
+	if stake.ID != 0 && stake.State == StateRequestSigned {
+		// the request was signed with the old key
+		stake.State = StateKeysCreated
+		stake.Signature = ""
+		return m.Store.UpdateStake(stake)
+	}
+	return nil
+}
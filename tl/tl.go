@@ -0,0 +1,156 @@
+package tl
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"strings"
+)
+
+//ErrShortBuffer data ended before the value was read
+var ErrShortBuffer = errors.New("tl: unexpected end of data")
+
+//ID constructor id of a TL scheme line, e.g. "tcp.ping random_id:long = tcp.Pong".
+//Parentheses are dropped as TL does, so (vector int) hashes as vector int.
+func ID(scheme string) uint32 {
+	return crc32.ChecksumIEEE([]byte(strings.NewReplacer("(", "", ")", "").Replace(scheme)))
+}
+
+//AppendUint32 append 32-bit little-endian value
+func AppendUint32(buf []byte, v uint32) []byte {
+	return append(buf, byte(v), byte(v>>8), byte(v>>16), byte(v>>24))
+}
+
+//AppendInt32 append TL int
+func AppendInt32(buf []byte, v int32) []byte {
+	return AppendUint32(buf, uint32(v))
+}
+
+//AppendInt64 append TL long
+func AppendInt64(buf []byte, v int64) []byte {
+	return binary.LittleEndian.AppendUint64(buf, uint64(v))
+}
+
+//AppendInt256 append TL int256, v must be 32 bytes long
+func AppendInt256(buf []byte, v []byte) []byte {
+	var b [32]byte
+	copy(b[:], v)
+	return append(buf, b[:]...)
+}
+
+//AppendBytes append TL bytes/string with length prefix and padding
+func AppendBytes(buf []byte, data []byte) []byte {
+	n := len(data)
+	var prefix int
+	if n < 254 {
+		buf = append(buf, byte(n))
+		prefix = 1
+	} else {
+		buf = append(buf, 254, byte(n), byte(n>>8), byte(n>>16))
+		prefix = 4
+	}
+	buf = append(buf, data...)
+	for (prefix+n)%4 != 0 {
+		buf = append(buf, 0)
+		n++
+	}
+	return buf
+}
+
+//Reader sequential TL decoder, the first error sticks and zero values are returned after it
+type Reader struct {
+	buf []byte
+	err error
+}
+
+//NewReader reader over serialized data
+func NewReader(data []byte) *Reader {
+	return &Reader{buf: data}
+}
+
+//Err first decoding error
+func (r *Reader) Err() error {
+	return r.err
+}
+
+//Len bytes left
+func (r *Reader) Len() int {
+	return len(r.buf)
+}
+
+func (r *Reader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || len(r.buf) < n {
+		r.err = ErrShortBuffer
+		return nil
+	}
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b
+}
+
+//Uint32 read 32-bit value, also used for constructor ids
+func (r *Reader) Uint32() uint32 {
+	b := r.next(4)
+	if b == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint32(b)
+}
+
+//Int32 read TL int
+func (r *Reader) Int32() int32 {
+	return int32(r.Uint32())
+}
+
+//Int64 read TL long
+func (r *Reader) Int64() int64 {
+	b := r.next(8)
+	if b == nil {
+		return 0
+	}
+	return int64(binary.LittleEndian.Uint64(b))
+}
+
+//Int256 read TL int256
+func (r *Reader) Int256() []byte {
+	b := r.next(32)
+	if b == nil {
+		return nil
+	}
+	return append([]byte(nil), b...)
+}
+
+//Bytes read TL bytes/string
+func (r *Reader) Bytes() []byte {
+	head := r.next(1)
+	if head == nil {
+		return nil
+	}
+	n, prefix := int(head[0]), 1
+	if n == 254 {
+		b := r.next(3)
+		if b == nil {
+			return nil
+		}
+		n, prefix = int(b[0])|int(b[1])<<8|int(b[2])<<16, 4
+	} else if n == 255 {
+		r.err = errors.New("tl: bad bytes length prefix")
+		return nil
+	}
+	data := r.next(n)
+	if data == nil {
+		return nil
+	}
+	if pad := (4 - (prefix+n)%4) % 4; pad > 0 {
+		r.next(pad)
+	}
+	return append([]byte(nil), data...)
+}
+
+//Text read TL string
+func (r *Reader) Text() string {
+	return string(r.Bytes())
+}
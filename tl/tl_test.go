@@ -0,0 +1,23 @@
+package tl
+
+import "testing"
+
+func TestID(t *testing.T) {
+	tests := []struct {
+		scheme string
+		id     uint32
+	}{
+		{"tcp.ping random_id:long = tcp.Pong", 0x4d082b9a},
+		{"liteServer.query data:bytes = Object", 0x798c06df},
+		{"liteServer.getMasterchainInfo = liteServer.MasterchainInfo", 0x89b5e62e},
+		{"liteServer.getConfigParams mode:# id:tonNode.blockIdExt param_list:(vector int) = liteServer.ConfigInfo", 0x2a111c19},
+		{"liteServer.runSmcMethod mode:# id:tonNode.blockIdExt account:liteServer.accountId method_id:long params:bytes = liteServer.RunMethodResult", 0x5cc65dd2},
+		{"engine.validator.stats stats:(vector engine.validator.oneStat) = engine.validator.Stats", 0x5d49d36f},
+		{"engine.validator.overlaysStats overlays:(vector engine.validator.overlayStats) = engine.validator.OverlaysStats", 0x9c09267f},
+	}
+	for _, tt := range tests {
+		if got := ID(tt.scheme); got != tt.id {
+			t.Errorf("ID(%q) = %08x, want %08x", tt.scheme, got, tt.id)
+		}
+	}
+}
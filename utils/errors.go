@@ -0,0 +1,19 @@
+package utils
+
+import "fmt"
+
+//ParseError value read from a node, the db or the user could not be parsed
+type ParseError struct {
+	What  string
+	Value string
+	Err   error
+}
+
+func (e ParseError) Error() string {
+	return fmt.Sprintf("bad %s %q: %v", e.What, e.Value, e.Err)
+}
+
+//Unwrap underlying error
+func (e ParseError) Unwrap() error {
+	return e.Err
+}
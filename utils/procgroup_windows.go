@@ -0,0 +1,11 @@
+package utils
+
+import "os/exec"
+
+//setProcessGroup process groups are not used on windows
+func setProcessGroup(cmd *exec.Cmd) {}
+
+//killProcessGroup kill the command only
+func killProcessGroup(cmd *exec.Cmd) {
+	cmd.Process.Kill()
+}
@@ -0,0 +1,194 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//Tool timeout and retry policy of one external tool
+type Tool struct {
+	Timeout    time.Duration
+	Retries    int
+	RetryDelay time.Duration
+}
+
+//Runner runs external commands under the policy of their tool, found by binary name
+type Runner struct {
+	Default Tool
+	Tools   map[string]Tool
+	//MaxOutput bytes of stdout and of stderr kept, the rest is dropped
+	MaxOutput int
+	Verbose   bool
+	//Observe gets every attempt with its start and error, e.g. to count it, may be nil
+	Observe func(path string, start time.Time, err error)
+}
+
+//CmdResult outcome of the last attempt of a command
+type CmdResult struct {
+	Args     []string
+	ExitCode int
+	Stdout   string
+	Stderr   string
+	Duration time.Duration
+	Attempts int
+}
+
+//CmdError command failed, timed out or exited with non-zero code
+type CmdError struct {
+	Result CmdResult
+	Err    error
+}
+
+func (e CmdError) Error() string {
+	msg := fmt.Sprintf("%s failed after %d attempts: %v", e.Result.Args[0], e.Result.Attempts, e.Err)
+	if stderr := strings.TrimSpace(e.Result.Stderr); stderr != "" {
+		msg += ": " + stderr
+	}
+	return msg
+}
+
+//Unwrap underlying error
+func (e CmdError) Unwrap() error {
+	return e.Err
+}
+
+//DefaultRunner runner used by CmdExec
+var DefaultRunner = &Runner{
+	Default:   Tool{Timeout: time.Minute},
+	MaxOutput: 1 << 20,
+}
+
+//ParseTools parse tool policies "name=timeout[/retries],...", e.g. "lite-client=20s/2,fift=5s"
+func ParseTools(spec string) (map[string]Tool, error) {
+	tools := make(map[string]Tool)
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		i := strings.IndexByte(item, '=')
+		if i <= 0 {
+			return nil, ParseError{What: "tool policy", Value: item, Err: fmt.Errorf("want name=timeout[/retries]")}
+		}
+		var tool Tool
+		policy := strings.SplitN(item[i+1:], "/", 2)
+		timeout, err := time.ParseDuration(policy[0])
+		if err != nil {
+			return nil, ParseError{What: "tool policy", Value: item, Err: err}
+		}
+		tool.Timeout = timeout
+		if len(policy) == 2 {
+			if tool.Retries, err = strconv.Atoi(policy[1]); err != nil || tool.Retries < 0 {
+				return nil, ParseError{What: "tool policy", Value: item, Err: fmt.Errorf("bad retries %q", policy[1])}
+			}
+			tool.RetryDelay = time.Second
+		}
+		tools[item[:i]] = tool
+	}
+	return tools, nil
+}
+
+//tool policy of the binary at path
+func (r *Runner) tool(path string) Tool {
+	if tool, ok := r.Tools[filepath.Base(path)]; ok {
+		return tool
+	}
+	return r.Default
+}
+
+//Run run the command until it succeeds or its tool runs out of retries, each attempt bounded
+//by the tool timeout. On timeout or cancel of ctx the whole process group is killed.
+func (r *Runner) Run(ctx context.Context, path string, args ...string) (CmdResult, error) {
+	tool := r.tool(path)
+	for attempt := 1; ; attempt++ {
+		result, err := r.runOnce(ctx, tool.Timeout, path, args...)
+		result.Attempts = attempt
+		if err == nil {
+			return result, nil
+		}
+		if attempt > tool.Retries || ctx.Err() != nil {
+			return result, CmdError{Result: result, Err: err}
+		}
+		log.Printf("%s failed, retrying in %s: %v", filepath.Base(path), tool.RetryDelay, err)
+		select {
+		case <-time.After(tool.RetryDelay):
+		case <-ctx.Done():
+			return result, CmdError{Result: result, Err: ctx.Err()}
+		}
+	}
+}
+
+//runOnce one attempt of the command
+func (r *Runner) runOnce(ctx context.Context, timeout time.Duration, path string, args ...string) (CmdResult, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	cmd := exec.Command(path, args...)
+	setProcessGroup(cmd)
+	stdout := &limitedBuffer{limit: r.MaxOutput}
+	stderr := &limitedBuffer{limit: r.MaxOutput}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	start := time.Now()
+	result := CmdResult{Args: cmd.Args, ExitCode: -1}
+	err := cmd.Start()
+	if err == nil {
+		done := make(chan error, 1)
+		go func() { done <- cmd.Wait() }()
+		select {
+		case err = <-done:
+		case <-ctx.Done():
+			killProcessGroup(cmd)
+			<-done
+			err = ctx.Err()
+		}
+	}
+	result.Duration = time.Since(start)
+	if cmd.ProcessState != nil {
+		result.ExitCode = cmd.ProcessState.ExitCode()
+	}
+	result.Stdout = stdout.String()
+	result.Stderr = stderr.String()
+	if r.Observe != nil {
+		r.Observe(path, start, err)
+	}
+	if r.Verbose {
+		log.Println(cmd.Args, "exit code", result.ExitCode, "in", result.Duration)
+		log.Println(result.Stdout, result.Stderr)
+	}
+	return result, err
+}
+
+//limitedBuffer keeps the first limit bytes written to it, 0 is no limit
+type limitedBuffer struct {
+	buf   bytes.Buffer
+	limit int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	n := len(p)
+	if b.limit > 0 {
+		if room := b.limit - b.buf.Len(); room < len(p) {
+			if room < 0 {
+				room = 0
+			}
+			p = p[:room]
+		}
+	}
+	b.buf.Write(p)
+	return n, nil
+}
+
+func (b *limitedBuffer) String() string {
+	return b.buf.String()
+}
@@ -0,0 +1,89 @@
+//go:build !windows
+// +build !windows
+
+package utils
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseTools(t *testing.T) {
+	tools, err := ParseTools("lite-client=20s/2, fift=5s,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]Tool{
+		"lite-client": {Timeout: 20 * time.Second, Retries: 2, RetryDelay: time.Second},
+		"fift":        {Timeout: 5 * time.Second},
+	}
+	if !reflect.DeepEqual(tools, want) {
+		t.Errorf("ParseTools() = %v, want %v", tools, want)
+	}
+	for _, spec := range []string{"fift", "=5s", "fift=5", "fift=5s/x", "fift=5s/-1"} {
+		var pe ParseError
+		if _, err := ParseTools(spec); !errors.As(err, &pe) {
+			t.Errorf("ParseTools(%q) = %v, want ParseError", spec, err)
+		}
+	}
+}
+
+func TestRunOutput(t *testing.T) {
+	r := &Runner{Default: Tool{Timeout: 10 * time.Second}}
+	result, err := r.Run(context.Background(), "/bin/sh", "-c", "echo out; echo err >&2; exit 3")
+	var ce CmdError
+	if !errors.As(err, &ce) {
+		t.Fatalf("Run() = %v, want CmdError", err)
+	}
+	if result.ExitCode != 3 || result.Stdout != "out\n" || result.Stderr != "err\n" || result.Attempts != 1 {
+		t.Errorf("Run() = %+v, want exit code 3, separate output and 1 attempt", result)
+	}
+
+	result, err = r.Run(context.Background(), "/bin/sh", "-c", "echo ok")
+	if err != nil || result.ExitCode != 0 || result.Stdout != "ok\n" {
+		t.Errorf("Run() = %+v, %v, want ok", result, err)
+	}
+}
+
+func TestRunRetries(t *testing.T) {
+	var observed []error
+	r := &Runner{
+		Default: Tool{Timeout: 10 * time.Second},
+		Tools:   map[string]Tool{"sh": {Timeout: 10 * time.Second, Retries: 2, RetryDelay: 10 * time.Millisecond}},
+		Observe: func(path string, start time.Time, err error) { observed = append(observed, err) },
+	}
+	result, err := r.Run(context.Background(), "/bin/sh", "-c", "exit 1")
+	if err == nil || result.Attempts != 3 {
+		t.Errorf("Run() = %+v, %v, want failure after 3 attempts", result, err)
+	}
+	if len(observed) != 3 || observed[2] == nil {
+		t.Errorf("observed %v, want 3 failed attempts", observed)
+	}
+}
+
+func TestRunTimeoutKillsGroup(t *testing.T) {
+	r := &Runner{Default: Tool{Timeout: 200 * time.Millisecond}}
+	start := time.Now()
+	// the background sleep keeps stdout open, only killing the group ends the run early
+	result, err := r.Run(context.Background(), "/bin/sh", "-c", "sleep 30 & sleep 30")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Run() = %v, want deadline exceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Run() took %s, the process group was not killed", elapsed)
+	}
+	if result.ExitCode != -1 {
+		t.Errorf("exit code %d, want -1 for a killed command", result.ExitCode)
+	}
+}
+
+func TestRunMaxOutput(t *testing.T) {
+	r := &Runner{Default: Tool{Timeout: 10 * time.Second}, MaxOutput: 4}
+	result, err := r.Run(context.Background(), "/bin/sh", "-c", "echo 123456789")
+	if err != nil || result.Stdout != "1234" {
+		t.Errorf("Run() = %q, %v, want output cut to 4 bytes", result.Stdout, err)
+	}
+}
@@ -1,13 +1,13 @@
 package utils
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/hex"
 	"fmt"
 	"log"
 	"math/big"
 	"os"
-	"os/exec"
 	"strings"
 )
 
@@ -56,21 +56,20 @@ func FileExists(filename string) bool {
 	return !info.IsDir()
 }
 
-//CmdExec Command execution
+//CmdExec Command execution with DefaultRunner, stdout and stderr are returned together
 func CmdExec(path string, verbose bool, args ...string) (string, error) {
-	cmd := exec.Command(path, args...)
-
-	cmd.StdinPipe()
-	output, err := cmd.CombinedOutput()
 	if verbose {
-		log.Println(cmd.Args)
-		log.Println(string(output))
+		log.Println(append([]string{path}, args...))
+	}
+	result, err := DefaultRunner.Run(context.Background(), path, args...)
+	output := result.Stdout + result.Stderr
+	if verbose {
+		log.Println(output)
 	}
 	if err != nil {
-		log.Println(path, "failed:", err)
-		return string(output), err
+		log.Println(err)
 	}
-	return string(output), err
+	return output, err
 }
 
 //AppendToFile append to file
@@ -87,12 +86,12 @@ func AppendToFile(path, key, value string) {
 }
 
 //PubKeyToHex pulibc key to hex conversion
-func PubKeyToHex(pubKey string) string {
+func PubKeyToHex(pubKey string) (string, error) {
 	p, err := base64.StdEncoding.DecodeString(pubKey)
 	if err != nil {
-		log.Fatalln(err)
+		return "", ParseError{What: "public key", Value: pubKey, Err: err}
 	}
 	h := hex.EncodeToString(p)
 	h = strings.TrimPrefix(h, "c6b41348")
-	return h
+	return h, nil
 }
@@ -0,0 +1,244 @@
+package liteclient
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/mercuryoio/ton-validator/cell"
+)
+
+//Participant stake in the active election
+type Participant struct {
+	PublicKey []byte
+	Stake     int64
+	//MaxFactor multiplied by 65536, 0 if the elector does not report it
+	MaxFactor int64
+}
+
+//FrozenStake stake of an elected validator held by the elector
+type FrozenStake struct {
+	WalletAddr []byte
+	Weight     uint64
+	Stake      int64
+	Banned     bool
+}
+
+//PastElection election whose stakes are still frozen
+type PastElection struct {
+	ElectionID int64
+	UnfreezeAt int64
+	StakeHeld  int64
+	TotalStake int64
+	Bonuses    int64
+	//Frozen by hex of the validator public key
+	Frozen map[string]FrozenStake
+}
+
+//ValidatorsConfig validator count limits from config param 16
+type ValidatorsConfig struct {
+	MaxValidators     int
+	MaxMainValidators int
+	MinValidators     int
+}
+
+//GetValidatorsConfig get validator count limits
+func (c *Config) GetValidatorsConfig() (ValidatorsConfig, error) {
+	params, err := c.GetConfigParams(16)
+	if err != nil {
+		return ValidatorsConfig{}, err
+	}
+	s := params[16].BeginParse()
+	config := ValidatorsConfig{
+		MaxValidators:     int(s.LoadUint(16)),
+		MaxMainValidators: int(s.LoadUint(16)),
+		MinValidators:     int(s.LoadUint(16)),
+	}
+	if err = s.Err(); err != nil {
+		return ValidatorsConfig{}, fmt.Errorf("bad config param 16: %v", err)
+	}
+	return config, nil
+}
+
+//GetActiveElectionID id of the open election, 0 when there is none
+func (c *Config) GetActiveElectionID(elector string) (int64, error) {
+	values, err := c.RunMethod(elector, "active_election_id")
+	if err != nil {
+		return 0, err
+	}
+	return intResult(values, 0)
+}
+
+//GetParticipants stakes submitted to the active election.
+//Max factors are only known from electors that have participant_list_extended.
+func (c *Config) GetParticipants(elector string) ([]Participant, error) {
+	values, err := c.RunMethod(elector, "participant_list_extended")
+	if _, ok := err.(MethodError); ok {
+		return c.getParticipantList(elector)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// elect_at, elect_close, min_stake, total_stake, list, failed, finished
+	if len(values) < 5 {
+		return nil, fmt.Errorf("participant_list_extended: %d results", len(values))
+	}
+	items, err := listItems(values[4])
+	if err != nil {
+		return nil, fmt.Errorf("participant_list_extended: %v", err)
+	}
+	participants := make([]Participant, 0, len(items))
+	for _, item := range items {
+		// [pubkey, [stake, max_factor, wallet, adnl_addr]]
+		pair, ok := item.([]interface{})
+		if !ok || len(pair) != 2 {
+			return nil, fmt.Errorf("participant_list_extended: bad entry %v", item)
+		}
+		key, err := publicKeyResult(pair[0])
+		if err != nil {
+			return nil, fmt.Errorf("participant_list_extended: %v", err)
+		}
+		info, ok := pair[1].([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("participant_list_extended: bad entry %v", item)
+		}
+		p := Participant{PublicKey: key}
+		if p.Stake, err = intResult(info, 0); err != nil {
+			return nil, fmt.Errorf("participant_list_extended: %v", err)
+		}
+		if p.MaxFactor, err = intResult(info, 1); err != nil {
+			return nil, fmt.Errorf("participant_list_extended: %v", err)
+		}
+		participants = append(participants, p)
+	}
+	return participants, nil
+}
+
+//getParticipantList stakes from participant_list of older electors
+func (c *Config) getParticipantList(elector string) ([]Participant, error) {
+	values, err := c.RunMethod(elector, "participant_list")
+	if err != nil {
+		return nil, err
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("participant_list: empty result")
+	}
+	items, err := listItems(values[0])
+	if err != nil {
+		return nil, fmt.Errorf("participant_list: %v", err)
+	}
+	participants := make([]Participant, 0, len(items))
+	for _, item := range items {
+		pair, ok := item.([]interface{})
+		if !ok || len(pair) != 2 {
+			return nil, fmt.Errorf("participant_list: bad entry %v", item)
+		}
+		key, err := publicKeyResult(pair[0])
+		if err != nil {
+			return nil, fmt.Errorf("participant_list: %v", err)
+		}
+		stake, err := intResult(pair, 1)
+		if err != nil {
+			return nil, fmt.Errorf("participant_list: %v", err)
+		}
+		participants = append(participants, Participant{PublicKey: key, Stake: stake})
+	}
+	return participants, nil
+}
+
+//GetPastElections elections whose stakes the elector still holds
+func (c *Config) GetPastElections(elector string) ([]PastElection, error) {
+	values, err := c.RunMethod(elector, "past_elections")
+	if err != nil {
+		return nil, err
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("past_elections: empty result")
+	}
+	items, err := listItems(values[0])
+	if err != nil {
+		return nil, fmt.Errorf("past_elections: %v", err)
+	}
+	elections := make([]PastElection, 0, len(items))
+	for _, item := range items {
+		// [election_id, unfreeze_at, stake_held, vset_hash, frozen_dict, total_stake, bonuses]
+		fields, ok := item.([]interface{})
+		if !ok || len(fields) < 7 {
+			return nil, fmt.Errorf("past_elections: bad entry %v", item)
+		}
+		var e PastElection
+		for i, dst := range map[int]*int64{0: &e.ElectionID, 1: &e.UnfreezeAt, 2: &e.StakeHeld, 5: &e.TotalStake, 6: &e.Bonuses} {
+			if *dst, err = intResult(fields, i); err != nil {
+				return nil, fmt.Errorf("past_elections: %v", err)
+			}
+		}
+		if e.Frozen, err = parseFrozen(fields[4]); err != nil {
+			return nil, fmt.Errorf("past_elections: election %d: %v", e.ElectionID, err)
+		}
+		elections = append(elections, e)
+	}
+	return elections, nil
+}
+
+//parseFrozen frozen stakes dictionary: pubkey -> addr:uint256 weight:uint64 true_stake:Grams banned:Bool
+func parseFrozen(v interface{}) (map[string]FrozenStake, error) {
+	frozen := map[string]FrozenStake{}
+	if v == nil {
+		return frozen, nil
+	}
+	var entries []cell.DictEntry
+	var err error
+	switch root := v.(type) {
+	case *cell.Cell:
+		entries, err = cell.DictEntries(root, 256)
+	case *cell.Slice:
+		entries, err = cell.LoadDictEntries(root, 256)
+	default:
+		return nil, fmt.Errorf("bad frozen dictionary %v", v)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		s := entry.Value
+		stake := FrozenStake{
+			WalletAddr: s.LoadBits(256),
+			Weight:     s.LoadUint(64),
+			Stake:      s.LoadGrams(),
+			Banned:     s.LoadBit(),
+		}
+		if err = s.Err(); err != nil {
+			return nil, err
+		}
+		frozen[fmt.Sprintf("%x", uint256(entry.Key))] = stake
+	}
+	return frozen, nil
+}
+
+//intResult i-th value as int64
+func intResult(values []interface{}, i int) (int64, error) {
+	if i >= len(values) {
+		return 0, fmt.Errorf("no result %d", i)
+	}
+	v, ok := values[i].(*big.Int)
+	if !ok {
+		return 0, fmt.Errorf("result %d is not an integer: %v", i, values[i])
+	}
+	if !v.IsInt64() {
+		return 0, fmt.Errorf("result %d overflows int64: %s", i, v)
+	}
+	return v.Int64(), nil
+}
+
+//publicKeyResult 256-bit public key from a stack integer
+func publicKeyResult(v interface{}) ([]byte, error) {
+	key, ok := v.(*big.Int)
+	if !ok || key.Sign() < 0 || key.BitLen() > 256 {
+		return nil, fmt.Errorf("bad public key %v", v)
+	}
+	return uint256(key), nil
+}
+
+//uint256 32 big endian bytes of a non-negative integer
+func uint256(v *big.Int) []byte {
+	return v.FillBytes(make([]byte, 32))
+}
@@ -1,17 +1,23 @@
 package liteclient
 
 import (
-	"github.com/mercuryoio/ton-validator/utils"
-	"log"
-	"strconv"
+	"encoding/hex"
+	"fmt"
 	"strings"
+	"sync"
+	"time"
+
+	"github.com/mercuryoio/ton-validator/adnl"
 )
 
 //Config config
 type Config struct {
-	LiteClient       *string
 	LiteclientConfig *string
+	Timeout          *time.Duration
 	Verbose          *bool
+
+	mu   sync.Mutex
+	conn *adnl.Conn
 }
 
 //NewClient new client
@@ -19,23 +25,28 @@ func NewClient(config *Config) *Config {
 	return config
 }
 
+//Close close lite server connection
+func (c *Config) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn != nil {
+		c.conn.Close()
+		c.conn = nil
+	}
+}
+
 //GetCurrentElectorAddress get current elector address
 func (c *Config) GetCurrentElectorAddress() (string, error) {
-	args := []string{"-C", *c.LiteclientConfig, "-v 0", "-r", "-c", "getconfig 1"}
-
-	output, err := utils.CmdExec(*c.LiteClient, *c.Verbose, args...)
+	params, err := c.GetConfigParams(1)
 	if err != nil {
-		log.Println("getCurrentElectorAddress err:", err)
-		return string(output), err
+		return "", err
 	}
-	i := strings.Index(output, "x{")
-	output = output[i:]
-	output = strings.TrimPrefix(output, "x{")
-	output = strings.Replace(output, "}", "", 1)
-	output = strings.TrimSpace(output)
-	output = "-1:" + output
-
-	return string(output), err
+	s := params[1].BeginParse()
+	addr := s.LoadBits(256)
+	if err = s.Err(); err != nil {
+		return "", fmt.Errorf("bad config param 1: %v", err)
+	}
+	return "-1:" + strings.ToUpper(hex.EncodeToString(addr)), nil
 }
 
 //ElectionPeriods election periods
@@ -46,6 +57,12 @@ type ElectionPeriods struct {
 	StakeHeldFor         int64
 }
 
+//KeyExpireAt when keys created for electionID are no longer needed: the validation round and the
+//stake freeze are over, with the election periods as a margin
+func (p ElectionPeriods) KeyExpireAt(electionID int64) int64 {
+	return electionID + p.ValidatorsElectedFor + p.StakeHeldFor + p.ElectionsStartBefore + p.ElectionsEndBefore
+}
+
 //StakeConfig network stake config
 type StakeConfig struct {
 	MinStake       int64
@@ -56,82 +73,68 @@ type StakeConfig struct {
 
 //GetElectionConfig get election config
 func (c *Config) GetElectionConfig() (ElectionPeriods, error) {
-	args := []string{"-C", *c.LiteclientConfig, "-v 0", "-r", "-c", "getconfig 15"}
-
-	output, err := utils.CmdExec(*c.LiteClient, *c.Verbose, args...)
+	params, err := c.GetConfigParams(15)
 	if err != nil {
-		log.Println("getCurrentElectorAddress err:", err)
 		return ElectionPeriods{}, err
 	}
-	i := strings.Index(output, "validators_elected_for")
-	output = output[i:]
-
-	output = strings.Replace(output, ")", "", 1)
-	lines := strings.Split(output, "\n")
-	output = lines[0]
-	lines = strings.Split(output, " ")
-	var values []string
-	for _, s := range lines {
-		strs := strings.Split(s, ":")
-		values = append(values, strs[1])
+	s := params[15].BeginParse()
+	periods := ElectionPeriods{
+		ValidatorsElectedFor: int64(s.LoadUint(32)),
+		ElectionsStartBefore: int64(s.LoadUint(32)),
+		ElectionsEndBefore:   int64(s.LoadUint(32)),
+		StakeHeldFor:         int64(s.LoadUint(32)),
 	}
-
-	var periods ElectionPeriods
-	periods.ValidatorsElectedFor, _ = strconv.ParseInt(values[0], 10, 64)
-	periods.ElectionsStartBefore, _ = strconv.ParseInt(values[1], 10, 64)
-	periods.ElectionsEndBefore, _ = strconv.ParseInt(values[2], 10, 64)
-	periods.StakeHeldFor, _ = strconv.ParseInt(values[3], 10, 64)
-	output = strings.TrimSpace(output)
-
-	return periods, err
-
+	if err = s.Err(); err != nil {
+		return ElectionPeriods{}, fmt.Errorf("bad config param 15: %v", err)
+	}
+	return periods, nil
 }
 
 //GetStakeConfig get stake config
 func (c *Config) GetStakeConfig() (StakeConfig, error) {
-	args := []string{"-C", *c.LiteclientConfig, "-v 0", "-r", "-c", "getconfig 17"}
-
-	output, err := utils.CmdExec(*c.LiteClient, *c.Verbose, args...)
+	params, err := c.GetConfigParams(17)
 	if err != nil {
-		log.Println("getStakeConfig err:", err)
 		return StakeConfig{}, err
 	}
-	i := strings.Index(output, "min_stake")
-	output = output[i:]
-	lines := strings.Split(output, "\n")
-	var values []string
-	for _, s := range lines {
-		i = strings.Index(s, "value")
-		if i > 0 {
-			output = s[i:]
-			output = strings.TrimPrefix(output, "value:")
-			output = strings.Replace(output, "))", "", 1)
-			output = strings.TrimRight(output, " ")
-			output = strings.TrimSpace(output)
-			i = strings.Index(output, "max_stake_factor")
-			if i > 0 {
-				minTotalStake := strings.Split(output, " ")
-				if minTotalStake[0] != "" {
-					values = append(values, minTotalStake[0])
-				}
-				output = output[i:]
-				output = strings.TrimPrefix(output, "max_stake_factor:")
-				output = strings.Replace(output, ")", "", 1)
-				output = strings.TrimSpace(output)
-				if output != "" {
-					values = append(values, output)
-				}
-			} else if output != "" {
-				values = append(values, output)
-			}
-		}
+	s := params[17].BeginParse()
+	stakeConfig := StakeConfig{
+		MinStake:       s.LoadGrams(),
+		MaxStake:       s.LoadGrams(),
+		MinTotalStake:  s.LoadGrams(),
+		MaxStakeFactor: int64(s.LoadUint(32)),
+	}
+	if err = s.Err(); err != nil {
+		return StakeConfig{}, fmt.Errorf("bad config param 17: %v", err)
 	}
+	return stakeConfig, nil
+}
+
+//Validator entry of a validator set
+type Validator struct {
+	PublicKey []byte
+	Weight    uint64
+	AdnlAddr  []byte
+}
 
-	var stakeConfig StakeConfig
-	stakeConfig.MinStake, _ = strconv.ParseInt(values[0], 10, 64)
-	stakeConfig.MaxStake, _ = strconv.ParseInt(values[1], 10, 64)
-	stakeConfig.MinTotalStake, _ = strconv.ParseInt(values[2], 10, 64)
-	stakeConfig.MaxStakeFactor, _ = strconv.ParseInt(values[3], 10, 64)
+//ValidatorSet validator set from config param 34 (current), 32 (previous) or 36 (next)
+type ValidatorSet struct {
+	UtimeSince  int64
+	UtimeUntil  int64
+	Total       int
+	Main        int
+	TotalWeight uint64
+	Validators  []Validator
+}
 
-	return stakeConfig, nil
+//GetValidatorSet get current validator set
+func (c *Config) GetValidatorSet() (ValidatorSet, error) {
+	params, err := c.GetConfigParams(34)
+	if err != nil {
+		return ValidatorSet{}, err
+	}
+	set, err := parseValidatorSet(params[34])
+	if err != nil {
+		return ValidatorSet{}, fmt.Errorf("bad config param 34: %v", err)
+	}
+	return set, nil
 }
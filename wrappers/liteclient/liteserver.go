@@ -0,0 +1,295 @@
+package liteclient
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math/big"
+	"math/rand"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/mercuryoio/ton-validator/adnl"
+	"github.com/mercuryoio/ton-validator/cell"
+	"github.com/mercuryoio/ton-validator/metrics"
+	"github.com/mercuryoio/ton-validator/tl"
+)
+
+//Lite server constructors, see lite_api.tl
+var (
+	idQuery              = tl.ID("liteServer.query data:bytes = Object")
+	idError              = tl.ID("liteServer.error code:int message:string = liteServer.Error")
+	idGetMasterchainInfo = tl.ID("liteServer.getMasterchainInfo = liteServer.MasterchainInfo")
+	idMasterchainInfo    = tl.ID("liteServer.masterchainInfo last:tonNode.blockIdExt state_root_hash:int256 init:tonNode.zeroStateIdExt = liteServer.MasterchainInfo")
+	idGetConfigParams    = tl.ID("liteServer.getConfigParams mode:# id:tonNode.blockIdExt param_list:(vector int) = liteServer.ConfigInfo")
+	idConfigInfo         = tl.ID("liteServer.configInfo mode:# id:tonNode.blockIdExt state_proof:bytes config_proof:bytes = liteServer.ConfigInfo")
+)
+
+//ServerError lite server answered with liteServer.error
+type ServerError struct {
+	Code    int32
+	Message string
+}
+
+func (e ServerError) Error() string {
+	return fmt.Sprintf("lite server error %d: %s", e.Code, e.Message)
+}
+
+//BlockID tonNode.blockIdExt
+type BlockID struct {
+	Workchain int32
+	Shard     int64
+	Seqno     int32
+	RootHash  []byte
+	FileHash  []byte
+}
+
+func appendBlockID(buf []byte, id BlockID) []byte {
+	buf = tl.AppendInt32(buf, id.Workchain)
+	buf = tl.AppendInt64(buf, id.Shard)
+	buf = tl.AppendInt32(buf, id.Seqno)
+	buf = tl.AppendInt256(buf, id.RootHash)
+	return tl.AppendInt256(buf, id.FileHash)
+}
+
+func readBlockID(r *tl.Reader) BlockID {
+	return BlockID{
+		Workchain: r.Int32(),
+		Shard:     r.Int64(),
+		Seqno:     r.Int32(),
+		RootHash:  r.Int256(),
+		FileHash:  r.Int256(),
+	}
+}
+
+//liteServer one entry of the "liteservers" list in the global config
+type liteServer struct {
+	IP   int64 `json:"ip"`
+	Port int   `json:"port"`
+	ID   struct {
+		Type string `json:"@type"`
+		Key  string `json:"key"`
+	} `json:"id"`
+}
+
+func (ls liteServer) addr() string {
+	ip := uint32(ls.IP)
+	host := net.IPv4(byte(ip>>24), byte(ip>>16), byte(ip>>8), byte(ip)).String()
+	return net.JoinHostPort(host, strconv.Itoa(ls.Port))
+}
+
+//readLiteServers lite servers from the lite-client/global config JSON
+func readLiteServers(path string) ([]liteServer, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var config struct {
+		LiteServers []liteServer `json:"liteservers"`
+	}
+	if err = json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+	if len(config.LiteServers) == 0 {
+		return nil, fmt.Errorf("no lite servers in %s", path)
+	}
+	return config.LiteServers, nil
+}
+
+//connect dial lite servers from the config in random order until one answers
+func (c *Config) connect() (*adnl.Conn, error) {
+	servers, err := readLiteServers(*c.LiteclientConfig)
+	if err != nil {
+		return nil, err
+	}
+	for _, i := range rand.Perm(len(servers)) {
+		ls := servers[i]
+		key, err := base64.StdEncoding.DecodeString(ls.ID.Key)
+		if err != nil {
+			log.Println("Bad key of lite server", ls.addr(), err)
+			continue
+		}
+		conn, err := adnl.Dial(ls.addr(), key, nil, *c.Timeout)
+		if err != nil {
+			log.Println("Lite server", ls.addr(), "unavailable:", err)
+			continue
+		}
+		if *c.Verbose {
+			log.Println("Connected to lite server", ls.addr())
+		}
+		return conn, nil
+	}
+	return nil, fmt.Errorf("none of %d lite servers is available", len(servers))
+}
+
+//query run lite server method, reconnecting once if the kept connection went stale
+func (c *Config) query(method []byte) (*tl.Reader, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for attempt := 0; ; attempt++ {
+		if c.conn == nil {
+			conn, err := c.connect()
+			if err != nil {
+				return nil, err
+			}
+			c.conn = conn
+		}
+		start := time.Now()
+		answer, err := c.conn.Query(tl.AppendBytes(tl.AppendUint32(nil, idQuery), method))
+		metrics.ObserveQuery("lite-server", start, err)
+		if err != nil {
+			c.conn.Close()
+			c.conn = nil
+			if attempt > 0 {
+				return nil, err
+			}
+			continue
+		}
+		r := tl.NewReader(answer)
+		if tl.NewReader(answer).Uint32() == idError {
+			r.Uint32()
+			se := ServerError{Code: r.Int32(), Message: r.Text()}
+			if err = r.Err(); err != nil {
+				return nil, err
+			}
+			return nil, se
+		}
+		return r, nil
+	}
+}
+
+//GetMasterchainInfo last masterchain block known to the lite server
+func (c *Config) GetMasterchainInfo() (BlockID, error) {
+	r, err := c.query(tl.AppendUint32(nil, idGetMasterchainInfo))
+	if err != nil {
+		return BlockID{}, err
+	}
+	if id := r.Uint32(); id != idMasterchainInfo {
+		return BlockID{}, fmt.Errorf("getMasterchainInfo: unexpected answer %08x", id)
+	}
+	last := readBlockID(r)
+	return last, r.Err()
+}
+
+//GetConfigParams config param cells from the latest masterchain state.
+//The state proof is checked against the root hash of the block, and the config proof against the state hash it proves.
+func (c *Config) GetConfigParams(params ...int32) (map[int32]*cell.Cell, error) {
+	last, err := c.GetMasterchainInfo()
+	if err != nil {
+		return nil, err
+	}
+	req := tl.AppendUint32(nil, idGetConfigParams)
+	req = tl.AppendUint32(req, 0)
+	req = appendBlockID(req, last)
+	req = tl.AppendInt32(req, int32(len(params)))
+	for _, p := range params {
+		req = tl.AppendInt32(req, p)
+	}
+	r, err := c.query(req)
+	if err != nil {
+		return nil, err
+	}
+	if id := r.Uint32(); id != idConfigInfo {
+		return nil, fmt.Errorf("getConfigParams: unexpected answer %08x", id)
+	}
+	r.Uint32() // mode
+	block := readBlockID(r)
+	stateProof := r.Bytes()
+	configProof := r.Bytes()
+	if err = r.Err(); err != nil {
+		return nil, err
+	}
+	if !sameBlock(block, last) {
+		return nil, fmt.Errorf("getConfigParams: answer for block %d, requested %d", block.Seqno, last.Seqno)
+	}
+
+	state, err := provenState(stateProof, configProof, last.RootHash)
+	if err != nil {
+		return nil, err
+	}
+	dict, err := configDict(state)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[int32]*cell.Cell, len(params))
+	for _, p := range params {
+		value, err := cell.DictGet(dict, 32, big.NewInt(int64(p)))
+		if err != nil {
+			return nil, fmt.Errorf("config param %d: %v", p, err)
+		}
+		if value == nil {
+			return nil, fmt.Errorf("config param %d not found", p)
+		}
+		param := value.LoadRef()
+		if err = value.Err(); err != nil {
+			return nil, fmt.Errorf("config param %d: %v", p, err)
+		}
+		result[p] = param
+	}
+	return result, nil
+}
+
+//sameBlock both ids are for the same block
+func sameBlock(a, b BlockID) bool {
+	return a.Workchain == b.Workchain && a.Shard == b.Shard && a.Seqno == b.Seqno &&
+		bytes.Equal(a.RootHash, b.RootHash) && bytes.Equal(a.FileHash, b.FileHash)
+}
+
+//provenState state root proven by the config proof, whose hash comes from the state_update of the block proven by the state proof
+func provenState(stateProof, configProof, rootHash []byte) (*cell.Cell, error) {
+	proof, err := cell.FromBOCSingle(stateProof)
+	if err != nil {
+		return nil, fmt.Errorf("bad state proof: %v", err)
+	}
+	block, err := cell.CheckProof(proof, rootHash)
+	if err != nil {
+		return nil, fmt.Errorf("bad state proof: %v", err)
+	}
+	// block: info, value_flow, state_update and extra
+	update := block.Ref(2)
+	if update == nil || update.Type() != cell.TypeMerkleUpdate {
+		return nil, fmt.Errorf("bad state proof: no state update")
+	}
+	s := update.BeginParse()
+	s.LoadUint(8)   // type
+	s.LoadBits(256) // old hash
+	stateHash := s.LoadBits(256)
+	if err = s.Err(); err != nil {
+		return nil, fmt.Errorf("bad state proof: %v", err)
+	}
+
+	proof, err = cell.FromBOCSingle(configProof)
+	if err != nil {
+		return nil, fmt.Errorf("bad config proof: %v", err)
+	}
+	state, err := cell.CheckProof(proof, stateHash)
+	if err != nil {
+		return nil, fmt.Errorf("bad config proof: %v", err)
+	}
+	return state, nil
+}
+
+//configDict config dictionary of a masterchain ShardStateUnsplit
+func configDict(state *cell.Cell) (*cell.Cell, error) {
+	// out_msg_queue_info, accounts, the anonymous cell and custom:(Maybe ^McStateExtra)
+	if state.RefsNum() != 4 {
+		return nil, fmt.Errorf("state has no masterchain extra")
+	}
+	s := state.Ref(3).BeginParse()
+	if tag := s.LoadUint(16); tag != 0xcc26 && s.Err() == nil {
+		return nil, fmt.Errorf("bad McStateExtra tag %04x", tag)
+	}
+	if s.LoadBit() {
+		s.LoadRef() // shard_hashes
+	}
+	s.LoadBits(256) // config_addr
+	dict := s.LoadRef()
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("bad McStateExtra: %v", err)
+	}
+	return dict, nil
+}
@@ -0,0 +1,282 @@
+package liteclient
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/mercuryoio/ton-validator/cell"
+	"github.com/mercuryoio/ton-validator/message"
+	"github.com/mercuryoio/ton-validator/tl"
+)
+
+//Get-method constructors, see lite_api.tl
+var (
+	idRunSmcMethod    = tl.ID("liteServer.runSmcMethod mode:# id:tonNode.blockIdExt account:liteServer.accountId method_id:long params:bytes = liteServer.RunMethodResult")
+	idRunMethodResult = tl.ID("liteServer.runMethodResult mode:# id:tonNode.blockIdExt shardblk:tonNode.blockIdExt shard_proof:mode.0?bytes proof:mode.0?bytes state_proof:mode.1?bytes init_c7:mode.3?bytes lib_extras:mode.4?bytes exit_code:int result:mode.2?bytes = liteServer.RunMethodResult")
+)
+
+//runMethodResultOnly mode bit asking only for the result stack, without proofs
+const runMethodResultOnly = 4
+
+//MethodError get-method finished with a non-zero exit code
+type MethodError struct {
+	Method   string
+	ExitCode int32
+}
+
+func (e MethodError) Error() string {
+	return fmt.Sprintf("get-method %s failed with exit code %d", e.Method, e.ExitCode)
+}
+
+//RunMethod run get-method of the account on the latest masterchain block.
+//Integer params are pushed in order, so the last one ends up on top of the stack.
+//Results come back in the order the method returns them: ints as *big.Int,
+//null as nil, cells and builders as *cell.Cell, slices as *cell.Slice and
+//tuples as []interface{}.
+func (c *Config) RunMethod(account string, method string, params ...*big.Int) ([]interface{}, error) {
+	addr, err := message.ParseAddress(account)
+	if err != nil {
+		return nil, err
+	}
+	stack, err := buildStack(params)
+	if err != nil {
+		return nil, err
+	}
+	last, err := c.GetMasterchainInfo()
+	if err != nil {
+		return nil, err
+	}
+	req := tl.AppendUint32(nil, idRunSmcMethod)
+	req = tl.AppendUint32(req, runMethodResultOnly)
+	req = appendBlockID(req, last)
+	req = tl.AppendInt32(req, addr.Workchain)
+	req = tl.AppendInt256(req, addr.Hash)
+	req = tl.AppendInt64(req, methodID(method))
+	req = tl.AppendBytes(req, stack.ToBOC())
+	r, err := c.query(req)
+	if err != nil {
+		return nil, err
+	}
+	if id := r.Uint32(); id != idRunMethodResult {
+		return nil, fmt.Errorf("runSmcMethod: unexpected answer %08x", id)
+	}
+	mode := r.Uint32()
+	readBlockID(r)
+	readBlockID(r)
+	if mode&1 != 0 {
+		r.Bytes() // shard_proof
+		r.Bytes() // proof
+	}
+	if mode&2 != 0 {
+		r.Bytes() // state_proof
+	}
+	if mode&8 != 0 {
+		r.Bytes() // init_c7
+	}
+	if mode&16 != 0 {
+		r.Bytes() // lib_extras
+	}
+	exitCode := r.Int32()
+	var result []byte
+	if mode&4 != 0 {
+		result = r.Bytes()
+	}
+	if err = r.Err(); err != nil {
+		return nil, err
+	}
+	if exitCode != 0 && exitCode != 1 {
+		return nil, MethodError{Method: method, ExitCode: exitCode}
+	}
+	root, err := cell.FromBOCSingle(result)
+	if err != nil {
+		return nil, fmt.Errorf("%s: bad result stack: %v", method, err)
+	}
+	values, err := parseStack(root)
+	if err != nil {
+		return nil, fmt.Errorf("%s: bad result stack: %v", method, err)
+	}
+	return values, nil
+}
+
+//methodID id of a get-method by its name, as func_compiler assigns it
+func methodID(name string) int64 {
+	var crc uint16
+	for i := 0; i < len(name); i++ {
+		crc ^= uint16(name[i]) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return int64(crc) | 0x10000
+}
+
+//buildStack VmStack with the ints, the first one at the bottom
+func buildStack(params []*big.Int) (*cell.Cell, error) {
+	list, err := cell.BeginCell().EndCell()
+	if err != nil {
+		return nil, err
+	}
+	for i, p := range params {
+		b := cell.BeginCell()
+		if i == len(params)-1 {
+			b.StoreUint(uint64(len(params)), 24)
+		}
+		b.StoreRef(list)
+		if p.IsInt64() {
+			b.StoreUint(0x01, 8).StoreInt(p.Int64(), 64)
+		} else {
+			if p.Sign() < 0 || p.BitLen() > 256 {
+				return nil, fmt.Errorf("param %s is out of supported range", p)
+			}
+			// vm_stk_int#0201_ value:int257, non-negative values start with a zero bit
+			b.StoreUint(0x0100, 15).StoreUint(0, 1).StoreBigUint(p, 256)
+		}
+		if list, err = b.EndCell(); err != nil {
+			return nil, err
+		}
+	}
+	if len(params) == 0 {
+		return cell.BeginCell().StoreUint(0, 24).EndCell()
+	}
+	return list, nil
+}
+
+//parseStack VmStack values from the bottom to the top
+func parseStack(root *cell.Cell) ([]interface{}, error) {
+	s := root.BeginParse()
+	depth := int(s.LoadUint(24))
+	values := make([]interface{}, depth)
+	for i := depth - 1; i >= 0; i-- {
+		rest := s.LoadRef()
+		if err := s.Err(); err != nil {
+			return nil, err
+		}
+		v, err := parseStackValue(s)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+		s = rest.BeginParse()
+	}
+	return values, s.Err()
+}
+
+func parseStackValue(s *cell.Slice) (interface{}, error) {
+	switch tag := s.LoadUint(8); tag {
+	case 0x00:
+		return nil, s.Err()
+	case 0x01:
+		return big.NewInt(s.LoadInt(64)), s.Err()
+	case 0x02:
+		switch s.LoadUint(7) {
+		case 0:
+			return s.LoadBigInt(257), s.Err()
+		case 0x7f:
+			if s.LoadBit() {
+				return nil, fmt.Errorf("NaN on the stack")
+			}
+		}
+	case 0x03, 0x05:
+		return s.LoadRef(), s.Err()
+	case 0x04:
+		c := s.LoadRef()
+		stBits, endBits := int(s.LoadUint(10)), int(s.LoadUint(10))
+		stRef, endRef := int(s.LoadUint(3)), int(s.LoadUint(3))
+		if err := s.Err(); err != nil {
+			return nil, err
+		}
+		return subslice(c, stBits, endBits, stRef, endRef)
+	case 0x07:
+		n := int(s.LoadUint(16))
+		return parseTuple(s, n)
+	default:
+		if s.Err() == nil {
+			return nil, fmt.Errorf("unsupported stack value %02x", tag)
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("bad stack value")
+}
+
+//parseTuple VmTuple n: head:(VmTupleRef n-1) tail:^VmStackValue
+func parseTuple(s *cell.Slice, n int) ([]interface{}, error) {
+	if n == 0 {
+		return []interface{}{}, s.Err()
+	}
+	var head []interface{}
+	switch {
+	case n-1 == 1:
+		v, err := parseStackValue(s.LoadRef().BeginParse())
+		if err != nil {
+			return nil, err
+		}
+		head = []interface{}{v}
+	case n-1 > 1:
+		ref := s.LoadRef()
+		if err := s.Err(); err != nil {
+			return nil, err
+		}
+		var err error
+		if head, err = parseTuple(ref.BeginParse(), n-1); err != nil {
+			return nil, err
+		}
+	}
+	tail := s.LoadRef()
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	v, err := parseStackValue(tail.BeginParse())
+	if err != nil {
+		return nil, err
+	}
+	return append(head, v), nil
+}
+
+//subslice bits and references of c that a VmCellSlice points to
+func subslice(c *cell.Cell, stBits, endBits, stRef, endRef int) (*cell.Slice, error) {
+	s := c.BeginParse()
+	s.LoadBits(stBits)
+	for i := 0; i < stRef; i++ {
+		s.LoadRef()
+	}
+	b := cell.BeginCell()
+	for n := endBits - stBits; n > 0; {
+		chunk := n
+		if chunk > 64 {
+			chunk = 64
+		}
+		b.StoreUint(s.LoadUint(chunk), chunk)
+		n -= chunk
+	}
+	for i := stRef; i < endRef; i++ {
+		b.StoreRef(s.LoadRef())
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	sub, err := b.EndCell()
+	if err != nil {
+		return nil, err
+	}
+	return sub.BeginParse(), nil
+}
+
+//listItems items of a lisp-style list: nested [head, tail] pairs ending with null
+func listItems(v interface{}) ([]interface{}, error) {
+	var items []interface{}
+	for v != nil {
+		pair, ok := v.([]interface{})
+		if !ok || len(pair) != 2 {
+			return nil, fmt.Errorf("bad list entry %v", v)
+		}
+		items = append(items, pair[0])
+		v = pair[1]
+	}
+	return items, nil
+}
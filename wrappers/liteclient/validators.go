@@ -0,0 +1,68 @@
+package liteclient
+
+import (
+	"fmt"
+
+	"github.com/mercuryoio/ton-validator/cell"
+)
+
+//parseValidatorSet decode validators#11 or validators_ext#12
+func parseValidatorSet(c *cell.Cell) (ValidatorSet, error) {
+	s := c.BeginParse()
+	tag := s.LoadUint(8)
+	set := ValidatorSet{
+		UtimeSince: int64(s.LoadUint(32)),
+		UtimeUntil: int64(s.LoadUint(32)),
+		Total:      int(s.LoadUint(16)),
+		Main:       int(s.LoadUint(16)),
+	}
+	var entries []cell.DictEntry
+	var err error
+	switch tag {
+	case 0x11:
+		entries, err = cell.LoadDictEntries(s, 16)
+	case 0x12:
+		set.TotalWeight = s.LoadUint(64)
+		entries, err = cell.DictEntries(s.LoadMaybeRef(), 16)
+	default:
+		if s.Err() == nil {
+			return ValidatorSet{}, fmt.Errorf("unknown validator set tag %02x", tag)
+		}
+	}
+	if s.Err() != nil {
+		return ValidatorSet{}, s.Err()
+	}
+	if err != nil {
+		return ValidatorSet{}, err
+	}
+	for _, entry := range entries {
+		v, err := parseValidator(entry.Value)
+		if err != nil {
+			return ValidatorSet{}, fmt.Errorf("validator %s: %v", entry.Key, err)
+		}
+		if tag == 0x11 {
+			set.TotalWeight += v.Weight
+		}
+		set.Validators = append(set.Validators, v)
+	}
+	return set, nil
+}
+
+//parseValidator decode validator#53 or validator_addr#73
+func parseValidator(s *cell.Slice) (Validator, error) {
+	tag := s.LoadUint(8)
+	if tag != 0x53 && tag != 0x73 && s.Err() == nil {
+		return Validator{}, fmt.Errorf("unknown validator descr tag %02x", tag)
+	}
+	if keyTag := s.LoadUint(32); keyTag != 0x8e81278a && s.Err() == nil {
+		return Validator{}, fmt.Errorf("unknown public key tag %08x", keyTag)
+	}
+	v := Validator{
+		PublicKey: s.LoadBits(256),
+		Weight:    s.LoadUint(64),
+	}
+	if tag == 0x73 {
+		v.AdnlAddr = s.LoadBits(256)
+	}
+	return v, s.Err()
+}
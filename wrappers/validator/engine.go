@@ -0,0 +1,352 @@
+package validator
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mercuryoio/ton-validator/adnl"
+	"github.com/mercuryoio/ton-validator/database"
+	"github.com/mercuryoio/ton-validator/metrics"
+	"github.com/mercuryoio/ton-validator/tl"
+	"github.com/mercuryoio/ton-validator/utils"
+)
+
+//Control protocol constructors, see ton_api.tl
+var (
+	idControlQuery             = tl.ID("engine.validator.controlQuery data:bytes = Object")
+	idControlQueryError        = tl.ID("engine.validator.controlQueryError code:int message:string = engine.validator.ControlQueryError")
+	idSuccess                  = tl.ID("engine.validator.success = engine.validator.Success")
+	idGenerateKeyPair          = tl.ID("engine.validator.generateKeyPair = engine.validator.KeyHash")
+	idKeyHash                  = tl.ID("engine.validator.keyHash key_hash:int256 = engine.validator.KeyHash")
+	idExportPublicKey          = tl.ID("engine.validator.exportPublicKey key_hash:int256 = PublicKey")
+	idPubEd25519               = tl.ID("pub.ed25519 key:int256 = PublicKey")
+	idSign                     = tl.ID("engine.validator.sign key_hash:int256 data:bytes = engine.validator.Signature")
+	idSignature                = tl.ID("engine.validator.signature signature:bytes = engine.validator.Signature")
+	idAddValidatorPermanentKey = tl.ID("engine.validator.addValidatorPermanentKey key_hash:int256 election_date:int ttl:int = engine.validator.Success")
+	idAddValidatorTempKey      = tl.ID("engine.validator.addValidatorTempKey permanent_key_hash:int256 key_hash:int256 ttl:int = engine.validator.Success")
+	idAddValidatorAdnlAddress  = tl.ID("engine.validator.addValidatorAdnlAddress permanent_key_hash:int256 key_hash:int256 ttl:int = engine.validator.Success")
+	idAddAdnlID                = tl.ID("engine.validator.addAdnlId key_hash:int256 category:int = engine.validator.Success")
+	idDelValidatorPermanentKey = tl.ID("engine.validator.delValidatorPermanentKey key_hash:int256 = engine.validator.Success")
+	idDelValidatorTempKey      = tl.ID("engine.validator.delValidatorTempKey permanent_key_hash:int256 key_hash:int256 = engine.validator.Success")
+	idDelAdnlID                = tl.ID("engine.validator.delAdnlId key_hash:int256 = engine.validator.Success")
+	idGetStats                 = tl.ID("engine.validator.getStats = engine.validator.Stats")
+	idStats                    = tl.ID("engine.validator.stats stats:(vector engine.validator.oneStat) = engine.validator.Stats")
+	idGetConfig                = tl.ID("engine.validator.getConfig = engine.validator.JsonConfig")
+	idJSONConfig               = tl.ID("engine.validator.jsonConfig data:string = engine.validator.JsonConfig")
+	idGetOverlaysStats         = tl.ID("engine.validator.getOverlaysStats = engine.validator.OverlaysStats")
+	idOverlaysStats            = tl.ID("engine.validator.overlaysStats overlays:(vector engine.validator.overlayStats) = engine.validator.OverlaysStats")
+	idPubOverlay               = tl.ID("pub.overlay name:bytes = PublicKey")
+)
+
+//KeyHash id of a key in the validator engine keyring
+type KeyHash [32]byte
+
+//String hex form, as printed by validator-engine-console
+func (h KeyHash) String() string {
+	return strings.ToUpper(hex.EncodeToString(h[:]))
+}
+
+//ParseKeyHash parse hex key hash
+func ParseKeyHash(s string) (KeyHash, error) {
+	var h KeyHash
+	b, err := hex.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return h, utils.ParseError{What: "key hash", Value: s, Err: err}
+	}
+	if len(b) != len(h) {
+		return h, utils.ParseError{What: "key hash", Value: s, Err: fmt.Errorf("want %d bytes, got %d", len(h), len(b))}
+	}
+	copy(h[:], b)
+	return h, nil
+}
+
+//PublicKey validator public key
+type PublicKey ed25519.PublicKey
+
+//String base64 of the TL serialized key, as printed by validator-engine-console
+func (k PublicKey) String() string {
+	return base64.StdEncoding.EncodeToString(adnl.SerializePublicKey(ed25519.PublicKey(k)))
+}
+
+//ControlError validator engine rejected the query, Message is the engine's own text
+type ControlError struct {
+	Code    int32
+	Message string
+}
+
+func (e ControlError) Error() string {
+	return fmt.Sprintf("validator engine error %d: %s", e.Code, e.Message)
+}
+
+//UnreachableError the node's control port could not be reached or dropped the connection
+type UnreachableError struct {
+	Node string
+	Err  error
+}
+
+func (e UnreachableError) Error() string {
+	return fmt.Sprintf("node %s unreachable: %v", e.Node, e.Err)
+}
+
+//Unwrap underlying network error
+func (e UnreachableError) Unwrap() error {
+	return e.Err
+}
+
+//Engine authenticated control connection to one validator engine
+type Engine struct {
+	conn *adnl.Conn
+}
+
+//DialEngine connect to the node's control port with its client key
+func DialEngine(node database.Node, timeout time.Duration) (*Engine, error) {
+	serverKey, err := adnl.ReadPublicKey(node.ServerPub)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read server key %s: %w", node.ServerPub, err)
+	}
+	clientKey, err := adnl.ReadPrivateKey(node.ClientCert)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client key %s: %w", node.ClientCert, err)
+	}
+	conn, err := adnl.Dial(node.HostPort, serverKey, clientKey, timeout)
+	if err != nil {
+		return nil, UnreachableError{Node: node.HostPort, Err: err}
+	}
+	return &Engine{conn: conn}, nil
+}
+
+//Close close the control connection
+func (e *Engine) Close() error {
+	return e.conn.Close()
+}
+
+//Alive connection can still be used
+func (e *Engine) Alive() bool {
+	select {
+	case <-e.conn.Closed():
+		return false
+	default:
+		return true
+	}
+}
+
+//query send control query and return the answer with controlQueryError turned into error
+func (e *Engine) query(req []byte) (*tl.Reader, error) {
+	start := time.Now()
+	answer, err := e.conn.Query(tl.AppendBytes(tl.AppendUint32(nil, idControlQuery), req))
+	metrics.ObserveQuery("validator-engine", start, err)
+	if err != nil {
+		return nil, err
+	}
+	r := tl.NewReader(answer)
+	if tl.NewReader(answer).Uint32() == idControlQueryError {
+		r.Uint32()
+		ce := ControlError{Code: r.Int32(), Message: r.Text()}
+		if err = r.Err(); err != nil {
+			return nil, err
+		}
+		return nil, ce
+	}
+	return r, nil
+}
+
+//expect check answer constructor
+func expect(r *tl.Reader, id uint32, method string) error {
+	if got := r.Uint32(); got != id {
+		if err := r.Err(); err != nil {
+			return fmt.Errorf("%s: %v", method, err)
+		}
+		return fmt.Errorf("%s: unexpected answer %08x", method, got)
+	}
+	return nil
+}
+
+//success run query answered with engine.validator.success
+func (e *Engine) success(req []byte, method string) error {
+	r, err := e.query(req)
+	if err != nil {
+		return err
+	}
+	return expect(r, idSuccess, method)
+}
+
+//NewKey generate new key pair in the engine keyring (newkey)
+func (e *Engine) NewKey() (KeyHash, error) {
+	var h KeyHash
+	r, err := e.query(tl.AppendUint32(nil, idGenerateKeyPair))
+	if err != nil {
+		return h, err
+	}
+	if err = expect(r, idKeyHash, "newkey"); err != nil {
+		return h, err
+	}
+	copy(h[:], r.Int256())
+	return h, r.Err()
+}
+
+//ExportPub public key of the key pair (exportpub)
+func (e *Engine) ExportPub(key KeyHash) (PublicKey, error) {
+	r, err := e.query(tl.AppendInt256(tl.AppendUint32(nil, idExportPublicKey), key[:]))
+	if err != nil {
+		return nil, err
+	}
+	if err = expect(r, idPubEd25519, "exportpub"); err != nil {
+		return nil, err
+	}
+	pub := r.Int256()
+	if err = r.Err(); err != nil {
+		return nil, err
+	}
+	return PublicKey(pub), nil
+}
+
+//Sign sign data with the key (sign)
+func (e *Engine) Sign(key KeyHash, data []byte) ([]byte, error) {
+	req := tl.AppendInt256(tl.AppendUint32(nil, idSign), key[:])
+	req = tl.AppendBytes(req, data)
+	r, err := e.query(req)
+	if err != nil {
+		return nil, err
+	}
+	if err = expect(r, idSignature, "sign"); err != nil {
+		return nil, err
+	}
+	signature := r.Bytes()
+	return signature, r.Err()
+}
+
+//AddPermKey add permanent validator key for the election (addpermkey)
+func (e *Engine) AddPermKey(key KeyHash, electionDate, expireAt int64) error {
+	req := tl.AppendInt256(tl.AppendUint32(nil, idAddValidatorPermanentKey), key[:])
+	req = tl.AppendInt32(req, int32(electionDate))
+	req = tl.AppendInt32(req, int32(expireAt))
+	return e.success(req, "addpermkey")
+}
+
+//AddTempKey add temporary key to the permanent one (addtempkey)
+func (e *Engine) AddTempKey(permKey, key KeyHash, expireAt int64) error {
+	req := tl.AppendInt256(tl.AppendUint32(nil, idAddValidatorTempKey), permKey[:])
+	req = tl.AppendInt256(req, key[:])
+	req = tl.AppendInt32(req, int32(expireAt))
+	return e.success(req, "addtempkey")
+}
+
+//AddAdnl add ADNL address of the given category (addadnl)
+func (e *Engine) AddAdnl(key KeyHash, category int32) error {
+	req := tl.AppendInt256(tl.AppendUint32(nil, idAddAdnlID), key[:])
+	req = tl.AppendInt32(req, category)
+	return e.success(req, "addadnl")
+}
+
+//AddValidatorAddr bind ADNL address to the permanent key (addvalidatoraddr)
+func (e *Engine) AddValidatorAddr(permKey, adnlKey KeyHash, expireAt int64) error {
+	req := tl.AppendInt256(tl.AppendUint32(nil, idAddValidatorAdnlAddress), permKey[:])
+	req = tl.AppendInt256(req, adnlKey[:])
+	req = tl.AppendInt32(req, int32(expireAt))
+	return e.success(req, "addvalidatoraddr")
+}
+
+//DelPermKey remove permanent validator key with its temporary keys and addresses (delpermkey)
+func (e *Engine) DelPermKey(key KeyHash) error {
+	req := tl.AppendInt256(tl.AppendUint32(nil, idDelValidatorPermanentKey), key[:])
+	return e.success(req, "delpermkey")
+}
+
+//DelTempKey remove temporary key of the permanent one (deltempkey)
+func (e *Engine) DelTempKey(permKey, key KeyHash) error {
+	req := tl.AppendInt256(tl.AppendUint32(nil, idDelValidatorTempKey), permKey[:])
+	req = tl.AppendInt256(req, key[:])
+	return e.success(req, "deltempkey")
+}
+
+//DelAdnl remove ADNL address (deladnl)
+func (e *Engine) DelAdnl(key KeyHash) error {
+	req := tl.AppendInt256(tl.AppendUint32(nil, idDelAdnlID), key[:])
+	return e.success(req, "deladnl")
+}
+
+//GetStats engine statistics as key/value pairs (getstats)
+func (e *Engine) GetStats() (map[string]string, error) {
+	r, err := e.query(tl.AppendUint32(nil, idGetStats))
+	if err != nil {
+		return nil, err
+	}
+	if err = expect(r, idStats, "getstats"); err != nil {
+		return nil, err
+	}
+	n := r.Int32()
+	stats := make(map[string]string, n)
+	for i := int32(0); i < n && r.Err() == nil; i++ {
+		key := r.Text()
+		stats[key] = r.Text()
+	}
+	if err = r.Err(); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+//GetConfig engine config as JSON (getconfig)
+func (e *Engine) GetConfig() (string, error) {
+	r, err := e.query(tl.AppendUint32(nil, idGetConfig))
+	if err != nil {
+		return "", err
+	}
+	if err = expect(r, idJSONConfig, "getconfig"); err != nil {
+		return "", err
+	}
+	data := r.Text()
+	return data, r.Err()
+}
+
+//OverlayPeers ADNL ids of the nodes in all overlays of the engine (getoverlaysstats)
+func (e *Engine) OverlayPeers() (map[string]bool, error) {
+	r, err := e.query(tl.AppendUint32(nil, idGetOverlaysStats))
+	if err != nil {
+		return nil, err
+	}
+	if err = expect(r, idOverlaysStats, "getoverlaysstats"); err != nil {
+		return nil, err
+	}
+	peers := make(map[string]bool)
+	overlays := r.Int32()
+	for i := int32(0); i < overlays && r.Err() == nil; i++ {
+		// overlay_id, overlay_id_full, adnl_id and scope
+		r.Int256()
+		switch id := r.Uint32(); id {
+		case idPubEd25519:
+			r.Int256()
+		case idPubOverlay:
+			r.Bytes()
+		default:
+			return nil, fmt.Errorf("getoverlaysstats: unexpected overlay key %08x", id)
+		}
+		r.Int256()
+		r.Text()
+		nodes := r.Int32()
+		for j := int32(0); j < nodes && r.Err() == nil; j++ {
+			// adnl_id ip_addr bdcst_errors fec_bdcst_errors t_out_bytes t_in_bytes t_out_pckts t_in_pckts
+			peers[string(r.Int256())] = true
+			r.Text()
+			for k := 0; k < 6; k++ {
+				r.Int32()
+			}
+		}
+		stats := r.Int32()
+		for j := int32(0); j < stats && r.Err() == nil; j++ {
+			r.Text()
+			r.Text()
+		}
+	}
+	if err = r.Err(); err != nil {
+		return nil, fmt.Errorf("getoverlaysstats: %v", err)
+	}
+	if r.Len() != 0 {
+		return nil, fmt.Errorf("getoverlaysstats: %d bytes left, unknown answer layout", r.Len())
+	}
+	return peers, nil
+}
@@ -0,0 +1,23 @@
+package validator
+
+import "testing"
+
+func TestIDs(t *testing.T) {
+	tests := []struct {
+		name string
+		got  uint32
+		want uint32
+	}{
+		{"controlQuery", idControlQuery, 0xa476bdc0},
+		{"generateKeyPair", idGenerateKeyPair, 0xeb25607b},
+		{"getStats", idGetStats, 0x52d5c311},
+		{"stats", idStats, 0x5d49d36f},
+		{"getOverlaysStats", idGetOverlaysStats, 0xfcd8acce},
+		{"overlaysStats", idOverlaysStats, 0x9c09267f},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s id = %08x, want %08x", tt.name, tt.got, tt.want)
+		}
+	}
+}
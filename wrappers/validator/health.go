@@ -0,0 +1,240 @@
+package validator
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mercuryoio/ton-validator/database"
+	"github.com/mercuryoio/ton-validator/utils"
+)
+
+//Node health verdicts
+const (
+	HealthHealthy  = "healthy"
+	HealthDegraded = "degraded"
+	HealthDown     = "down"
+)
+
+//Stats getstats of a node
+type Stats struct {
+	Unixtime int64
+	//MasterchainBlock last masterchain block, e.g. (-1,8000000000000000,1234):<root hash>:<file hash>
+	MasterchainBlock         string
+	MasterchainSeqno         int64
+	MasterchainBlockTime     int64
+	GCMasterchainBlock       string
+	KeyMasterchainBlock      string
+	KnownKeyMasterchainBlock string
+	RotateMasterchainBlock   string
+	//ShardClientMasterchainSeqno masterchain block whose shards the node has applied
+	ShardClientMasterchainSeqno int64
+	//StateSerializerMasterchainSeqno masterchain block of the last persistent state
+	StateSerializerMasterchainSeqno int64
+}
+
+//ParseStats parse getstats values, the masterchain block and its time must be there
+func ParseStats(values map[string]string) (Stats, error) {
+	s := Stats{
+		MasterchainBlock:         values["masterchainblock"],
+		GCMasterchainBlock:       values["gcmasterchainblock"],
+		KeyMasterchainBlock:      values["keymasterchainblock"],
+		KnownKeyMasterchainBlock: values["knownkeymasterchainblock"],
+		RotateMasterchainBlock:   values["rotatemasterchainblock"],
+	}
+	var err error
+	ints := []struct {
+		name     string
+		v        *int64
+		required bool
+	}{
+		{"unixtime", &s.Unixtime, true},
+		{"masterchainblocktime", &s.MasterchainBlockTime, true},
+		{"shardclientmasterchainseqno", &s.ShardClientMasterchainSeqno, false},
+		{"stateserializermasterchainseqno", &s.StateSerializerMasterchainSeqno, false},
+	}
+	for _, i := range ints {
+		value, ok := values[i.name]
+		if !ok {
+			if i.required {
+				return s, fmt.Errorf("getstats: no %s", i.name)
+			}
+			continue
+		}
+		if *i.v, err = strconv.ParseInt(value, 10, 64); err != nil {
+			return s, utils.ParseError{What: i.name, Value: value, Err: err}
+		}
+	}
+	if s.MasterchainSeqno, err = blockSeqno(s.MasterchainBlock); err != nil {
+		return s, utils.ParseError{What: "masterchainblock", Value: s.MasterchainBlock, Err: err}
+	}
+	return s, nil
+}
+
+//blockSeqno seqno of a block id printed as (workchain,shard,seqno):root_hash:file_hash
+func blockSeqno(block string) (int64, error) {
+	end := strings.IndexByte(block, ')')
+	if !strings.HasPrefix(block, "(") || end < 0 {
+		return 0, fmt.Errorf("not a block id")
+	}
+	parts := strings.Split(block[1:end], ",")
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("not a block id")
+	}
+	return strconv.ParseInt(parts[2], 10, 64)
+}
+
+//SyncLag seconds the node's last masterchain block is behind its clock
+func (s Stats) SyncLag() int64 {
+	return s.Unixtime - s.MasterchainBlockTime
+}
+
+//Thresholds limits of a healthy node
+type Thresholds struct {
+	//SyncLag seconds the masterchain may be behind the node's clock, over DownSyncLag the node is down
+	SyncLag     int64
+	DownSyncLag int64
+	//ShardClientLag masterchain blocks the shard client may be behind
+	ShardClientLag int64
+	//StateSerializerLag masterchain blocks the last persistent state may be behind
+	StateSerializerLag int64
+	//TimeDrift seconds the node's clock may differ from ours
+	TimeDrift int64
+	//MinPeers fewest overlay peers, checked when the node reports them
+	MinPeers int
+}
+
+//DefaultThresholds thresholds of the bot and ton-cli
+var DefaultThresholds = Thresholds{
+	SyncLag:            25,
+	DownSyncLag:        600,
+	ShardClientLag:     20,
+	StateSerializerLag: 40000,
+	TimeDrift:          10,
+	MinPeers:           5,
+}
+
+//Health health report of a node
+type Health struct {
+	Node  string
+	Stats Stats
+	//SyncLag, ShardClientLag, StateSerializerLag and TimeDrift as measured against Thresholds
+	SyncLag            int64
+	ShardClientLag     int64
+	StateSerializerLag int64
+	TimeDrift          int64
+	//ActiveKeys permanent validator keys in the node config that have not expired, -1 if unknown
+	ActiveKeys int
+	//Peers distinct overlay peers, -1 if unknown
+	Peers   int
+	Verdict string
+	//Problems why the node is not healthy
+	Problems []string
+}
+
+//InSync the node answered and its masterchain is no more than the threshold behind
+func (h Health) InSync(t Thresholds) bool {
+	return h.Verdict != HealthDown && h.SyncLag <= t.SyncLag
+}
+
+func (h *Health) degrade(format string, args ...interface{}) {
+	h.Problems = append(h.Problems, fmt.Sprintf(format, args...))
+	if h.Verdict == HealthHealthy {
+		h.Verdict = HealthDegraded
+	}
+}
+
+//NodeHealth getstats of the node, with its config and overlay stats, judged by t
+func (c *Config) NodeHealth(node database.Node, t Thresholds) Health {
+	h := Health{Node: node.HostPort, ActiveKeys: -1, Peers: -1, Verdict: HealthHealthy}
+	asked := time.Now().Unix()
+	stats, err := c.ValGetStats(node)
+	if err != nil {
+		h.Verdict = HealthDown
+		h.Problems = append(h.Problems, fmt.Sprintf("getstats failed: %v", err))
+		return h
+	}
+	h.Stats = stats
+	h.SyncLag = stats.SyncLag()
+	h.TimeDrift = stats.Unixtime - asked
+	if stats.ShardClientMasterchainSeqno > 0 {
+		h.ShardClientLag = stats.MasterchainSeqno - stats.ShardClientMasterchainSeqno
+	}
+	if stats.StateSerializerMasterchainSeqno > 0 {
+		h.StateSerializerLag = stats.MasterchainSeqno - stats.StateSerializerMasterchainSeqno
+	}
+
+	if h.SyncLag > t.DownSyncLag {
+		h.Verdict = HealthDown
+		h.Problems = append(h.Problems, fmt.Sprintf("masterchain is %d seconds behind", h.SyncLag))
+	} else if h.SyncLag > t.SyncLag {
+		h.degrade("masterchain is %d seconds behind", h.SyncLag)
+	}
+	if h.ShardClientLag > t.ShardClientLag {
+		h.degrade("shard client is %d blocks behind", h.ShardClientLag)
+	}
+	if h.StateSerializerLag > t.StateSerializerLag {
+		h.degrade("state serializer is %d blocks behind", h.StateSerializerLag)
+	}
+	if h.TimeDrift > t.TimeDrift || -h.TimeDrift > t.TimeDrift {
+		h.degrade("clock is %d seconds off", h.TimeDrift)
+	}
+
+	if keys, err := c.activeKeys(node, stats.Unixtime); err != nil {
+		h.degrade("getconfig failed: %v", err)
+	} else {
+		h.ActiveKeys = keys
+	}
+	// the peers stay unknown when the overlay stats can't be read, the node still answered
+	if peers, err := c.peers(node); err != nil {
+		h.degrade("getoverlaysstats failed: %v", err)
+	} else {
+		h.Peers = peers
+		if peers < t.MinPeers {
+			h.degrade("%d peers", peers)
+		}
+	}
+	return h
+}
+
+//activeKeys permanent validator keys of the node config that expire after now
+func (c *Config) activeKeys(node database.Node, now int64) (int, error) {
+	var data string
+	err := c.do(node, func(e *Engine) error {
+		var err error
+		data, err = e.GetConfig()
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	var config struct {
+		Validators []struct {
+			ID       string `json:"id"`
+			ExpireAt int64  `json:"expire_at"`
+		} `json:"validators"`
+	}
+	if err = json.Unmarshal([]byte(data), &config); err != nil {
+		return 0, fmt.Errorf("bad node config: %v", err)
+	}
+	n := 0
+	for _, v := range config.Validators {
+		if v.ExpireAt > now {
+			n++
+		}
+	}
+	return n, nil
+}
+
+//peers distinct ADNL ids of the nodes in all overlays of the node
+func (c *Config) peers(node database.Node) (int, error) {
+	var ids map[string]bool
+	err := c.do(node, func(e *Engine) error {
+		var err error
+		ids, err = e.OverlayPeers()
+		return err
+	})
+	return len(ids), err
+}
@@ -1,18 +1,26 @@
 package validator
 
 import (
-	"fmt"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/mercuryoio/ton-validator/adnl"
 	"github.com/mercuryoio/ton-validator/database"
 	"github.com/mercuryoio/ton-validator/utils"
-	"log"
-	"strconv"
-	"strings"
 )
 
 //Config config
 type Config struct {
-	ValidatorConsole *string
-	Verbose          *bool
+	Timeout *time.Duration
+	Verbose *bool
+
+	mu      sync.Mutex
+	engines map[int]*Engine
+	dials   sync.Map // node id to the mutex serializing its dials
 }
 
 //NewClient set config
@@ -20,198 +28,251 @@ func NewClient(config *Config) *Config {
 	return config
 }
 
-//ValidatorStats stats
-type ValidatorStats struct {
-	unixtime                        int64
-	masterchainblock                string
-	masterchainblocktime            int64
-	gcmasterchainblock              string
-	keymasterchainblock             string
-	knownkeymasterchainblock        string
-	rotatemasterchainblock          string
-	stateserializermasterchainseqno int64
-	shardclientmasterchainseqno     int64
+//cached kept connection to the node if it is still alive
+func (c *Config) cached(nodeID int) *Engine {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.engines[nodeID]; ok && e.Alive() {
+		return e
+	}
+	return nil
 }
 
-//ValidatorAddPermKey add perm key
-func (c *Config) ValidatorAddPermKey(node database.Node, keyHash string, electionDate int64) bool {
-	expireAt := electionDate + 70000
-	/* args := fmt.Sprintf("-c addpermkey %s %d %d", keyHash, electionDate, expireAt)
-	output, err := validatorEngineConsoleReq(args) */
-	valCmd := fmt.Sprintf("-c addpermkey %s %d %d", keyHash, electionDate, expireAt)
-	args := []string{"-k", node.ClientCert, "-p", node.ServerPub, "-a", node.HostPort, "-v 0", valCmd}
-
-	output, err := utils.CmdExec(*c.ValidatorConsole, *c.Verbose, args...)
+//engine control connection to the node, dialed on first use and kept open.
+//Only dials to the same node wait for each other, slow nodes do not hold up the rest.
+func (c *Config) engine(node database.Node) (*Engine, error) {
+	if e := c.cached(node.ID); e != nil {
+		return e, nil
+	}
+	mu, _ := c.dials.LoadOrStore(node.ID, &sync.Mutex{})
+	mu.(*sync.Mutex).Lock()
+	defer mu.(*sync.Mutex).Unlock()
+	// another caller may have dialed while this one waited
+	if e := c.cached(node.ID); e != nil {
+		return e, nil
+	}
+	e, err := DialEngine(node, *c.Timeout)
 	if err != nil {
-		log.Println(err)
-		return false
+		return nil, err
 	}
-	if !strings.Contains(output, "success") {
-		return false
+	c.mu.Lock()
+	if c.engines == nil {
+		c.engines = make(map[int]*Engine)
+	}
+	c.engines[node.ID] = e
+	c.mu.Unlock()
+	if *c.Verbose {
+		log.Println("Connected to validator engine", node.HostPort)
 	}
+	return e, nil
+}
 
-	return true
+//do run fn on the node's connection, redialing once if the kept connection went stale.
+//A query timeout makes the node unreachable as a failed dial does.
+func (c *Config) do(node database.Node, fn func(e *Engine) error) error {
+	for attempt := 0; ; attempt++ {
+		e, err := c.engine(node)
+		if err != nil {
+			return err
+		}
+		err = fn(e)
+		if errors.Is(err, adnl.ErrTimeout) {
+			return UnreachableError{Node: node.HostPort, Err: err}
+		}
+		if _, rejected := err.(ControlError); err == nil || rejected || e.Alive() {
+			return err
+		}
+		if attempt > 0 {
+			return UnreachableError{Node: node.HostPort, Err: err}
+		}
+	}
 }
 
-//ValidatorAddTempKey add temp key
-func (c *Config) ValidatorAddTempKey(node database.Node, permKeyHash string, keyHash string, expireAt int64) bool {
-	//args := fmt.Sprintf("-c addtempkey %s %s %d", permKeyHash, keyHash, expireAt)
-	//output, err := validatorEngineConsoleReq(args)
-	valCmd := fmt.Sprintf("-c addtempkey %s %s %d", permKeyHash, keyHash, expireAt)
-	args := []string{"-k", node.ClientCert, "-p", node.ServerPub, "-a", node.HostPort, "-v 0", valCmd}
+//Close close all control connections
+func (c *Config) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for id, e := range c.engines {
+		e.Close()
+		delete(c.engines, id)
+	}
+}
 
-	_, err := utils.CmdExec(*c.ValidatorConsole, *c.Verbose, args...)
+//ValidatorAddPermKey add perm key
+func (c *Config) ValidatorAddPermKey(node database.Node, keyHash string, electionDate, expireAt int64) error {
+	key, err := ParseKeyHash(keyHash)
 	if err != nil {
-		log.Fatalln("validatorEngineConsoleReq err:", err)
-		return false
+		return err
 	}
-	return true
+	return c.do(node, func(e *Engine) error {
+		return e.AddPermKey(key, electionDate, expireAt)
+	})
 }
 
-//ValidatorAddAdnl add adnl
-func (c *Config) ValidatorAddAdnl(node database.Node, keyHash string, category int) bool {
-	//	args := fmt.Sprintf("-c addadnl %s %d", keyHash, category)
-	//	output, err := validatorEngineConsoleReq(args)
-	valCmd := fmt.Sprintf("-c addadnl %s %d", keyHash, category)
-	args := []string{"-k", node.ClientCert, "-p", node.ServerPub, "-a", node.HostPort, "-v 0", valCmd}
+//ValidatorAddTempKey add temp key
+func (c *Config) ValidatorAddTempKey(node database.Node, permKeyHash string, keyHash string, expireAt int64) error {
+	permKey, err := ParseKeyHash(permKeyHash)
+	if err != nil {
+		return err
+	}
+	key, err := ParseKeyHash(keyHash)
+	if err != nil {
+		return err
+	}
+	return c.do(node, func(e *Engine) error {
+		return e.AddTempKey(permKey, key, expireAt)
+	})
+}
 
-	_, err := utils.CmdExec(*c.ValidatorConsole, *c.Verbose, args...)
+//ValidatorAddAdnl add adnl
+func (c *Config) ValidatorAddAdnl(node database.Node, keyHash string, category int) error {
+	key, err := ParseKeyHash(keyHash)
 	if err != nil {
-		log.Fatalln("validatorEngineConsoleReq err:", err)
-		return false
+		return err
 	}
-	return true
+	return c.do(node, func(e *Engine) error {
+		return e.AddAdnl(key, int32(category))
+	})
 }
 
 //ValidatorAddValidatorAddr add validator addr
-func (c *Config) ValidatorAddValidatorAddr(node database.Node, permKeyHash string, keyHash string, expireAt int64) bool {
-	valCmd := fmt.Sprintf("-c addvalidatoraddr %s %s %d", permKeyHash, keyHash, expireAt)
-	//output, err := validatorEngineConsoleReq(args)
-	args := []string{"-k", node.ClientCert, "-p", node.ServerPub, "-a", node.HostPort, "-v 0", valCmd}
-	_, err := utils.CmdExec(*c.ValidatorConsole, *c.Verbose, args...)
+func (c *Config) ValidatorAddValidatorAddr(node database.Node, permKeyHash string, keyHash string, expireAt int64) error {
+	permKey, err := ParseKeyHash(permKeyHash)
 	if err != nil {
-		log.Fatalln("validatorEngineConsoleReq err:", err)
-		return false
+		return err
 	}
-	return true
+	key, err := ParseKeyHash(keyHash)
+	if err != nil {
+		return err
+	}
+	return c.do(node, func(e *Engine) error {
+		return e.AddValidatorAddr(permKey, key, expireAt)
+	})
+}
+
+//ValidatorDelPermKey del perm key
+func (c *Config) ValidatorDelPermKey(node database.Node, keyHash string) error {
+	key, err := ParseKeyHash(keyHash)
+	if err != nil {
+		return err
+	}
+	return c.do(node, func(e *Engine) error {
+		return e.DelPermKey(key)
+	})
 }
 
-//ValidatorSign sign message
+//ValidatorDelTempKey del temp key
+func (c *Config) ValidatorDelTempKey(node database.Node, permKeyHash string, keyHash string) error {
+	permKey, err := ParseKeyHash(permKeyHash)
+	if err != nil {
+		return err
+	}
+	key, err := ParseKeyHash(keyHash)
+	if err != nil {
+		return err
+	}
+	return c.do(node, func(e *Engine) error {
+		return e.DelTempKey(permKey, key)
+	})
+}
+
+//ValidatorDelAdnl del adnl
+func (c *Config) ValidatorDelAdnl(node database.Node, keyHash string) error {
+	key, err := ParseKeyHash(keyHash)
+	if err != nil {
+		return err
+	}
+	return c.do(node, func(e *Engine) error {
+		return e.DelAdnl(key)
+	})
+}
+
+//ValidatorSign sign hex encoded data, signature is returned in base64
 func (c *Config) ValidatorSign(node database.Node, keyHash string, data string) (string, error) {
-	valCmd := fmt.Sprintf("-c sign %s %s", keyHash, data)
-	//output, err := validatorEngineConsoleReq(args)
-	args := []string{"-k", node.ClientCert, "-p", node.ServerPub, "-a", node.HostPort, "-v 0", valCmd}
-	output, err := utils.CmdExec(*c.ValidatorConsole, *c.Verbose, args...)
+	key, err := ParseKeyHash(keyHash)
+	if err != nil {
+		return "", err
+	}
+	raw, err := hex.DecodeString(data)
 	if err != nil {
-		log.Fatalln("validatorEngineConsoleReq err:", err)
-		return string(output), err
+		return "", utils.ParseError{What: "data to sign", Value: data, Err: err}
 	}
-	i := strings.Index(output, "got signature")
-	output = output[i:]
-	output = strings.TrimPrefix(output, "got signature ")
-	output = strings.TrimSpace(output)
-	return string(output), nil
+	var signature []byte
+	err = c.do(node, func(e *Engine) error {
+		var err error
+		signature, err = e.Sign(key, raw)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(signature), nil
 }
 
 //ValidatorCreateNewKey create new key
 func (c *Config) ValidatorCreateNewKey(node database.Node, electionID int64) (database.Key, error) {
-	valCmd := "-c newkey"
-	args := []string{"-k", node.ClientCert, "-p", node.ServerPub, "-a", node.HostPort, "-v 0", valCmd}
-
-	output, err := utils.CmdExec(*c.ValidatorConsole, *c.Verbose, args...)
+	var keyHash KeyHash
+	err := c.do(node, func(e *Engine) error {
+		var err error
+		keyHash, err = e.NewKey()
+		return err
+	})
 	if err != nil {
-		log.Fatalln("validatorEngineConsoleReq err:", err)
 		return database.Key{}, err
 	}
-	i := strings.Index(output, "new key")
-	output = output[i:]
-	output = strings.TrimPrefix(output, "new key ")
-	output = strings.TrimSpace(output)
 	key := database.Key{
-		Key:        output,
+		Key:        keyHash.String(),
 		ElectionID: electionID,
 		NodeID:     node.ID,
 		Type:       "key",
 	}
-	return key, err
+	return key, nil
 }
 
 //ValidatorGetPublicKey get public key
 func (c *Config) ValidatorGetPublicKey(node database.Node, signingKey string, electionID int64) (database.Key, error) {
-	valCmd := fmt.Sprintf("-c exportpub %s", signingKey)
-	//output, err := validatorEngineConsoleReq(args)
-	args := []string{"-k", node.ClientCert, "-p", node.ServerPub, "-a", node.HostPort, "-v 0", valCmd}
-	output, err := utils.CmdExec(*c.ValidatorConsole, *c.Verbose, args...)
+	keyHash, err := ParseKeyHash(signingKey)
+	if err != nil {
+		return database.Key{}, err
+	}
+	var pub PublicKey
+	err = c.do(node, func(e *Engine) error {
+		var err error
+		pub, err = e.ExportPub(keyHash)
+		return err
+	})
 	if err != nil {
-		log.Println("getValidatorPublicKey err:", err)
 		return database.Key{}, err
 	}
-	i := strings.Index(output, "got public key:")
-	output = output[i:]
-	output = strings.TrimPrefix(output, "got public key: ")
-	output = strings.TrimSpace(output)
 	key := database.Key{
-		Key:        output,
+		Key:        pub.String(),
 		ElectionID: electionID,
 		NodeID:     node.ID,
 		Type:       "pubkey",
 	}
-	return key, err
+	return key, nil
 }
 
 //ValGetStats getstats
-func (c *Config) ValGetStats(node database.Node) ValidatorStats {
-	valCmd := "-c getstats"
-	args := []string{"-k", node.ClientCert, "-p", node.ServerPub, "-a", node.HostPort, "-v 0", valCmd}
-	output, err := utils.CmdExec(*c.ValidatorConsole, *c.Verbose, args...)
+func (c *Config) ValGetStats(node database.Node) (Stats, error) {
+	var values map[string]string
+	err := c.do(node, func(e *Engine) error {
+		var err error
+		values, err = e.GetStats()
+		return err
+	})
 	if err != nil {
-		log.Println("Validator getstats err:", err)
-		//return string(output), err
-	}
-	i := strings.Index(output, "unixtime")
-	output = output[i:]
-	lines := strings.Split(output, "\n")
-	var values []string
-	for _, s := range lines {
-		output = s
-		output = strings.TrimPrefix(output, "unixtime")
-		output = strings.TrimPrefix(output, "masterchainblocktime")
-		output = strings.TrimPrefix(output, "masterchainblock")
-		output = strings.TrimPrefix(output, "gcmasterchainblock")
-		output = strings.TrimPrefix(output, "keymasterchainblock")
-		output = strings.TrimPrefix(output, "knownkeymasterchainblock")
-		output = strings.TrimPrefix(output, "rotatemasterchainblock")
-		output = strings.TrimPrefix(output, "stateserializermasterchainseqno")
-		output = strings.TrimPrefix(output, "shardclientmasterchainseqno")
-		output = strings.TrimSpace(output)
-		values = append(values, output)
-
-	}
-	unixtime, _ := strconv.ParseInt(values[0], 10, 64)
-	masterchainblocktime, _ := strconv.ParseInt(values[2], 10, 64)
-	stateserializermasterchainseqno, _ := strconv.ParseInt(values[7], 10, 64)
-	shardclientmasterchainseqno, _ := strconv.ParseInt(values[8], 10, 64)
-	stats := ValidatorStats{
-		unixtime:                        unixtime,
-		masterchainblock:                values[1],
-		masterchainblocktime:            masterchainblocktime,
-		gcmasterchainblock:              values[3],
-		keymasterchainblock:             values[4],
-		knownkeymasterchainblock:        values[5],
-		rotatemasterchainblock:          values[6],
-		stateserializermasterchainseqno: stateserializermasterchainseqno,
-		shardclientmasterchainseqno:     shardclientmasterchainseqno,
-	}
-	return stats
+		return Stats{}, err
+	}
+	return ParseStats(values)
 }
 
 //CheckNodeSync check if node in sync
 func (c *Config) CheckNodeSync(node database.Node) bool {
-	stats := c.ValGetStats(node)
-	diff := stats.unixtime - stats.masterchainblocktime
-
-	if diff > 25 {
+	stats, err := c.ValGetStats(node)
+	if err != nil {
+		log.Println("Validator getstats err:", err)
 		return false
 	}
-	return true
+	return stats.SyncLag() <= DefaultThresholds.SyncLag
 }